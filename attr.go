@@ -1,72 +1,347 @@
 package slogx
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"reflect"
+	"runtime/debug"
 	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"go.innotegrity.dev/errorx"
 	"go.innotegrity.dev/generic"
+	"go.innotegrity.dev/runtimex"
 )
 
+// DefaultGroupSeparator is the separator used to join group and attribute keys when groups are flattened.
+const DefaultGroupSeparator = "."
+
+// attrsContextKey is used to store attributes to add to every record in a standard Go context object.
+type attrsContextKey struct{}
+
+// AttrsFromContext retrieves the attributes stored in the context using [ContextWithAttrs], if any.
+//
+// The returned slice must not be modified.
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	if attrs, ok := ctx.Value(attrsContextKey{}).([]slog.Attr); ok {
+		return attrs
+	}
+	return nil
+}
+
 // ConsolidateAttrs combines the given attributes with attributes from the record, mapping the record attributes under
 // the group, if not empty.
 //
 // Attribute values are resolved during the consolidation and duplicate attributes are removed from the returned slice
-// and any nested groups. If an attribute is specified more than once, the last one specified is used.
+// and any nested groups. If an attribute is specified more than once, the last value specified is used at the position
+// of the first one.
 func ConsolidateAttrs(attrs []slog.Attr, group string, record slog.Record) []slog.Attr {
-	result := attrs
+	return ConsolidateAttrsWithOptions(attrs, group, record, ConsolidateAttrsOptions{})
+}
+
+// ConsolidateAttrsOptions holds the options for consolidating attributes using [ConsolidateAttrsWithOptions].
+type ConsolidateAttrsOptions struct {
+	// DeferResolve indicates whether or not to leave attribute values unresolved.
+	//
+	// By default, every value is resolved during the consolidation, which forces any slog.LogValuer to be evaluated
+	// even if the attribute is later removed or never printed. When this is true, duplicates are still removed using
+	// the attribute keys but values are left for the caller to resolve, so a slog.LogValuer which resolves to a group
+	// is not inlined or deduplicated with any other attributes.
+	DeferResolve bool
+
+	// Groups is the stack of groups to nest the record's attributes within, starting with the outermost group.
+	//
+	// This is typically the list of group names passed to a handler's WithGroup() function. If this is not empty, it
+	// is used instead of the group passed to [ConsolidateAttrsWithOptions].
+	Groups []string
+
+	// KeepDuplicates indicates whether or not to keep attributes with duplicate keys.
+	//
+	// By default, only the last value specified for a key is kept. When this is true, every attribute is kept in the
+	// order in which it was specified so that a formatter can decide how to handle duplicates itself. Groups with the
+	// same key are still merged.
+	KeepDuplicates bool
+}
 
-	if group == "" {
+// ConsolidateAttrsWithOptions combines the given attributes with attributes from the record using the given options,
+// mapping the record attributes under the group, if not empty.
+//
+// Duplicate attributes are removed from the returned slice and any nested groups. If an attribute is specified more
+// than once, the last value specified is used at the position of the first one. Groups with the same key are merged.
+func ConsolidateAttrsWithOptions(attrs []slog.Attr, group string, record slog.Record,
+	opts ConsolidateAttrsOptions) []slog.Attr {
+
+	// pre-size the result so that adding the record's attributes does not grow the slice (or modify the backing
+	// array of the handler's attributes)
+	result := make([]slog.Attr, 0, len(attrs)+record.NumAttrs())
+	result = append(result, attrs...)
+
+	groups := opts.Groups
+	if len(groups) == 0 && group != "" {
+		groups = []string{group}
+	}
+	if len(groups) == 0 {
 		record.Attrs(func(attr slog.Attr) bool {
 			result = append(result, attr)
 			return true
 		})
 	} else {
-		groupAttrs := []any{}
+		groupAttrs := make([]slog.Attr, 0, record.NumAttrs())
 		record.Attrs(func(attr slog.Attr) bool {
 			groupAttrs = append(groupAttrs, attr)
 			return true
 		})
-		result = append(result, slog.Group(group, groupAttrs...))
+		result = append(result, NestAttrs(groups, groupAttrs)...)
+	}
+	if opts.KeepDuplicates {
+		return mergeSameKeyGroups(result, !opts.DeferResolve)
+	}
+	return uniqAttrs(result, !opts.DeferResolve)
+}
+
+// ContextWithAttrs returns a new context with the given attributes added to any attributes already stored in it.
+//
+// Handlers created using handler.NewContextAttrsHandler() add the attributes to every record logged using the
+// returned context, or any context derived from it. This allows attributes such as a user or tenant ID to be stored
+// early on, such as in HTTP middleware, rather than passing a logger created using With() around. The attributes
+// stored in the given context are not modified.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, attrsContextKey{}, append(slices.Clip(AttrsFromContext(ctx)), attrs...))
+}
+
+// DefaultDurationBuckets is the set of bucket boundaries used by [DurationBucket] when none are supplied.
+var DefaultDurationBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// DurationBucket returns an Attr containing both the raw duration under the given key and a bucket label for the
+// duration under the key with a "_bucket" suffix.
+//
+// The bucket label is the smallest bucket boundary the duration is less than or equal to (eg: "le=100ms") or
+// "le=+Inf" if the duration exceeds every boundary. If no buckets are supplied, [DefaultDurationBuckets] is used.
+//
+// The returned Attr is a group with an empty key, so its attributes are inlined by the handler.
+func DurationBucket(key string, d time.Duration, buckets ...time.Duration) slog.Attr {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
 	}
-	return UniqAttrs(result)
+	sorted := slices.Clone(buckets)
+	slices.Sort(sorted)
+
+	label := "le=+Inf"
+	for _, b := range sorted {
+		if d <= b {
+			label = fmt.Sprintf("le=%s", b)
+			break
+		}
+	}
+	return slog.Group("", slog.Duration(key, d), slog.String(fmt.Sprintf("%s_bucket", key), label))
 }
 
 // Err returns an Attr for an error value.
+//
+// If the error combines multiple errors, such as one returned by errors.Join(), the attribute is a group named by the
+// key holding an attribute for each of the errors, keyed by its 1-based index (eg: KEY.001, KEY.002), rather than the
+// combined message. Any error with an Unwrap() []error function is expanded this way, including one created by
+// fmt.Errorf() with multiple %w verbs, whose own message is not included. Errors combined within the errors are
+// expanded as well, up to a depth of DefaultErrXMaxDepth.
+//
+// If the key is empty, "error" is used instead so that the attribute is not dropped by handlers.
 func Err(key string, value error) slog.Attr {
+	if key == "" {
+		key = defaultErrorAttrName
+	}
+	return errAttr(key, value, DefaultErrXOptions().NestedErrorKeyFormat, DefaultErrXMaxDepth, 0)
+}
+
+// errAttr returns an Attr for an error value found at the given depth, expanding errors which combine multiple errors
+// into a group using the key format until the maximum depth is reached.
+func errAttr(key string, value error, keyFormat string, maxDepth, depth int) slog.Attr {
 	if value == nil {
 		return slog.Attr{
 			Key:   key,
 			Value: slog.AnyValue(nil),
 		}
 	}
+	if joined, ok := value.(interface{ Unwrap() []error }); ok {
+		if errs := joined.Unwrap(); len(errs) > 0 {
+			attrs := make([]slog.Attr, 0, len(errs))
+			for i, err := range errs {
+				key := fmt.Sprintf(keyFormat, i+1)
+				if depth >= maxDepth {
+					attrs = append(attrs, slog.String(key, ErrXTruncatedValue))
+				} else {
+					attrs = append(attrs, errAttr(key, err, keyFormat, maxDepth, depth+1))
+				}
+			}
+			return slog.Attr{
+				Key:   key,
+				Value: slog.GroupValue(attrs...),
+			}
+		}
+	}
 	return slog.Attr{
 		Key:   key,
 		Value: slog.StringValue(value.Error()),
 	}
 }
 
+const (
+	// DefaultErrXMaxDepth is the default maximum depth to which nested errors are expanded by [ErrX].
+	DefaultErrXMaxDepth = 10
+
+	// ErrXTruncatedValue is the value used in place of nested errors which are not expanded by [ErrX] because the
+	// maximum depth was reached or because the error is nested within itself.
+	ErrXTruncatedValue = "...truncated"
+)
+
+// ErrXOptions holds the options for converting an extended error into an Attr using [ErrXWithOptions].
+type ErrXOptions struct {
+	// AttributesKey is the key of the group holding the attributes of the error.
+	//
+	// By default, this is "attributes".
+	AttributesKey string
+
+	// CodeKey is the key of the attribute holding the error code.
+	//
+	// By default, this is "code".
+	CodeKey string
+
+	// ErrorKey is the key of the attribute holding the error message.
+	//
+	// By default, this is "error".
+	ErrorKey string
+
+	// InternalErrorKey is the key of the attribute holding the message of the internal error, if there is one.
+	//
+	// By default, this is "internal_error".
+	InternalErrorKey string
+
+	// MaxDepth is the maximum depth to which nested errors are expanded.
+	//
+	// Nested errors of the top-level error are at a depth of 1, their nested errors are at a depth of 2 and so on.
+	// Nested errors beyond this depth are replaced with ErrXTruncatedValue. If this is 0 or less, DefaultErrXMaxDepth
+	// is used.
+	MaxDepth int
+
+	// NestedErrorKeyFormat is the format string used to create the key of each nested error from its 1-based index
+	// using fmt.Sprintf().
+	//
+	// By default, this is "%03d".
+	NestedErrorKeyFormat string
+
+	// NestedErrorsKey is the key of the group holding any nested errors.
+	//
+	// By default, this is "nested_errors".
+	NestedErrorsKey string
+}
+
+// DefaultErrXOptions returns the default set of options used by [ErrX].
+func DefaultErrXOptions() ErrXOptions {
+	return ErrXOptions{
+		AttributesKey:        "attributes",
+		CodeKey:              "code",
+		ErrorKey:             "error",
+		InternalErrorKey:     "internal_error",
+		MaxDepth:             DefaultErrXMaxDepth,
+		NestedErrorKeyFormat: "%03d",
+		NestedErrorsKey:      "nested_errors",
+	}
+}
+
 // ErrX returns an Attr for an extended error value.
+//
+// The attribute is a group named by the key holding the code, message, internal error, attributes and nested errors
+// of the error using the sub-keys from [DefaultErrXOptions]. An internal error which combines multiple errors, such
+// as one returned by errors.Join(), is expanded into a group just like [Err] does. When flattened, these become
+// KEY.code, KEY.error and so on, which is why the default console formatter part order prints both the "error"
+// attribute and attributes matching "error\..*" before any other attributes. Use [ErrXWithOptions] to customize the
+// sub-keys.
+//
+// If the key is empty, "error" is used instead so that the sub-keys are not inlined into the surrounding attributes
+// where they could collide with other attributes.
 func ErrX(key string, value errorx.Error) slog.Attr {
+	return ErrXWithOptions(key, value, DefaultErrXOptions())
+}
+
+// ErrXWithOptions returns an Attr for an extended error value using the given options.
+//
+// Any option which is not set uses the value from [DefaultErrXOptions]. If the key is empty, "error" is used instead.
+func ErrXWithOptions(key string, value errorx.Error, opts ErrXOptions) slog.Attr {
+	if key == "" {
+		key = defaultErrorAttrName
+	}
+	defaults := DefaultErrXOptions()
+	if opts.AttributesKey == "" {
+		opts.AttributesKey = defaults.AttributesKey
+	}
+	if opts.CodeKey == "" {
+		opts.CodeKey = defaults.CodeKey
+	}
+	if opts.ErrorKey == "" {
+		opts.ErrorKey = defaults.ErrorKey
+	}
+	if opts.InternalErrorKey == "" {
+		opts.InternalErrorKey = defaults.InternalErrorKey
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = defaults.MaxDepth
+	}
+	if opts.NestedErrorKeyFormat == "" {
+		opts.NestedErrorKeyFormat = defaults.NestedErrorKeyFormat
+	}
+	if opts.NestedErrorsKey == "" {
+		opts.NestedErrorsKey = defaults.NestedErrorsKey
+	}
+	return errXAttr(key, value, opts, 0, map[uintptr]bool{})
+}
+
+// errXAttr returns an Attr for an extended error value found at the given depth.
+//
+// The ancestors map holds the pointer identity of each error currently being expanded so that errors which are nested
+// within themselves are replaced with ErrXTruncatedValue rather than being expanded again.
+func errXAttr(key string, value errorx.Error, opts ErrXOptions, depth int, ancestors map[uintptr]bool) slog.Attr {
 	if value == nil {
 		return slog.Attr{
 			Key:   key,
 			Value: slog.AnyValue(nil),
 		}
 	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer && !v.IsNil() {
+		if ancestors[v.Pointer()] {
+			return slog.String(key, ErrXTruncatedValue)
+		}
+		ancestors[v.Pointer()] = true
+		defer delete(ancestors, v.Pointer())
+	}
 
 	// add the core attributes
 	attrs := []any{
-		slog.Int("code", value.Code()),
-		slog.String("error", value.Error()),
+		slog.Int(opts.CodeKey, value.Code()),
+		slog.String(opts.ErrorKey, value.Error()),
 	}
 	err := value.InternalError()
 	if err != nil {
-		attrs = append(attrs, slog.String("internal_error", err.Error()))
+		attrs = append(attrs, errAttr(opts.InternalErrorKey, err, opts.NestedErrorKeyFormat, opts.MaxDepth, depth))
 	}
 
 	// add any attributes from the error
@@ -75,16 +350,21 @@ func ErrX(key string, value errorx.Error) slog.Attr {
 		errorAttrs = append(errorAttrs, slog.Any(k, v))
 	}
 	if len(errorAttrs) > 0 {
-		attrs = append(attrs, slog.Group("attributes", errorAttrs...))
+		attrs = append(attrs, slog.Group(opts.AttributesKey, errorAttrs...))
 	}
 
-	// add nested errors
+	// add nested errors until the maximum depth is reached
 	nestedErrs := []any{}
 	for i, ne := range value.NestedErrors() {
-		nestedErrs = append(nestedErrs, ErrX(fmt.Sprintf("%03d", i+1), ne))
+		key := fmt.Sprintf(opts.NestedErrorKeyFormat, i+1)
+		if depth >= opts.MaxDepth {
+			nestedErrs = append(nestedErrs, slog.String(key, ErrXTruncatedValue))
+		} else {
+			nestedErrs = append(nestedErrs, errXAttr(key, ne, opts, depth+1, ancestors))
+		}
 	}
 	if len(nestedErrs) > 0 {
-		attrs = append(attrs, slog.Group("nested_errors", nestedErrs...))
+		attrs = append(attrs, slog.Group(opts.NestedErrorsKey, nestedErrs...))
 	}
 	v := slog.Group(key, attrs...)
 	return v
@@ -93,20 +373,87 @@ func ErrX(key string, value errorx.Error) slog.Attr {
 // FlattenAttrs takes the given slice of attributes and recursively "flattens" groups changing the attribute keys to
 // GROUP.KEY (or GROUP.GROUP.KEY in the case of nested groups).
 func FlattenAttrs(attrs []slog.Attr) []slog.Attr {
+	return FlattenAttrsSep(attrs, DefaultGroupSeparator)
+}
+
+// FlattenAttrsSep is like [FlattenAttrs] except that the given separator is used to join group and attribute keys
+// instead of a period (eg: GROUP_KEY when the separator is an underscore).
+func FlattenAttrsSep(attrs []slog.Attr, sep string) []slog.Attr {
+	return FlattenAttrsWithOptions(attrs, FlattenAttrsOptions{Separator: sep})
+}
+
+// FlattenAttrsOptions holds the options for flattening attributes using [FlattenAttrsWithOptions].
+type FlattenAttrsOptions struct {
+	// MaxGroupDepth is the maximum number of nested groups to flatten into an attribute key.
+	//
+	// Any group nested deeper is kept as a single attribute whose value is the group encoded as a JSON object string
+	// (eg: with a depth of 1, GROUP1 > GROUP2 > KEY becomes GROUP1.GROUP2={"KEY":...}). If this is 0 or less, groups
+	// are flattened no matter how deeply they are nested.
+	MaxGroupDepth int
+
+	// Separator is used to join group and attribute keys.
+	//
+	// If empty, DefaultGroupSeparator is used.
+	Separator string
+}
+
+// FlattenAttrsWithOptions is like [FlattenAttrs] except that the given options are used to flatten the attributes.
+func FlattenAttrsWithOptions(attrs []slog.Attr, opts FlattenAttrsOptions) []slog.Attr {
+	if opts.Separator == "" {
+		opts.Separator = DefaultGroupSeparator
+	}
+	return flattenAttrs(attrs, opts, 0)
+}
+
+// flattenAttrs flattens the given attributes, which are nested within the given number of groups.
+func flattenAttrs(attrs []slog.Attr, opts FlattenAttrsOptions, depth int) []slog.Attr {
 	result := []slog.Attr{}
 	for _, attr := range attrs {
-		if attr.Value.Kind() == slog.KindGroup {
-			groupAttrs := FlattenAttrs(attr.Value.Group())
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() != slog.KindGroup {
+			result = append(result, attr)
+		} else if opts.MaxGroupDepth > 0 && depth >= opts.MaxGroupDepth {
+			result = append(result, slog.String(attr.Key, string(appendGroupJSON(nil, attr.Value.Group()))))
+		} else {
+			groupAttrs := flattenAttrs(attr.Value.Group(), opts, depth+1)
 			for _, groupAttr := range groupAttrs {
-				result = append(result, slog.Attr{Key: fmt.Sprintf("%s.%s", attr.Key, groupAttr.Key), Value: groupAttr.Value})
+				groupAttr.Key = attr.Key + opts.Separator + groupAttr.Key
+				result = append(result, groupAttr)
 			}
-		} else {
-			result = append(result, attr)
 		}
 	}
 	return result
 }
 
+// appendGroupJSON appends the given group attributes to the byte slice encoded as a JSON object.
+//
+// Values which cannot be encoded as JSON are encoded as their string representation instead.
+func appendGroupJSON(b []byte, attrs []slog.Attr) []byte {
+	b = append(b, '{')
+	for i, attr := range attrs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		key, _ := json.Marshal(attr.Key)
+		b = append(append(b, key...), ':')
+		v := attr.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			b = appendGroupJSON(b, v.Group())
+			continue
+		}
+		value := v.Any()
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		output, err := json.Marshal(value)
+		if err != nil {
+			output, _ = json.Marshal(v.String())
+		}
+		b = append(b, output...)
+	}
+	return append(b, '}')
+}
+
 // HttpRequest returns an Attr for an HTTP request object.
 func HttpRequest(key string, req *http.Request, sensitiveHeaders []string, sensitiveQueryParams []string) slog.Attr {
 	if req == nil {
@@ -186,30 +533,125 @@ func HttpResponse(key string, resp *http.Response, sensitiveHeaders []string) sl
 	)
 }
 
+// Lazy returns an Attr whose value is computed by calling the given function only when the value is resolved.
+//
+// Handlers resolve attribute values while consolidating them (see [ConsolidateAttrs]), so the function is only called
+// if the record is actually handled. The function is called each time the value is resolved.
+func Lazy(key string, fn func() any) slog.Attr {
+	return slog.Any(key, lazyValuer(fn))
+}
+
+// lazyValuer is a slog.LogValuer which calls the underlying function to compute its value.
+type lazyValuer func() any
+
+// LogValue calls the underlying function and returns its result as a value.
+func (fn lazyValuer) LogValue() slog.Value {
+	if fn == nil {
+		return slog.AnyValue(nil)
+	}
+	return slog.AnyValue(fn())
+}
+
+// Source returns a group attribute holding the function, file and line of the source code location identified by the
+// given program counter.
+//
+// The group uses slog.SourceKey as its key and the same function, file and line keys as slog.Source so that source
+// information attached to records created manually (eg: for [Logger.LogRecord]) matches the source information
+// printed by handlers. Use [NewRecord] or runtime.Callers() to capture the program counter. If the location cannot
+// be determined from the program counter, an empty group is returned, which handlers ignore.
+func Source(pc uintptr) slog.Attr {
+	if pc == 0 {
+		return slog.Group(slog.SourceKey)
+	}
+	frame := runtimex.FrameFromPC(pc)
+	if frame.File == "" {
+		return slog.Group(slog.SourceKey)
+	}
+	return slog.Group(slog.SourceKey,
+		slog.String("function", frame.Function),
+		slog.String("file", frame.File),
+		slog.Int("line", frame.Line),
+	)
+}
+
+// NestAttrs nests the given attributes within the given stack of groups, starting with the outermost group.
+//
+// For example, nesting the attributes within the groups "a" and "b" returns a single group "a" containing a group "b"
+// which contains the attributes. If no groups are supplied, the attributes are returned unchanged.
+func NestAttrs(groups []string, attrs []slog.Attr) []slog.Attr {
+	for i := len(groups) - 1; i >= 0; i-- {
+		attrs = []slog.Attr{{Key: groups[i], Value: slog.GroupValue(attrs...)}}
+	}
+	return attrs
+}
+
 // SortAttrs sorts the given attributes and returns a slice sorted by attribute key.
 //
-// Any nested attribute groups are sorted by attribute key as well.
+// Any nested attribute groups are sorted by attribute key as well. Keys are compared case-sensitively and duplicate
+// keys are collapsed into a single attribute using the last value specified. Use [SortAttrsWithOptions] to change
+// this behavior.
 func SortAttrs(attrs []slog.Attr) []slog.Attr {
-	attrMap := map[string]slog.Value{}
-	keySet := generic.NewSet[string]()
+	return SortAttrsWithOptions(attrs, SortAttrsOptions{})
+}
+
+// SortAttrsOptions holds the options for sorting attributes using [SortAttrsWithOptions].
+type SortAttrsOptions struct {
+	// CaseInsensitive indicates whether or not to compare keys without regard to case (eg: "apple" sorts before
+	// "Zebra").
+	//
+	// Keys which only differ by case are ordered case-sensitively.
+	CaseInsensitive bool
+
+	// KeepDuplicates indicates whether or not to keep attributes with duplicate keys.
+	//
+	// If false, duplicate keys are collapsed into a single attribute using the last value specified. If true, every
+	// attribute is kept and attributes with the same key remain in the order in which they were specified.
+	KeepDuplicates bool
+}
+
+// SortAttrsWithOptions sorts the given attributes using the given options and returns a slice sorted by attribute key.
+//
+// Any nested attribute groups are sorted using the same options. The sort is stable.
+func SortAttrsWithOptions(attrs []slog.Attr, opts SortAttrsOptions) []slog.Attr {
+	result := make([]slog.Attr, 0, len(attrs))
+	index := map[string]int{}
 	for _, attr := range attrs {
-		attrMap[attr.Key] = attr.Value
-		keySet.Add(attr.Key)
+		v := attr.Value.Resolve()
+		if i, ok := index[attr.Key]; ok && !opts.KeepDuplicates {
+			result[i].Value = v
+			continue
+		}
+		index[attr.Key] = len(result)
+		result = append(result, slog.Attr{Key: attr.Key, Value: v})
 	}
-	keys := keySet.Members()
-	sort.Strings(keys)
-
-	result := []slog.Attr{}
-	for _, key := range keys {
-		if attrMap[key].Kind() == slog.KindGroup {
-			result = append(result, slog.Group(key, generic.AnySlice(SortAttrs(attrMap[key].Group()))...))
-		} else {
-			result = append(result, slog.Attr{Key: key, Value: attrMap[key]})
+	for i, attr := range result {
+		if attr.Value.Kind() == slog.KindGroup {
+			result[i] = slog.Attr{Key: attr.Key, Value: slog.GroupValue(SortAttrsWithOptions(attr.Value.Group(), opts)...)}
 		}
 	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if opts.CaseInsensitive {
+			a, b := strings.ToLower(result[i].Key), strings.ToLower(result[j].Key)
+			if a != b {
+				return a < b
+			}
+		}
+		return result[i].Key < result[j].Key
+	})
 	return result
 }
 
+// Stack returns an Attr holding the stack trace of the calling goroutine as a string.
+//
+// If the key is empty, "stack" is used.
+func Stack(key string) slog.Attr {
+	if key == "" {
+		key = "stack"
+	}
+	return slog.String(key, string(debug.Stack()))
+}
+
 // ToAttrMap converts the given attribute slice to a map of string/values.
 //
 // This function does not recursively convert groups. Use [FlattenAttrs] to flatten the attribute list first.
@@ -224,22 +666,106 @@ func ToAttrMap(attrs []slog.Attr) map[string]slog.Value {
 // UniqAttrs removes duplicate attributes from the slice and any nested groups, resolving attribute values along
 // the way.
 //
-// If an attribute is duplicated, the last duplicate entry is used in the resulting slice.
+// If an attribute is duplicated, the value of the last duplicate entry is used in the resulting slice while the
+// position of the first entry is kept so that the order of the attributes remains stable. Groups with the same key
+// are merged rather than replaced, so attributes added to a group by separate calls are all kept, unless a non-group
+// value with the same key follows them.
+//
+// Groups with an empty key are inlined into the slice containing them, just as they are by the standard handlers.
 func UniqAttrs(attrs []slog.Attr) []slog.Attr {
-	seen := generic.NewSet[string]()
-	result := []slog.Attr{}
+	return uniqAttrs(attrs, true)
+}
 
-	for i := len(attrs) - 1; i >= 0; i-- {
-		if seen.Contains(attrs[i].Key) {
+// uniqAttrs removes duplicate attributes from the slice and any nested groups, resolving attribute values along the
+// way if resolve is true.
+func uniqAttrs(attrs []slog.Attr, resolve bool) []slog.Attr {
+	attrs = inlineEmptyGroups(attrs, resolve)
+	lastIndex := make(map[string]int, len(attrs))
+	for i, attr := range attrs {
+		lastIndex[attr.Key] = i
+	}
+
+	seen := generic.NewSet[string]()
+	result := make([]slog.Attr, 0, len(lastIndex))
+	for i, attr := range attrs {
+		if seen.Contains(attr.Key) {
 			continue
 		}
-		v := attrs[i].Value.Resolve()
+		last := lastIndex[attr.Key]
+		v := attrs[last].Value
 		if v.Kind() == slog.KindGroup {
-			result = append(result, slog.Group(attrs[i].Key, generic.AnySlice(UniqAttrs(v.Group()))...))
+			groupAttrs := v.Group()
+			if last != i {
+				groupAttrs = mergeGroups(attrs[i:last+1], attr.Key)
+			}
+			result = append(result, slog.Attr{Key: attr.Key, Value: slog.GroupValue(uniqAttrs(groupAttrs, resolve)...)})
 		} else {
-			result = append(result, slog.Attr{Key: attrs[i].Key, Value: v})
+			result = append(result, slog.Attr{Key: attr.Key, Value: v})
+		}
+		seen.Add(attr.Key)
+	}
+	return result
+}
+
+// mergeSameKeyGroups merges groups with the same key in the slice and any nested groups, keeping every other
+// attribute, and resolves attribute values along the way if resolve is true.
+//
+// The merged group is kept at the position of the first group with the key.
+func mergeSameKeyGroups(attrs []slog.Attr, resolve bool) []slog.Attr {
+	attrs = inlineEmptyGroups(attrs, resolve)
+	seen := generic.NewSet[string]()
+	result := make([]slog.Attr, 0, len(attrs))
+	for i, attr := range attrs {
+		if attr.Value.Kind() != slog.KindGroup {
+			result = append(result, attr)
+			continue
+		}
+		if seen.Contains(attr.Key) {
+			continue
+		}
+		groupAttrs := []slog.Attr{}
+		for _, other := range attrs[i:] {
+			if other.Key == attr.Key && other.Value.Kind() == slog.KindGroup {
+				groupAttrs = append(groupAttrs, other.Value.Group()...)
+			}
+		}
+		result = append(result, slog.Attr{Key: attr.Key, Value: slog.GroupValue(mergeSameKeyGroups(groupAttrs, resolve)...)})
+		seen.Add(attr.Key)
+	}
+	return result
+}
+
+// mergeGroups returns the combined attributes of every group with the given key in the slice.
+//
+// Any non-group value with the key discards the attributes of the groups before it.
+func mergeGroups(attrs []slog.Attr, key string) []slog.Attr {
+	result := []slog.Attr{}
+	for _, attr := range attrs {
+		if attr.Key != key {
+			continue
+		}
+		if attr.Value.Kind() != slog.KindGroup {
+			result = result[:0]
+			continue
+		}
+		result = append(result, attr.Value.Group()...)
+	}
+	return result
+}
+
+// inlineEmptyGroups replaces any group with an empty key with the attributes contained in the group, resolving the
+// given attributes first if resolve is true.
+func inlineEmptyGroups(attrs []slog.Attr, resolve bool) []slog.Attr {
+	result := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		if resolve {
+			attr.Value = attr.Value.Resolve()
+		}
+		if attr.Key == "" && attr.Value.Kind() == slog.KindGroup {
+			result = append(result, inlineEmptyGroups(attr.Value.Group(), resolve)...)
+		} else {
+			result = append(result, attr)
 		}
-		seen.Add(attrs[i].Key)
 	}
 	return result
 }
@@ -0,0 +1,276 @@
+package slogx_test
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/errorx"
+	"go.innotegrity.dev/slogx"
+)
+
+func TestLazy(t *testing.T) {
+	calls := 0
+	attr := slogx.Lazy("lazy", func() any {
+		calls++
+		return 42
+	})
+	if calls != 0 {
+		t.Errorf("expected function not to be called before resolution, called %d time(s)", calls)
+	}
+
+	attrs := slogx.UniqAttrs([]slog.Attr{attr, slog.Group("group", slogx.Lazy("nested", func() any { return "value" }))})
+	if calls != 1 {
+		t.Errorf("expected function to be called once, called %d time(s)", calls)
+	}
+	m := slogx.ToAttrMap(slogx.FlattenAttrs(attrs))
+	if v, ok := m["lazy"]; !ok || v.Kind() != slog.KindInt64 || v.Int64() != 42 {
+		t.Errorf("expected lazy=42, got %v", v)
+	}
+	if v, ok := m["group.nested"]; !ok || v.String() != "value" {
+		t.Errorf("expected group.nested=value, got %v", v)
+	}
+}
+
+func TestUniqAttrsPreservesOrder(t *testing.T) {
+	attrs := slogx.UniqAttrs([]slog.Attr{
+		slog.String("a", "first"),
+		slog.String("b", "only"),
+		slog.String("a", "last"),
+	})
+	if len(attrs) != 2 {
+		t.Errorf("expected 2 attributes, got %d", len(attrs))
+		return
+	}
+	if attrs[0].Key != "a" || attrs[0].Value.String() != "last" {
+		t.Errorf("expected a=last as the first attribute, got %s", attrs[0])
+	}
+	if attrs[1].Key != "b" || attrs[1].Value.String() != "only" {
+		t.Errorf("expected b=only as the second attribute, got %s", attrs[1])
+	}
+}
+
+func TestDurationBucket(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		buckets  []time.Duration
+		expected string
+	}{
+		{d: 75 * time.Millisecond, expected: "le=100ms"},
+		{d: 100 * time.Millisecond, expected: "le=100ms"},
+		{d: time.Minute, expected: "le=+Inf"},
+		{d: 2 * time.Second, buckets: []time.Duration{5 * time.Second, time.Second}, expected: "le=5s"},
+	}
+	for _, test := range tests {
+		attrs := slogx.UniqAttrs([]slog.Attr{slogx.DurationBucket("latency", test.d, test.buckets...)})
+		m := slogx.ToAttrMap(attrs)
+		if v, ok := m["latency"]; !ok || v.Duration() != test.d {
+			t.Errorf("expected latency=%s, got %v", test.d, v)
+		}
+		if v, ok := m["latency_bucket"]; !ok || v.String() != test.expected {
+			t.Errorf("expected latency_bucket=%s, got %v", test.expected, v)
+		}
+	}
+}
+
+func TestSortAttrsWithOptions(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.String("Zebra", "1"),
+		slog.String("apple", "1"),
+		slog.String("apple", "2"),
+	}
+
+	sorted := slogx.SortAttrs(attrs)
+	if len(sorted) != 2 || sorted[0].Key != "Zebra" || sorted[1].Key != "apple" || sorted[1].Value.String() != "2" {
+		t.Errorf("unexpected case-sensitive sort result: %v", sorted)
+	}
+
+	sorted = slogx.SortAttrsWithOptions(attrs, slogx.SortAttrsOptions{CaseInsensitive: true, KeepDuplicates: true})
+	if len(sorted) != 3 || sorted[0].Key != "apple" || sorted[0].Value.String() != "1" ||
+		sorted[1].Key != "apple" || sorted[1].Value.String() != "2" || sorted[2].Key != "Zebra" {
+		t.Errorf("unexpected case-insensitive sort result: %v", sorted)
+	}
+}
+
+func TestFlattenAttrsSep(t *testing.T) {
+	attrs := slogx.FlattenAttrsSep([]slog.Attr{
+		slog.String("a", "1"),
+		slog.Group("g1", slog.Group("g2", slog.String("b", "2"))),
+	}, "_")
+	if len(attrs) != 2 || attrs[0].Key != "a" || attrs[1].Key != "g1_g2_b" {
+		t.Errorf("unexpected flattened attributes: %v", attrs)
+	}
+}
+
+func TestFlattenAttrsWithOptions(t *testing.T) {
+	attrs := slogx.FlattenAttrsWithOptions([]slog.Attr{
+		slog.String("a", "1"),
+		slog.Group("g1", slog.Int("b", 2), slog.Group("g2", slog.String("c", "3"), slog.Group("g3", slog.Bool("d", true)))),
+	}, slogx.FlattenAttrsOptions{MaxGroupDepth: 1})
+	values := slogx.ToAttrMap(attrs)
+	if len(attrs) != 3 || values["g1.b"].Int64() != 2 {
+		t.Errorf("unexpected flattened attributes: %v", attrs)
+		return
+	}
+	expected := `{"c":"3","g3":{"d":true}}`
+	if v := values["g1.g2"].String(); v != expected {
+		t.Errorf("expected g1.g2 to be %s, got %s", expected, v)
+	}
+}
+
+// nestedErr is a simple extended error used for testing.
+type nestedErr struct {
+	internal error
+	msg      string
+	nested   []errorx.Error
+}
+
+func (e *nestedErr) Attrs() map[string]any        { return nil }
+func (e *nestedErr) Code() int                    { return 1 }
+func (e *nestedErr) Error() string                { return e.msg }
+func (e *nestedErr) InternalError() error         { return e.internal }
+func (e *nestedErr) NestedErrors() []errorx.Error { return e.nested }
+
+func TestErrXWithOptions(t *testing.T) {
+	inner := &nestedErr{msg: "inner"}
+	middle := &nestedErr{msg: "middle", nested: []errorx.Error{inner}}
+	outer := &nestedErr{msg: "outer", nested: []errorx.Error{middle}}
+
+	attr := slogx.ErrXWithOptions("err", outer, slogx.ErrXOptions{
+		CodeKey:              "status",
+		MaxDepth:             1,
+		NestedErrorKeyFormat: "cause_%d",
+		NestedErrorsKey:      "causes",
+	})
+	values := slogx.ToAttrMap(slogx.FlattenAttrs([]slog.Attr{attr}))
+	if _, ok := values["err.status"]; !ok {
+		t.Errorf("expected renamed code key, got %v", values)
+		return
+	}
+	if v, ok := values["err.causes.cause_1.error"]; !ok || v.String() != "middle" {
+		t.Errorf("expected renamed nested error keys, got %v", values)
+		return
+	}
+	if v := values["err.causes.cause_1.causes.cause_1"]; v.String() != slogx.ErrXTruncatedValue {
+		t.Errorf("expected errors beyond the maximum depth to be truncated, got %v", values)
+	}
+}
+
+func TestErrXCycle(t *testing.T) {
+	err := &nestedErr{msg: "outer"}
+	child := &nestedErr{msg: "child", nested: []errorx.Error{err}}
+	err.nested = []errorx.Error{err, child}
+
+	values := slogx.ToAttrMap(slogx.FlattenAttrs([]slog.Attr{slogx.ErrX("err", err)}))
+	if v := values["err.nested_errors.001"]; v.String() != slogx.ErrXTruncatedValue {
+		t.Errorf("expected self-referential error to be truncated, got %v", values)
+	}
+	if v := values["err.nested_errors.002.error"]; v.String() != "child" {
+		t.Errorf("expected child error to be expanded, got %v", values)
+	}
+	if v := values["err.nested_errors.002.nested_errors.001"]; v.String() != slogx.ErrXTruncatedValue {
+		t.Errorf("expected indirect cycle to be truncated, got %v", values)
+	}
+}
+
+func TestErrEmptyKey(t *testing.T) {
+	if attr := slogx.Err("", errors.New("failed")); attr.Key != "error" {
+		t.Errorf("expected empty key to default to error, got %q", attr.Key)
+	}
+	if attr := slogx.ErrX("", &nestedErr{msg: "failed"}); attr.Key != "error" {
+		t.Errorf("expected empty key to default to error, got %q", attr.Key)
+	}
+}
+
+func TestErrJoined(t *testing.T) {
+	e1, e2, e3 := errors.New("first"), errors.New("second"), errors.New("third")
+	attr := slogx.Err("err", errors.Join(e1, errors.Join(e2, e3)))
+	if attr.Value.Kind() != slog.KindGroup {
+		t.Errorf("expected joined error to be expanded into a group, got %s", attr.String())
+		return
+	}
+	values := slogx.ToAttrMap(slogx.FlattenAttrs([]slog.Attr{attr}))
+	expected := map[string]string{"err.001": "first", "err.002.001": "second", "err.002.002": "third"}
+	if len(values) != len(expected) {
+		t.Errorf("expected %d attributes, got %v", len(expected), values)
+		return
+	}
+	for k, v := range expected {
+		if values[k].String() != v {
+			t.Errorf("expected %s to be %q, got %v", k, v, values)
+		}
+	}
+
+	values = slogx.ToAttrMap(slogx.FlattenAttrs([]slog.Attr{
+		slogx.ErrX("err", &nestedErr{msg: "failed", internal: errors.Join(e1, e2)}),
+	}))
+	if values["err.internal_error.001"].String() != "first" || values["err.internal_error.002"].String() != "second" {
+		t.Errorf("expected joined internal error to be expanded, got %v", values)
+	}
+}
+
+func TestSource(t *testing.T) {
+	r, line := slogx.NewRecord(time.Now(), slogx.LevelInfo, "message", 0), currentLine()
+	attr := slogx.Source(r.PC)
+	if attr.Key != slog.SourceKey || attr.Value.Kind() != slog.KindGroup {
+		t.Errorf("expected %s group, got %s", slog.SourceKey, attr.String())
+		return
+	}
+	values := slogx.ToAttrMap(attr.Value.Group())
+	if fn := values["function"].String(); !strings.HasSuffix(fn, ".TestSource") {
+		t.Errorf("expected function to be TestSource, got %s", fn)
+	}
+	if file := values["file"].String(); !strings.HasSuffix(file, "attr_test.go") {
+		t.Errorf("expected file to be attr_test.go, got %s", file)
+	}
+	if values["line"].Int64() != int64(line) {
+		t.Errorf("expected line %d, got %d", line, values["line"].Int64())
+	}
+
+	if attr := slogx.Source(0); len(attr.Value.Group()) != 0 {
+		t.Errorf("expected empty group for unknown location, got %s", attr.String())
+	}
+}
+
+func TestConsolidateAttrsDeferResolve(t *testing.T) {
+	resolved := 0
+	lazy := slogx.Lazy("lazy", func() any { resolved++; return "value" })
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0)
+	r.AddAttrs(slog.String("a", "2"), lazy)
+
+	attrs := slogx.ConsolidateAttrsWithOptions([]slog.Attr{slog.String("a", "1")}, "", r,
+		slogx.ConsolidateAttrsOptions{DeferResolve: true})
+	if resolved != 0 {
+		t.Errorf("expected value not to be resolved, resolved %d times", resolved)
+		return
+	}
+	if len(attrs) != 2 || attrs[0].Value.String() != "2" || attrs[1].Value.Kind() != slog.KindLogValuer {
+		t.Errorf("unexpected attributes: %v", attrs)
+		return
+	}
+
+	attrs = slogx.ConsolidateAttrs(nil, "", r)
+	if resolved != 1 || attrs[1].Value.String() != "value" {
+		t.Errorf("expected value to be resolved once, resolved %d times: %v", resolved, attrs)
+	}
+}
+
+func TestUniqAttrsMergesGroups(t *testing.T) {
+	attrs := slogx.UniqAttrs([]slog.Attr{
+		slog.Group("a", slog.Int("x", 1)),
+		slog.Int("b", 2),
+		slog.Group("a", slog.Int("y", 2), slog.Int("x", 3)),
+	})
+	expected := `[a=[x=3 y=2] b=2]`
+	if actual := fmt.Sprintf("%v", attrs); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	attrs = slogx.NestAttrs([]string{"a", "b"}, []slog.Attr{slog.Int("x", 1)})
+	if actual := fmt.Sprintf("%v", attrs); actual != `[a=[b=[x=1]]]` {
+		t.Errorf("expected nested groups, got %s", actual)
+	}
+}
@@ -50,6 +50,13 @@ func (b *Buffer) String() string {
 	return string(*b)
 }
 
+// Truncate discards all but the first n bytes in the buffer.
+//
+// It panics if n is negative or greater than the length of the buffer.
+func (b *Buffer) Truncate(n int) {
+	*b = (*b)[:n]
+}
+
 // Write handles writing bytes to the buffer.
 func (b *Buffer) Write(p []byte) (int, error) {
 	*b = append(*b, p...)
@@ -3,7 +3,6 @@ package slogx
 import (
 	"context"
 	"log/slog"
-	"runtime"
 	"time"
 
 	"go.innotegrity.dev/errorx"
@@ -19,8 +18,8 @@ type errorOptionsContext struct{}
 
 // ErrorOptions stores options for working with error records.
 type ErrorOptions struct {
-	// AdjustFrameCount indicates a number of frames to adjust the skip by when calling runtime.Callers. By default,
-	// 3 frames are skipped when creating the record.
+	// AdjustFrameCount indicates a number of frames to adjust the skip by when capturing the caller. By default, the
+	// caller of NewErrorRecord() is used as the source of the record.
 	AdjustFrameCount int
 
 	// IncludeFileLine indicates whether or not to invoke runtime.Callers to get the program counter in order to retrieve
@@ -73,10 +72,8 @@ func NewErrorRecord(ctx context.Context, level slog.Leveler, msg string, err err
 	// include program counter, if desired
 	pc := uintptr(0)
 	if opts.IncludeFileLine {
-		var pcs [1]uintptr
-		// skip runtime.Callers, this function
-		runtime.Callers(2+opts.AdjustFrameCount, pcs[:])
-		pc = pcs[0]
+		// skip this function
+		pc = callerPC(1 + opts.AdjustFrameCount)
 	}
 
 	// create the record and attach the error as an attribute
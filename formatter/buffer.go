@@ -10,6 +10,9 @@ import (
 )
 
 // BufferFormatter describes the interface a formatter which outputs a record to a buffer must implement.
+//
+// A single formatter is shared by a handler and every handler created from it, so FormatRecord may be called
+// concurrently. Implementations must be safe for concurrent use and guard any state shared between records.
 type BufferFormatter interface {
 	// FormatRecord should take the data from the record and format it as needed, storing it in the returned buffer.
 	FormatRecord(context.Context, time.Time, slogx.Level, uintptr, string, []slog.Attr) (*slogx.Buffer, error)
@@ -23,3 +26,29 @@ type ColorBufferFormatter interface {
 	// IsColorized should return whether or not the formatter uses colorized output.
 	IsColorized() bool
 }
+
+// DuplicateKeyFormatter describes the interface a formatter which handles attributes with duplicate keys itself may
+// implement.
+//
+// Handlers normally remove duplicate attributes before formatting a record, keeping only the last value for each key.
+// Handlers in the handler package pass every attribute to a formatter implementing this interface whose
+// KeepsDuplicateKeys() function returns true instead.
+type DuplicateKeyFormatter interface {
+	// KeepsDuplicateKeys should return whether or not the formatter needs to receive attributes with duplicate keys.
+	KeepsDuplicateKeys() bool
+}
+
+// RecordAttrsFormatter describes the interface a formatter which combines a handler's attributes with a record's
+// attributes itself may implement.
+//
+// Handlers in the handler package which support this interface pass the attributes and groups added to the handler
+// along with the record to FormatRecordAttrs() instead of consolidating the attributes and calling FormatRecord().
+// This allows the formatter to skip work which is redundant for a slice it consolidated itself, such as sorting a
+// copy of it. The output must be identical to calling FormatRecord() with the consolidated attributes.
+type RecordAttrsFormatter interface {
+	BufferFormatter
+
+	// FormatRecordAttrs should combine the attributes with those from the record, nesting the record's attributes
+	// within the groups, and format the record, storing it in the returned buffer.
+	FormatRecordAttrs(ctx context.Context, r slog.Record, attrs []slog.Attr, groups []string) (*slogx.Buffer, error)
+}
@@ -5,8 +5,12 @@ import (
 	"encoding"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"log/slog"
 
@@ -20,6 +24,9 @@ const (
 	// ConsoleFormatterAttrsPart is the part used by PartOrder for all attributes.
 	ConsoleFormatterAttrsPart = "attrs"
 
+	// ConsoleFormatterDeltaTimePart is the part used for PartOrder for the time elapsed since the previous record.
+	ConsoleFormatterDeltaTimePart = "deltatime"
+
 	// ConsoleFormatterLevelPart is the part used for PartOrder for the message level.
 	ConsoleFormatterLevelPart = "level"
 
@@ -80,6 +87,9 @@ func ConsoleFormatterAttrRegexPart(attrKey string) ConsoleFormatterPart {
 	return ConsoleFormatterPart(fmt.Sprintf("%s%s", consoleFormatterAttrRegexPart, attrKey))
 }
 
+// ansiEscapeRegex matches ANSI escape sequences used for colorizing output.
+var ansiEscapeRegex = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
 // consoleFormatterOptionsContext can be used to retrieve the options used by the formatter from the context.
 type consoleFormatterOptionsContext struct{}
 
@@ -93,27 +103,121 @@ type ConsoleFormatterOptions struct {
 	// If nil, attributes are simply printed unchanged as key=value.
 	AttrFormatter FormatAttrFn
 
+	// AttrPriority is a list of attribute keys to print before any other attributes, in the order given.
+	//
+	// Use a single period (.) to separate group name from attribute name if the attribute is nested within a group
+	// (eg: GROUP.ATTRIBUTE). The remaining attributes follow in their usual order, sorted if SortAttributes is true.
+	// Note that this *only* affects the output for ConsoleFormatterAttrsPart.
+	AttrPriority []string
+
+	// AttrTimeLayout is the layout to use when printing time values in attributes.
+	//
+	// Time values are converted to TimeZone before they are printed. Use AttrTimeLayoutUnix or AttrTimeLayoutUnixMilli
+	// to print them as a number since the Unix epoch instead. If empty, time.RFC3339 is used.
+	AttrTimeLayout string
+
+	// ContinuationLinePrefix is printed at the start of each continuation line of a multi-line message.
+	//
+	// If the prefix is shorter than the column at which the message starts, it is padded with spaces so the
+	// continuation lines still align under the message. This only applies if IndentContinuationLines is true.
+	ContinuationLinePrefix string
+
+	// DeterministicTime indicates whether or not to print DeterministicTimeValue in place of the time of the record.
+	//
+	// This makes the output stable for golden-file tests. The time part and any delta time part are both replaced,
+	// while time values in attributes are still printed as usual. Combine this with SortAttributes and EnableColor set
+	// to false for fully deterministic output.
+	DeterministicTime bool
+
+	// DurationFormat determines how duration values in attributes are printed.
+	//
+	// By default, durations are printed using their String() function.
+	DurationFormat DurationFormat
+
 	// EnableColor determines whether or not to enable colorized output.
 	EnableColor bool
 
+	// GroupSeparator is the separator used to join group and attribute keys when referring to attributes nested
+	// within groups.
+	//
+	// Wherever the other options describe using a single period (.) to separate group and attribute names, this
+	// separator is used instead. By default, a single period (.) is used.
+	GroupSeparator string
+
 	// IgnoreAttrs is a list of regular expressions to use for matching attributes which should not be printed.
 	//
 	// Note that this only applies to attributes and not defined parts like the level, message, source or time. If you
 	// want to ignore those, simply leave them out of the PartOrder array.
 	//
+	// If a regular expression matches the key of a group (eg: GROUP or GROUP.NESTED), the entire group is not printed.
+	//
 	// If any regular expression does not compile, it is simply ignored.
 	IgnoreAttrs []string
 
+	// IndentContinuationLines determines whether or not to indent continuation lines of a multi-line message so they
+	// align under the message part rather than starting at the beginning of the line.
+	//
+	// The message is indented after MessageFormatter has been called. Any ANSI color sequences are ignored when
+	// determining the column at which the message starts.
+	IndentContinuationLines bool
+
 	// LevelFormatter is the middleware formatting function to call to format the level.
 	//
 	// If nil, the level is printed using FormatLevelValueDefault().
 	LevelFormatter FormatLevelValueFn
 
+	// MaxGroupDepth is the maximum number of nested groups to flatten into attribute keys.
+	//
+	// Any group nested deeper is printed as a single attribute whose value is the group encoded as a JSON object
+	// (eg: with a depth of 1, GROUP1.GROUP2={"KEY":"value"}). If this is 0 or less, groups are always flattened.
+	MaxGroupDepth int
+
+	// MaxValueLength is the maximum length, in bytes, of an attribute value.
+	//
+	// String values and values of any other type which are printed as strings are truncated to this length with a
+	// suffix indicating how many bytes were removed. Groups are never truncated as a whole; only their individual
+	// values are. The value is truncated after any attribute formatter has been called. If this is 0 or less,
+	// values are never truncated.
+	MaxValueLength int
+
+	// MaxRecordBytes is the maximum size, in bytes, of a formatted record.
+	//
+	// If a formatted record exceeds this size, it is handled according to OversizeRecordMode. This is useful for
+	// protecting transports which reject messages over a certain size. If this is 0 or less, the size of records is
+	// not limited.
+	MaxRecordBytes int
+
 	// MessageFormatter is the middlware formatting function to call to format the message.
 	//
 	// If nil, the message is printed as-is.
 	MessageFormatter FormatMessageValueFn
 
+	// OmitEmpty determines whether or not to skip attributes with empty values.
+	//
+	// Attributes are considered empty if their value is nil, an empty string or a group whose attributes are all
+	// empty. Explicitly set false and 0 values are still printed. Use OmitZero to skip those as well. Values are
+	// checked after any attribute formatter has been called.
+	OmitEmpty bool
+
+	// OmitTrailingNewline indicates whether or not to leave out the newline character normally appended to the end of
+	// each record.
+	//
+	// Handlers writing to files or consoles should leave this disabled while transports which frame messages
+	// themselves may wish to enable it.
+	OmitTrailingNewline bool
+
+	// OmitZero determines whether or not to skip attributes with zero values.
+	//
+	// This is a stricter version of OmitEmpty which also skips false, 0, zero durations, the zero time and any other
+	// value which is the zero value of its type.
+	OmitZero bool
+
+	// OversizeRecordMode determines what to do with a record whose formatted output exceeds MaxRecordBytes.
+	//
+	// By default, the record is replaced with a reduced record containing just the time, level, message and source
+	// along with a truncated=true attribute.
+	OversizeRecordMode OversizeRecordMode
+
 	// PartOrder is the order in which to print the various parts of the message.
 	//
 	// The following values are valid for the string:
@@ -123,6 +227,8 @@ type ConsoleFormatterOptions struct {
 	//                                 to separate group name from attribute name if the attribute is nested within a
 	//                                 group; if the regex does not compile, it is ignored
 	// ConsoleFormatterAttrsPart - all attributes
+	// ConsoleFormatterDeltaTimePart - the time elapsed since the previous record formatted by the formatter (eg:
+	//                                 +0.250s); the first record prints the time of the record instead
 	// ConsoleFormatterLevelPart - the log level of the message from the record as a string
 	// ConsoleFormatterMessagePart - the message from the record
 	// ConsoleFormatterSourcePart - the location at which the message was logged
@@ -145,6 +251,11 @@ type ConsoleFormatterOptions struct {
 	// Note that this *only* affects the output for ConsoleFormatterAttrsPart.
 	SortAttributes bool
 
+	// SortAttributesCaseInsensitive determines whether or not to ignore case when sorting attributes.
+	//
+	// This only applies if SortAttributes is true.
+	SortAttributesCaseInsensitive bool
+
 	// SourceFormatter is the middleware formatting function to call to format the source code location where the record
 	// was created.
 	//
@@ -168,6 +279,12 @@ type ConsoleFormatterOptions struct {
 	// If nil, the time is printed using FormatTimeValueDefault().
 	TimeFormatter FormatTimeValueFn
 
+	// TimeZone is the location to convert the time of the record and any time values in attributes to before they are
+	// formatted.
+	//
+	// If nil, times are converted to UTC. Use time.Local to print times in the local time zone.
+	TimeZone *time.Location
+
 	// UniqueAttributesOnly indicates whether or not to only print unique attributes.
 	//
 	// If multiple attributes are present within the same group with the same key name, only the latest attribute
@@ -182,6 +299,24 @@ type ConsoleFormatterOptions struct {
 	// The only exception to this rule is that attributes which are not nested and overlap with the time, level, source
 	// or message keys will be ignored as time, level, source and message are core parts of the output.
 	UniqueAttributesOnly bool
+
+	// ValueRedactPatterns is a list of regular expressions to use for matching sensitive data within string attribute
+	// values.
+	//
+	// Any part of a string value matching a pattern is replaced with RedactedValue. The name of any entry in
+	// BuiltinValueRedactPatterns (eg: email) may be used in place of a regular expression to enable that built-in
+	// pattern. Values are redacted after any attribute formatter has been called and before they are truncated.
+	//
+	// If any regular expression does not compile, it is simply ignored.
+	ValueRedactPatterns []string
+
+	// ValueTransformers is a map of functions to call to transform attribute values of a specific kind before they
+	// are formatted.
+	//
+	// The function registered for the kind of the resolved value is called before SpecificAttrFormatter or
+	// AttrFormatter (eg: to always render slog.KindTime values in a specific layout). Values such as []byte use
+	// slog.KindAny, so their transformer should check the underlying type using Value.Any().
+	ValueTransformers map[slog.Kind]func(slog.Value) slog.Value
 }
 
 // ConsoleFormatterOptionsFromContext retrieves the formatter options from the context.
@@ -206,6 +341,7 @@ func ContextWithConsoleFormatterOptions(ctx context.Context, opts ConsoleFormatt
 // DefaultConsoleFormatterOptions returns a default set of options for the console formatter.
 func DefaultConsoleFormatterOptions() ConsoleFormatterOptions {
 	return ConsoleFormatterOptions{
+		GroupSeparator: slogx.DefaultGroupSeparator,
 		IgnoreAttrs:    []string{},
 		LevelFormatter: FormatLevelValueDefault,
 		PartOrder: []ConsoleFormatterPart{
@@ -222,18 +358,115 @@ func DefaultConsoleFormatterOptions() ConsoleFormatterOptions {
 		SortAttributes:  true,
 		SourceFormatter: FormatSourceValueDefault,
 		TimeFormatter: func(ctx context.Context, level slog.Leveler, t time.Time) (string, error) {
-			return t.Local().Format("03:04:05PM"), nil
+			return t.Format("03:04:05PM"), nil
 		},
 		UniqueAttributesOnly: true,
 	}
 }
 
+// ConsoleFormatterOption is an option which can be passed to NewConsoleFormatter() to configure the formatter.
+//
+// ConsoleFormatterOptions is itself an option which replaces any options set before it, so a complete set of options
+// can still be passed to NewConsoleFormatter() directly.
+type ConsoleFormatterOption interface {
+	applyConsoleFormatterOption(opts *ConsoleFormatterOptions)
+}
+
+// applyConsoleFormatterOption replaces the given options with this set of options.
+func (o ConsoleFormatterOptions) applyConsoleFormatterOption(opts *ConsoleFormatterOptions) {
+	*opts = o
+}
+
+// consoleFormatterOptionFn is a function which modifies the options for the console formatter.
+type consoleFormatterOptionFn func(opts *ConsoleFormatterOptions)
+
+// applyConsoleFormatterOption calls the function to modify the given options.
+func (fn consoleFormatterOptionFn) applyConsoleFormatterOption(opts *ConsoleFormatterOptions) {
+	fn(opts)
+}
+
+// WithColor enables or disables colorized output.
+//
+// When enabled, the attribute, level and source formatters and the error attribute formatter are replaced with the
+// same colorized formatters used by DefaultConsoleFormatter(true). When disabled, they are reset to their uncolorized
+// defaults. Pass any options which set those formatters after this option.
+func WithColor(enable bool) ConsoleFormatterOption {
+	return consoleFormatterOptionFn(func(opts *ConsoleFormatterOptions) {
+		opts.EnableColor = enable
+		if enable {
+			opts.AttrFormatter = ColorizeAttrFormatter
+			opts.LevelFormatter = ColorizeLevelFormatter
+			opts.SourceFormatter = ColorizeSourceFormatter
+			opts.SpecificAttrFormatter = map[string]FormatAttrFn{
+				"error": ColorizeErrorAttrFormatter,
+			}
+		} else {
+			opts.AttrFormatter = nil
+			opts.LevelFormatter = FormatLevelValueDefault
+			opts.SourceFormatter = FormatSourceValueDefault
+			opts.SpecificAttrFormatter = nil
+		}
+	})
+}
+
+// WithDeterministicTime determines whether or not to print DeterministicTimeValue in place of the time of the record.
+func WithDeterministicTime(deterministic bool) ConsoleFormatterOption {
+	return consoleFormatterOptionFn(func(opts *ConsoleFormatterOptions) {
+		opts.DeterministicTime = deterministic
+	})
+}
+
+// WithIgnoreAttrs sets the list of regular expressions used to match attributes which should not be printed.
+func WithIgnoreAttrs(patterns ...string) ConsoleFormatterOption {
+	return consoleFormatterOptionFn(func(opts *ConsoleFormatterOptions) {
+		opts.IgnoreAttrs = patterns
+	})
+}
+
+// WithPartOrder sets the order in which to print the various parts of the message.
+func WithPartOrder(parts ...ConsoleFormatterPart) ConsoleFormatterOption {
+	return consoleFormatterOptionFn(func(opts *ConsoleFormatterOptions) {
+		opts.PartOrder = parts
+	})
+}
+
+// WithSortAttributes determines whether or not to sort attributes in the output.
+func WithSortAttributes(sort bool) ConsoleFormatterOption {
+	return consoleFormatterOptionFn(func(opts *ConsoleFormatterOptions) {
+		opts.SortAttributes = sort
+	})
+}
+
+// WithTimeLayout sets the time formatter to one which prints the time of the record using the given layout.
+func WithTimeLayout(layout string) ConsoleFormatterOption {
+	return consoleFormatterOptionFn(func(opts *ConsoleFormatterOptions) {
+		opts.TimeFormatter = func(ctx context.Context, level slog.Leveler, t time.Time) (string, error) {
+			return t.Format(layout), nil
+		}
+	})
+}
+
 // consoleFormatter formats records for output to a console such as stdout, stderr or even a file.
+//
+// The formatter is safe for concurrent use. Everything built while formatting a record is local to the call and the
+// only state shared between records, the time of the last record, is guarded by a mutex.
 type consoleFormatter struct {
 	// unexported variables
+	attrPriority        map[string]int
+	deltaTime           *deltaTimeState
 	ignoredAttrPatterns []*regexp.Regexp
 	options             ConsoleFormatterOptions
+	redactPatterns      []*regexp.Regexp
 	willPrintAttrs      bool
+	willPrintDeltaTime  bool
+}
+
+// deltaTimeState holds the time of the last record formatted by a console formatter.
+//
+// Formatters are frequently shared between handlers, so access to the time is guarded by a mutex.
+type deltaTimeState struct {
+	last time.Time
+	lock sync.Mutex
 }
 
 // DefaultConsoleFormatter returns a console formatter with typical defaults already set.
@@ -262,7 +495,19 @@ func DefaultConsoleFormatter(colorize bool) *consoleFormatter {
 }
 
 // NewConsoleFormatter creates and returns a new console formatter.
-func NewConsoleFormatter(opts ConsoleFormatterOptions) *consoleFormatter {
+//
+// The options are applied in order on top of DefaultConsoleFormatterOptions(). For example:
+//
+//	f := NewConsoleFormatter(WithColor(true), WithSortAttributes(false))
+//
+// For backward compatibility, a ConsoleFormatterOptions object may also be passed, in which case it replaces all of
+// the options applied before it, including the defaults.
+func NewConsoleFormatter(options ...ConsoleFormatterOption) *consoleFormatter {
+	opts := DefaultConsoleFormatterOptions()
+	for _, o := range options {
+		o.applyConsoleFormatterOption(&opts)
+	}
+
 	// set default options
 	if len(opts.PartOrder) == 0 {
 		opts.PartOrder = []ConsoleFormatterPart{
@@ -276,20 +521,30 @@ func NewConsoleFormatter(opts ConsoleFormatterOptions) *consoleFormatter {
 			ConsoleFormatterAttrsPart,
 		}
 	}
+	if opts.GroupSeparator == "" {
+		opts.GroupSeparator = slogx.DefaultGroupSeparator
+	}
 	if opts.PartSeparator == "" {
 		opts.PartSeparator = " "
 	}
 
 	// create the formatter object
 	f := &consoleFormatter{
+		attrPriority:        attrPriorityIndex(opts.AttrPriority),
+		deltaTime:           &deltaTimeState{},
 		ignoredAttrPatterns: []*regexp.Regexp{},
 		options:             opts,
+		redactPatterns:      compileValueRedactPatterns(opts.ValueRedactPatterns),
 		willPrintAttrs:      false,
+		willPrintDeltaTime:  false,
 	}
 	for _, p := range opts.PartOrder {
 		if p.IsSpecificAttr() || p.IsRegexAttr() || p == ConsoleFormatterAttrsPart {
 			f.willPrintAttrs = true
 		}
+		if p == ConsoleFormatterDeltaTimePart {
+			f.willPrintDeltaTime = true
+		}
 	}
 	for _, p := range opts.IgnoreAttrs {
 		regex, err := regexp.Compile(p)
@@ -304,35 +559,65 @@ func NewConsoleFormatter(opts ConsoleFormatterOptions) *consoleFormatter {
 // handler.
 //
 // By default, duration values in attributes are formatted using the String() function and time values are formatted
-// in UTC time using the RFC3339 layout.
+// in TimeZone using the RFC3339 layout. Use the AttrTimeLayout and DurationFormat options to change this.
+//
+// If MaxRecordBytes is set and the formatted record exceeds it, the record is handled according to OversizeRecordMode
+// and ErrRecordTooLarge may be returned.
 func (f *consoleFormatter) FormatRecord(ctx context.Context, timestamp time.Time, level slogx.Level, pc uintptr,
 	msg string, attrs []slog.Attr) (*slogx.Buffer, error) {
 
+	// the previous time is swapped out once so that reformatting an oversized record prints the same delta
+	var last time.Time
+	if f.willPrintDeltaTime {
+		last = f.swapDeltaTime(timestamp)
+	}
+
+	buf, err := f.formatRecord(ctx, timestamp, last, level, pc, msg, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return limitRecordSize(buf, f.options.MaxRecordBytes, f.options.OversizeRecordMode,
+		func() (*slogx.Buffer, error) {
+			return f.formatRecord(ctx, timestamp, last, level, pc, msg, []slog.Attr{slog.Bool("truncated", true)})
+		})
+}
+
+// formatRecord handles formatting the given record and outputting it into the returned buffer without limiting the
+// size of the output.
+//
+// The last parameter is the time of the previous record, which is zero if there is no previous record.
+func (f *consoleFormatter) formatRecord(ctx context.Context, timestamp, last time.Time, level slogx.Level,
+	pc uintptr, msg string, attrs []slog.Attr) (*slogx.Buffer, error) {
+
 	var err error
 	var strVal string
 	buf := slogx.NewBuffer()
 	formatterCtx := ContextWithConsoleFormatterOptions(ctx, f.options)
 
-	// flatten attributes
+	// flatten attributes, keeping the originals for the message formatter
+	recordAttrs := attrs
 	var attrMap map[string]slog.Value
 	if f.willPrintAttrs {
 		if f.options.SortAttributes {
-			attrs = slogx.SortAttrs(attrs)
+			attrs = slogx.SortAttrsWithOptions(attrs, slogx.SortAttrsOptions{
+				CaseInsensitive: f.options.SortAttributesCaseInsensitive,
+			})
 		}
-		attrs = slogx.FlattenAttrs(attrs)
+		attrs = prioritizeAttrs(slogx.FlattenAttrsWithOptions(attrs, slogx.FlattenAttrsOptions{
+			MaxGroupDepth: f.options.MaxGroupDepth,
+			Separator:     f.options.GroupSeparator,
+		}), "", f.options.GroupSeparator, f.attrPriority)
 	}
 
 	// now let's actually print the parts out
-	lastBufLen := 0
 	printedAttrs := generic.NewSet[string]()
 	for _, part := range f.options.PartOrder {
-		// only print the parts separator if we actually printed something before
-		currentBufLen := buf.Len()
-		if currentBufLen > 0 && currentBufLen != lastBufLen {
-			fmt.Fprintf(buf, "%s", f.options.PartSeparator)
-			currentBufLen = buf.Len()
+		// print the parts separator if we printed something before - it's removed again if the part prints nothing
+		separatorStart := buf.Len()
+		if separatorStart > 0 {
+			buf.WriteString(f.options.PartSeparator)
 		}
-		lastBufLen = currentBufLen
+		partStart := buf.Len()
 
 		switch part {
 		case ConsoleFormatterAttrsPart:
@@ -340,6 +625,20 @@ func (f *consoleFormatter) FormatRecord(ctx context.Context, timestamp time.Time
 				return nil, err
 			}
 
+		case ConsoleFormatterDeltaTimePart:
+			if last.IsZero() {
+				strVal, err = formatRecordTime(formatterCtx, level, timestamp, f.options.TimeFormatter,
+					f.options.TimeZone, f.options.DeterministicTime)
+				if err != nil {
+					return nil, err
+				}
+			} else if f.options.DeterministicTime {
+				strVal = DeterministicTimeValue
+			} else {
+				strVal = fmt.Sprintf("%+.3fs", timestamp.Sub(last).Seconds())
+			}
+			fmt.Fprintf(buf, "%s", strVal)
+
 		case ConsoleFormatterLevelPart:
 			if f.options.LevelFormatter != nil {
 				strVal, err = f.options.LevelFormatter(formatterCtx, level)
@@ -353,7 +652,8 @@ func (f *consoleFormatter) FormatRecord(ctx context.Context, timestamp time.Time
 
 		case ConsoleFormatterMessagePart:
 			if f.options.MessageFormatter != nil {
-				strVal, err = f.options.MessageFormatter(formatterCtx, level, msg)
+				strVal, err = formatMessage(formatterCtx, f.options.MessageFormatter, level, msg, recordAttrs,
+					f.options.GroupSeparator, f.ignoredAttrPatterns, f.redactPatterns)
 			} else {
 				strVal = msg
 				err = nil
@@ -361,6 +661,9 @@ func (f *consoleFormatter) FormatRecord(ctx context.Context, timestamp time.Time
 			if err != nil {
 				return nil, err
 			}
+			if f.options.IndentContinuationLines && strings.Contains(strVal, "\n") {
+				strVal = f.indentContinuationLines(buf, strVal)
+			}
 			fmt.Fprintf(buf, "%s", strVal)
 
 		case ConsoleFormatterSourcePart:
@@ -375,11 +678,8 @@ func (f *consoleFormatter) FormatRecord(ctx context.Context, timestamp time.Time
 			fmt.Fprintf(buf, "%s", strVal)
 
 		case ConsoleFormatterTimePart:
-			if f.options.TimeFormatter != nil {
-				strVal, err = f.options.TimeFormatter(formatterCtx, level, timestamp)
-			} else {
-				strVal, err = FormatTimeValueDefault(formatterCtx, level, timestamp)
-			}
+			strVal, err = formatRecordTime(formatterCtx, level, timestamp, f.options.TimeFormatter,
+				f.options.TimeZone, f.options.DeterministicTime)
 			if err != nil {
 				return nil, err
 			}
@@ -398,17 +698,19 @@ func (f *consoleFormatter) FormatRecord(ctx context.Context, timestamp time.Time
 			} else if attrRegex := part.GetAttrRegex(); attrRegex != "" { // attribute regex
 				regex, err := regexp.Compile(attrRegex)
 				if err == nil {
-					for attr, val := range attrMap {
-						currentBufLen = buf.Len()
-						if currentBufLen > 0 && currentBufLen != lastBufLen {
-							fmt.Fprintf(buf, "%s", f.options.PartSeparator)
-							currentBufLen = buf.Len()
-						}
-						lastBufLen = currentBufLen
+					// iterate over the matching keys in sorted order so the output is deterministic
+					matchedAttrs := []string{}
+					for attr := range attrMap {
 						if regex.MatchString(attr) {
-							if err := f.printAttr(formatterCtx, buf, level, attr, val, printedAttrs); err != nil {
-								return nil, err
-							}
+							matchedAttrs = append(matchedAttrs, attr)
+						}
+					}
+					sort.Strings(matchedAttrs)
+					for _, attr := range matchedAttrs {
+						if err := f.printSeparated(buf, partStart, func() error {
+							return f.printAttr(formatterCtx, buf, level, attr, attrMap[attr], printedAttrs)
+						}); err != nil {
+							return nil, err
 						}
 					}
 				}
@@ -416,10 +718,17 @@ func (f *consoleFormatter) FormatRecord(ctx context.Context, timestamp time.Time
 				fmt.Fprint(buf, part)
 			}
 		}
+
+		// remove the separator if nothing was printed for the part
+		if buf.Len() == partStart {
+			buf.Truncate(separatorStart)
+		}
 	}
 
 	// finally - write the message
-	buf.WriteByte('\n')
+	if !f.options.OmitTrailingNewline {
+		buf.WriteByte('\n')
+	}
 	return buf, nil
 }
 
@@ -428,6 +737,31 @@ func (f consoleFormatter) IsColorized() bool {
 	return f.options.EnableColor
 }
 
+// ResetDeltaTime resets the time of the last record formatted by the formatter.
+//
+// The next record printed with ConsoleFormatterDeltaTimePart will print its time instead of the time elapsed since
+// the previous record.
+func (f *consoleFormatter) ResetDeltaTime() {
+	f.deltaTime.lock.Lock()
+	defer f.deltaTime.lock.Unlock()
+	f.deltaTime.last = time.Time{}
+}
+
+// indentContinuationLines indents every line after the first in the message so that it aligns with the column at
+// which the message will be printed in the buffer.
+func (f consoleFormatter) indentContinuationLines(buf *slogx.Buffer, msg string) string {
+	line := buf.String()
+	if i := strings.LastIndexByte(line, '\n'); i != -1 {
+		line = line[i+1:]
+	}
+	column := utf8.RuneCountInString(ansiEscapeRegex.ReplaceAllString(line, ""))
+	indent := f.options.ContinuationLinePrefix
+	if padding := column - utf8.RuneCountInString(indent); padding > 0 {
+		indent += strings.Repeat(" ", padding)
+	}
+	return strings.ReplaceAll(msg, "\n", "\n"+indent)
+}
+
 // printAttr prints the given
 func (f consoleFormatter) printAttr(ctx context.Context, buf *slogx.Buffer, level slog.Leveler, attrKey string,
 	attrValue slog.Value, printedAttrs generic.Set[string]) error {
@@ -437,25 +771,23 @@ func (f consoleFormatter) printAttr(ctx context.Context, buf *slogx.Buffer, leve
 		return nil
 	}
 
-	// ignore the attribute if the key matches
-	for _, p := range f.ignoredAttrPatterns {
-		if p.MatchString(attrKey) {
-			return nil
-		}
+	// ignore the attribute if the key or any group containing it matches
+	if isIgnoredKey(attrKey, f.options.GroupSeparator, f.ignoredAttrPatterns) {
+		return nil
 	}
 
 	// extract the group name and attribute from the key
 	group := ""
 	actualAttrKey := attrKey
-	groupIndex := strings.LastIndex(attrKey, ".")
+	groupIndex := strings.LastIndex(attrKey, f.options.GroupSeparator)
 	if groupIndex != -1 {
 		group = attrKey[:groupIndex]
-		actualAttrKey = attrKey[groupIndex+1:]
+		actualAttrKey = attrKey[groupIndex+len(f.options.GroupSeparator):]
 	}
 
 	// format the attribute using any formatter functions first
 	formattedKey := attrKey
-	formattedValue := attrValue.Resolve()
+	formattedValue := transformValue(attrValue.Resolve(), f.options.ValueTransformers)
 	var err error
 	if fn, ok := f.options.SpecificAttrFormatter[attrKey]; ok && fn != nil {
 		formattedKey, formattedValue, err = fn(ctx, level, group, actualAttrKey, formattedValue)
@@ -469,42 +801,47 @@ func (f consoleFormatter) printAttr(ctx context.Context, buf *slogx.Buffer, leve
 		}
 	}
 
+	if omitValue(formattedValue, f.options.OmitEmpty, f.options.OmitZero) {
+		return nil
+	}
+
 	// format the key/value
 	switch formattedValue.Kind() {
 	case slog.KindBool:
 		fmt.Fprintf(buf, "%s=%t", formattedKey, formattedValue.Bool())
 	case slog.KindString:
-		fmt.Fprintf(buf, "%s=%s", formattedKey, formattedValue.String())
+		fmt.Fprintf(buf, "%s=%s", formattedKey,
+			truncateValue(redactValue(formattedValue.String(), f.redactPatterns), f.options.MaxValueLength))
 	case slog.KindDuration:
-		fmt.Fprintf(buf, "%s=%s", formattedKey, formattedValue.Duration().String())
+		fmt.Fprintf(buf, "%s=%s", formattedKey, f.options.DurationFormat.Format(formattedValue.Duration()))
 	case slog.KindTime:
-		fmt.Fprintf(buf, "%s=%s", formattedKey, formattedValue.Time().UTC().Format(time.RFC3339))
-	case slog.KindFloat64:
-		fmt.Fprintf(buf, "%s=%f", formattedKey, formattedValue.Float64())
-	case slog.KindInt64:
-		fmt.Fprintf(buf, "%s=%d", formattedKey, formattedValue.Int64())
-	case slog.KindUint64:
-		fmt.Fprintf(buf, "%s=%d", formattedKey, formattedValue.Uint64())
+		fmt.Fprintf(buf, "%s=%s", formattedKey,
+			formatAttrTime(formattedValue.Time(), f.options.AttrTimeLayout, f.options.TimeZone))
+	case slog.KindFloat64, slog.KindInt64, slog.KindUint64:
+		fmt.Fprintf(buf, "%s=%s", formattedKey, formatConsoleNumber(formattedValue))
 	case slog.KindGroup:
-		for i, attr := range formattedValue.Group() {
-			if i > 0 {
-				buf.WriteString(f.options.PartSeparator)
-			}
-			groupKey := fmt.Sprintf("%s.%s", attrKey, attr.Key)
-			if err := f.printAttr(ctx, buf, level, groupKey, attr.Value, printedAttrs); err != nil {
+		groupStart := buf.Len()
+		for _, attr := range formattedValue.Group() {
+			groupKey := attrKey + f.options.GroupSeparator + attr.Key
+			if err := f.printSeparated(buf, groupStart, func() error {
+				return f.printAttr(ctx, buf, level, groupKey, attr.Value, printedAttrs)
+			}); err != nil {
 				return err
 			}
 			printedAttrs.Add(groupKey)
 		}
 	default:
-		if tm, ok := formattedValue.Any().(encoding.TextMarshaler); ok {
+		if n, ok := formattedValue.Any().(colorizedNumber); ok {
+			fmt.Fprintf(buf, "%s=%s", formattedKey, n)
+		} else if tm, ok := formattedValue.Any().(encoding.TextMarshaler); ok {
 			output, err := tm.MarshalText()
 			if err != nil {
 				return err
 			}
-			fmt.Fprintf(buf, "%s=%s", formattedKey, string(output))
+			fmt.Fprintf(buf, "%s=%s", formattedKey, truncateValue(string(output), f.options.MaxValueLength))
 		} else {
-			fmt.Fprintf(buf, "%s=%+v", formattedKey, formattedValue.Any())
+			fmt.Fprintf(buf, "%s=%s", formattedKey,
+				truncateValue(fmt.Sprintf("%+v", formattedValue.Any()), f.options.MaxValueLength))
 		}
 	}
 	printedAttrs.Add(attrKey)
@@ -515,47 +852,118 @@ func (f consoleFormatter) printAttr(ctx context.Context, buf *slogx.Buffer, leve
 func (f consoleFormatter) printAttrs(ctx context.Context, buf *slogx.Buffer, level slog.Leveler, attrs []slog.Attr,
 	printedAttrs generic.Set[string]) error {
 
-	lastBufLen := buf.Len()
+	attrsStart := buf.Len()
 	for _, attr := range attrs {
 		// already printed the given key
 		if printedAttrs.Contains(attr.Key) {
 			continue
 		}
 
-		// only print the parts separator if we actually printed something before
-		currentBufLen := buf.Len()
-		if currentBufLen > 0 && currentBufLen != lastBufLen {
-			buf.WriteString(f.options.PartSeparator)
-			currentBufLen = buf.Len()
-		}
-		lastBufLen = currentBufLen
-
 		// print the attribute
-		if err := f.printAttr(ctx, buf, level, attr.Key, attr.Value, printedAttrs); err != nil {
+		if err := f.printSeparated(buf, attrsStart, func() error {
+			return f.printAttr(ctx, buf, level, attr.Key, attr.Value, printedAttrs)
+		}); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// printSeparated calls fn to print to the buffer, preceding the output with the parts separator if anything has been
+// printed to the buffer since start.
+//
+// If fn does not print anything, the separator is removed again so that no stray separators are left in the output.
+func (f consoleFormatter) printSeparated(buf *slogx.Buffer, start int, fn func() error) error {
+	separatorStart := buf.Len()
+	if separatorStart > start {
+		buf.WriteString(f.options.PartSeparator)
+	}
+	printStart := buf.Len()
+	if err := fn(); err != nil {
+		return err
+	}
+	if buf.Len() == printStart {
+		buf.Truncate(separatorStart)
+	}
+	return nil
+}
+
 // ColorizeAttrFormatter is a customized formatter for colorizing attribute keys.
 func ColorizeAttrFormatter(ctx context.Context, level slog.Leveler, group, attrKey string,
 	attrValue slog.Value) (string, slog.Value, error) {
 
 	c := color.New(color.FgHiBlue)
 	if group != "" {
-		attrKey = fmt.Sprintf("%s.%s", group, attrKey)
+		attrKey = group + ConsoleFormatterOptionsFromContext(ctx).GroupSeparator + attrKey
 	}
 	return c.Sprint(attrKey), attrValue, nil
 }
 
+// ColorizeByThreshold returns an attribute formatter which colorizes numeric values using below if the value is less
+// than threshold and above otherwise.
+//
+// Only integer and float values are colorized; any other value is returned unchanged. If either color is nil, values
+// on that side of the threshold are not colorized. Keys are returned uncolorized. The console formatter prints a
+// colorized number exactly as it prints the number without color, apart from the color sequences, so it is not
+// truncated using MaxValueLength. The colorized value is not a number anymore, so this should only be used with the
+// console formatter.
+//
+// For example, to print negative numbers in red:
+//
+//	opts.SpecificAttrFormatter["balance"] = formatter.ColorizeByThreshold(0, color.New(color.FgHiRed), nil)
+func ColorizeByThreshold(threshold float64, below, above *color.Color) FormatAttrFn {
+	return func(ctx context.Context, level slog.Leveler, group, attrKey string, attrValue slog.Value) (string,
+		slog.Value, error) {
+
+		if group != "" {
+			attrKey = group + ConsoleFormatterOptionsFromContext(ctx).GroupSeparator + attrKey
+		}
+
+		var n float64
+		switch attrValue.Kind() {
+		case slog.KindFloat64:
+			n = attrValue.Float64()
+		case slog.KindInt64:
+			n = float64(attrValue.Int64())
+		case slog.KindUint64:
+			n = float64(attrValue.Uint64())
+		default:
+			return attrKey, attrValue, nil
+		}
+		c := above
+		if n < threshold {
+			c = below
+		}
+		if c == nil {
+			return attrKey, attrValue, nil
+		}
+		return attrKey, slog.AnyValue(colorizedNumber(c.Sprint(formatConsoleNumber(attrValue)))), nil
+	}
+}
+
+// colorizedNumber holds a number which has already been formatted and colorized by ColorizeByThreshold().
+type colorizedNumber string
+
+// formatConsoleNumber formats the given integer or float value the way the console formatter prints numbers.
+func formatConsoleNumber(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindFloat64:
+		return strconv.FormatFloat(v.Float64(), 'f', 6, 64)
+	case slog.KindInt64:
+		return strconv.FormatInt(v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.FormatUint(v.Uint64(), 10)
+	}
+	return v.String()
+}
+
 // ColorizeErrorAttrFormatter is a customized formatter for colorizing error keys.
 func ColorizeErrorAttrFormatter(ctx context.Context, level slog.Leveler, group, attrKey string,
 	attrValue slog.Value) (string, slog.Value, error) {
 
 	c := color.New(color.FgHiRed)
 	if group != "" {
-		attrKey = fmt.Sprintf("%s.%s", group, attrKey)
+		attrKey = group + ConsoleFormatterOptionsFromContext(ctx).GroupSeparator + attrKey
 	}
 	return c.Sprint(attrKey), attrValue, nil
 }
@@ -604,3 +1012,12 @@ func ColorizeSourceFormatter(ctx context.Context, level slog.Leveler, pc uintptr
 	f := runtimex.FrameFromPC(pc)
 	return color.New(color.FgHiWhite).Sprintf("%s", f), nil
 }
+
+// swapDeltaTime stores the given time as the time of the last record and returns the previously stored time.
+func (f consoleFormatter) swapDeltaTime(timestamp time.Time) time.Time {
+	f.deltaTime.lock.Lock()
+	defer f.deltaTime.lock.Unlock()
+	last := f.deltaTime.last
+	f.deltaTime.last = timestamp
+	return last
+}
@@ -0,0 +1,392 @@
+package formatter_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"log/slog"
+
+	"github.com/fatih/color"
+	"go.innotegrity.dev/errorx"
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/formatter/formattertest"
+)
+
+func TestConsoleFormatterIndentContinuationLines(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{prefix: "", expected: "INF > first line\n      second line\n      third line\n"},
+		{prefix: "|", expected: "INF > first line\n|     second line\n|     third line\n"},
+	}
+	for _, test := range tests {
+		opts := formatter.DefaultConsoleFormatterOptions()
+		opts.ContinuationLinePrefix = test.prefix
+		opts.IndentContinuationLines = true
+		opts.PartOrder = []formatter.ConsoleFormatterPart{
+			formatter.ConsoleFormatterLevelPart,
+			">",
+			formatter.ConsoleFormatterMessagePart,
+		}
+		f := formatter.NewConsoleFormatter(opts)
+		buf, err := f.FormatRecord(context.Background(), time.Now(), slogx.LevelInfo, 0,
+			"first line\nsecond line\nthird line", nil)
+		if err != nil {
+			t.Errorf("failed to format record: %s", err.Error())
+			return
+		}
+		if buf.String() != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, buf.String())
+		}
+	}
+}
+
+func TestConsoleFormatterRegexPartOrder(t *testing.T) {
+	opts := formatter.DefaultConsoleFormatterOptions()
+	opts.PartOrder = []formatter.ConsoleFormatterPart{
+		formatter.ConsoleFormatterMessagePart,
+		formatter.ConsoleFormatterAttrRegexPart(`error\..*`),
+		formatter.ConsoleFormatterAttrsPart,
+	}
+	f := formatter.NewConsoleFormatter(opts)
+	expected := "message error.code=42 error.msg=failed key=value\n"
+	for i := 0; i < 20; i++ {
+		output, err := formattertest.FormatToString(f, slogx.LevelError, "message",
+			slog.String("key", "value"),
+			slog.Group("error", slog.String("msg", "failed"), slog.Int("code", 42)),
+		)
+		if err != nil {
+			t.Errorf("failed to format record: %s", err.Error())
+			return
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+			return
+		}
+	}
+}
+
+func TestConsoleFormatterPartSeparators(t *testing.T) {
+	tests := []struct {
+		partOrder   []formatter.ConsoleFormatterPart
+		ignoreAttrs []string
+		expected    string
+	}{
+		{
+			partOrder: []formatter.ConsoleFormatterPart{
+				formatter.ConsoleFormatterMessagePart,
+				formatter.ConsoleFormatterAttrPart("error.code"),
+				formatter.ConsoleFormatterAttrRegexPart(`^error\.c`),
+			},
+			expected: "message error.code=42\n",
+		},
+		{
+			partOrder: []formatter.ConsoleFormatterPart{
+				formatter.ConsoleFormatterMessagePart,
+				formatter.ConsoleFormatterAttrRegexPart(`error\..*`),
+				formatter.ConsoleFormatterAttrsPart,
+				formatter.ConsoleFormatterAttrPart("missing"),
+			},
+			ignoreAttrs: []string{`^error\.code$`, `^other$`},
+			expected:    "message error.msg=failed key=value\n",
+		},
+		{
+			partOrder: []formatter.ConsoleFormatterPart{
+				formatter.ConsoleFormatterAttrRegexPart(`^missing`),
+				formatter.ConsoleFormatterMessagePart,
+				formatter.ConsoleFormatterAttrsPart,
+			},
+			ignoreAttrs: []string{`.*`},
+			expected:    "message\n",
+		},
+	}
+	for _, test := range tests {
+		opts := formatter.DefaultConsoleFormatterOptions()
+		opts.IgnoreAttrs = test.ignoreAttrs
+		opts.PartOrder = test.partOrder
+		output, err := formattertest.FormatToString(formatter.NewConsoleFormatter(opts), slogx.LevelError, "message",
+			slog.String("key", "value"),
+			slog.String("other", "value"),
+			slog.Group("error", slog.String("msg", "failed"), slog.Int("code", 42)),
+		)
+		if err != nil {
+			t.Errorf("failed to format record: %s", err.Error())
+			return
+		}
+		if output != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, output)
+		}
+	}
+}
+
+func TestConsoleFormatterAttrPriority(t *testing.T) {
+	opts := formatter.DefaultConsoleFormatterOptions()
+	opts.AttrPriority = []string{"request_id", "http.status"}
+	opts.PartOrder = []formatter.ConsoleFormatterPart{formatter.ConsoleFormatterAttrsPart}
+	opts.OmitTrailingNewline = true
+	f := formatter.NewConsoleFormatter(opts)
+	output, err := formattertest.FormatToString(f, slogx.LevelInfo, "message",
+		slog.String("alpha", "a"),
+		slog.Group("http", slog.String("method", "GET"), slog.Int("status", 200)),
+		slog.String("request_id", "123"),
+	)
+	if err != nil {
+		t.Errorf("failed to format record: %s", err.Error())
+		return
+	}
+	expected := "request_id=123 http.status=200 alpha=a http.method=GET"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestConsoleFormatterFunctionalOptions(t *testing.T) {
+	f := formatter.NewConsoleFormatter(
+		formatter.WithColor(false),
+		formatter.WithIgnoreAttrs("^secret$"),
+		formatter.WithPartOrder(
+			formatter.ConsoleFormatterTimePart,
+			formatter.ConsoleFormatterLevelPart,
+			formatter.ConsoleFormatterMessagePart,
+			formatter.ConsoleFormatterAttrsPart,
+		),
+		formatter.WithSortAttributes(false),
+		formatter.WithTimeLayout("2006-01-02"),
+	)
+	if f.IsColorized() {
+		t.Errorf("expected formatter not to be colorized")
+		return
+	}
+	buf, err := f.FormatRecord(context.Background(), time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), slogx.LevelInfo, 0,
+		"message", []slog.Attr{slog.Int("b", 2), slog.String("secret", "hunter2"), slog.Int("a", 1)})
+	if err != nil {
+		t.Errorf("failed to format record: %s", err.Error())
+		return
+	}
+	expected := "2024-03-01 INF message b=2 a=1\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	if !formatter.NewConsoleFormatter(formatter.WithColor(true)).IsColorized() {
+		t.Errorf("expected formatter to be colorized")
+	}
+}
+
+func TestConsoleFormatterDeterministicTime(t *testing.T) {
+	f := formatter.NewConsoleFormatter(
+		formatter.WithColor(false),
+		formatter.WithDeterministicTime(true),
+		formatter.WithPartOrder(
+			formatter.ConsoleFormatterTimePart,
+			formatter.ConsoleFormatterDeltaTimePart,
+			formatter.ConsoleFormatterLevelPart,
+			formatter.ConsoleFormatterMessagePart,
+			formatter.ConsoleFormatterAttrsPart,
+		),
+	)
+	expected := "<time> <time> INF message a=1 b=2\n"
+	for i := 0; i < 2; i++ {
+		output, err := formattertest.FormatToString(f, slogx.LevelInfo, "message", slog.Int("b", 2), slog.Int("a", 1))
+		if err != nil {
+			t.Errorf("failed to format record: %s", err.Error())
+			return
+		}
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+			return
+		}
+	}
+}
+
+// consoleTestErr is a simple extended error used for testing.
+type consoleTestErr struct {
+	nested []errorx.Error
+}
+
+func (e *consoleTestErr) Attrs() map[string]any        { return map[string]any{"id": 7} }
+func (e *consoleTestErr) Code() int                    { return 42 }
+func (e *consoleTestErr) Error() string                { return "failed" }
+func (e *consoleTestErr) InternalError() error         { return errors.New("disk full") }
+func (e *consoleTestErr) NestedErrors() []errorx.Error { return e.nested }
+
+func TestConsoleFormatterDefaultErrorParts(t *testing.T) {
+	opts := formatter.DefaultConsoleFormatterOptions()
+	opts.PartOrder = []formatter.ConsoleFormatterPart{
+		formatter.ConsoleFormatterMessagePart,
+		formatter.ConsoleFormatterAttrPart("error"),
+		formatter.ConsoleFormatterAttrRegexPart(`error\..*`),
+		formatter.ConsoleFormatterAttrsPart,
+	}
+	f := formatter.NewConsoleFormatter(opts)
+
+	output, err := formattertest.FormatToString(f, slogx.LevelError, "message", slog.String("a", "b"),
+		slogx.ErrX("error", &consoleTestErr{nested: []errorx.Error{&consoleTestErr{}}}))
+	if err != nil {
+		t.Errorf("failed to format record: %s", err.Error())
+		return
+	}
+	expected := "message error.attributes.id=7 error.code=42 error.error=failed error.internal_error=disk full " +
+		"error.nested_errors.001.attributes.id=7 error.nested_errors.001.code=42 error.nested_errors.001.error=failed " +
+		"error.nested_errors.001.internal_error=disk full a=b\n"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	output, err = formattertest.FormatToString(f, slogx.LevelError, "message", slog.String("a", "b"),
+		slogx.Err("error", errors.New("failed")))
+	if err != nil {
+		t.Errorf("failed to format record: %s", err.Error())
+		return
+	}
+	if output != "message error=failed a=b\n" {
+		t.Errorf("expected %q, got %q", "message error=failed a=b\n", output)
+	}
+}
+
+func TestColorizeByThreshold(t *testing.T) {
+	red := color.New(color.FgRed)
+	red.EnableColor()
+	colorize := formatter.ColorizeByThreshold(0, red, nil)
+	opts := formatter.DefaultConsoleFormatterOptions()
+	opts.MaxValueLength = 4
+	opts.PartOrder = []formatter.ConsoleFormatterPart{formatter.ConsoleFormatterAttrsPart}
+	opts.SortAttributes = false
+	opts.SpecificAttrFormatter = map[string]formatter.FormatAttrFn{
+		"balance":       colorize,
+		"account.debt":  colorize,
+		"account.limit": colorize,
+		"name":          colorize,
+	}
+	opts.OmitTrailingNewline = true
+	f := formatter.NewConsoleFormatter(opts)
+	output, err := formattertest.FormatToString(f, slogx.LevelInfo, "message",
+		slog.Int("balance", -5),
+		slog.Group("account", slog.Float64("debt", -1234.5), slog.Uint64("limit", 100)),
+		slog.String("name", "frodo"),
+	)
+	if err != nil {
+		t.Errorf("failed to format record: %s", err.Error())
+		return
+	}
+
+	// numbers are printed as they are without color and never truncated
+	expected := "balance=\x1b[31m-5\x1b[0m account.debt=\x1b[31m-1234.500000\x1b[0m account.limit=100 " +
+		"name=frod…(truncated 1 bytes)"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestConsoleFormatterMaxGroupDepth(t *testing.T) {
+	opts := formatter.DefaultConsoleFormatterOptions()
+	opts.MaxGroupDepth = 1
+	opts.PartOrder = []formatter.ConsoleFormatterPart{formatter.ConsoleFormatterAttrsPart}
+	opts.OmitTrailingNewline = true
+	f := formatter.NewConsoleFormatter(opts)
+	output, err := formattertest.FormatToString(f, slogx.LevelInfo, "message",
+		slog.Group("request", slog.String("method", "GET"), slog.Group("headers", slog.String("accept", "*/*"))),
+	)
+	if err != nil {
+		t.Errorf("failed to format record: %s", err.Error())
+		return
+	}
+	expected := `request.headers={"accept":"*/*"} request.method=GET`
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestConsoleFormatterDeltaTimePart(t *testing.T) {
+	f := formatter.NewConsoleFormatter(
+		formatter.WithPartOrder(formatter.ConsoleFormatterDeltaTimePart, formatter.ConsoleFormatterMessagePart),
+		formatter.WithTimeLayout("15:04:05"),
+	)
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		msg      string
+		offset   time.Duration
+		reset    bool
+		expected string
+	}{
+		{"first", 0, false, "03:04:05 first\n"},
+		{"second", 250 * time.Millisecond, false, "+0.250s second\n"},
+		{"third", 1250 * time.Millisecond, false, "+1.000s third\n"},
+		{"fourth", time.Second, false, "-0.250s fourth\n"},
+		{"fifth", 2 * time.Second, true, "03:04:07 fifth\n"},
+	}
+	for _, tt := range tests {
+		if tt.reset {
+			f.ResetDeltaTime()
+		}
+		buf, err := f.FormatRecord(context.Background(), start.Add(tt.offset), slogx.LevelInfo, 0, tt.msg, nil)
+		if err != nil {
+			t.Errorf("failed to format record: %s", err.Error())
+			return
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			return
+		}
+	}
+}
+
+func TestConsoleFormatterConcurrent(t *testing.T) {
+	f := formatter.NewConsoleFormatter(
+		formatter.WithPartOrder(
+			formatter.ConsoleFormatterDeltaTimePart,
+			formatter.ConsoleFormatterMessagePart,
+			formatter.ConsoleFormatterAttrsPart,
+		),
+	)
+	start := time.Now()
+	errs := make(chan error, 50)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			buf, err := f.FormatRecord(context.Background(), start.Add(time.Duration(i)*time.Millisecond),
+				slogx.LevelInfo, 0, "message", []slog.Attr{slog.Int("i", i)})
+			if err == nil && !strings.Contains(buf.String(), " message i=") {
+				err = errors.New("unexpected output: " + buf.String())
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("failed to format record: %s", err.Error())
+			return
+		}
+	}
+}
+
+func TestConsoleFormatterOmitTrailingNewline(t *testing.T) {
+	for _, omit := range []bool{false, true} {
+		opts := formatter.DefaultConsoleFormatterOptions()
+		opts.EnableColor = false
+		opts.OmitTrailingNewline = omit
+		opts.PartOrder = []formatter.ConsoleFormatterPart{formatter.ConsoleFormatterMessagePart}
+		output, err := formattertest.FormatToString(formatter.NewConsoleFormatter(opts), slogx.LevelInfo, "message")
+		if err != nil {
+			t.Errorf("failed to format record: %s", err.Error())
+			return
+		}
+		expected := "message\n"
+		if omit {
+			expected = "message"
+		}
+		if output != expected {
+			t.Errorf("expected %q with OmitTrailingNewline=%t, got %q", expected, omit, output)
+		}
+	}
+}
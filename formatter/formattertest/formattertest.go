@@ -0,0 +1,26 @@
+// Package formattertest provides utilities for testing formatters.
+package formattertest
+
+import (
+	"context"
+	"time"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+// FormatToString formats a record with the given level, message and attributes using the formatter and returns the
+// output as a string.
+//
+// The record is formatted using a background context, the current time and no source code location, making this
+// useful for table-driven tests of formatters and their options.
+func FormatToString(f formatter.BufferFormatter, level slogx.Level, msg string, attrs ...slog.Attr) (string, error) {
+	buf, err := f.FormatRecord(context.Background(), time.Now(), level, 0, msg, attrs)
+	if err != nil {
+		return "", err
+	}
+	defer buf.Free()
+	return buf.String(), nil
+}
@@ -0,0 +1,29 @@
+package formattertest_test
+
+import (
+	"testing"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/formatter/formattertest"
+)
+
+func TestFormatToString(t *testing.T) {
+	opts := formatter.DefaultConsoleFormatterOptions()
+	opts.PartOrder = []formatter.ConsoleFormatterPart{
+		formatter.ConsoleFormatterLevelPart,
+		formatter.ConsoleFormatterMessagePart,
+		formatter.ConsoleFormatterAttrsPart,
+	}
+	output, err := formattertest.FormatToString(formatter.NewConsoleFormatter(opts), slogx.LevelWarn, "message",
+		slog.String("key", "value"))
+	if err != nil {
+		t.Errorf("failed to format record: %s", err.Error())
+		return
+	}
+	if expected := "WRN message key=value\n"; output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
@@ -1,12 +1,17 @@
 package formatter
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"log/slog"
 
@@ -44,18 +49,67 @@ type JSONFormatterOptions struct {
 	// If nil, attributes remain unchanged.
 	AttrFormatter FormatAttrFn
 
+	// AttrPriority is a list of attribute keys to write before any other attributes, in the order given.
+	//
+	// Use a single period (.) to separate group name from attribute name if the attribute is nested within a group
+	// (eg: GROUP.ATTRIBUTE); the attribute is then written first within its group. The remaining attributes follow in
+	// their usual order, sorted if SortAttrs is true.
+	AttrPriority []string
+
+	// AttrTimeLayout is the layout to use when writing time values in attributes.
+	//
+	// Time values are converted to TimeZone before they are written as strings. Use AttrTimeLayoutUnix or
+	// AttrTimeLayoutUnixMilli to write them as a number since the Unix epoch instead. If empty, time.RFC3339 is used.
+	AttrTimeLayout string
+
+	// DeterministicTime indicates whether or not to write DeterministicTimeValue in place of the time of the record.
+	//
+	// This makes the output stable for golden-file tests. Time values in attributes are still written as usual.
+	// Combine this with SortAttrs for fully deterministic output.
+	DeterministicTime bool
+
+	// DuplicateKeyMode determines how attributes with duplicate keys, including within groups, are written.
+	//
+	// By default, only the last value for each key is written (DuplicateKeyLastWins). Handlers usually remove
+	// duplicate attributes before formatting a record (eg: using slogx.ConsolidateAttrs()), so any other mode only
+	// takes effect when the handler keeps them. The handlers in the handler package keep duplicate attributes when
+	// this is set to any other mode, while still merging groups with the same key. Handlers which use
+	// slogx.ConsolidateAttrs() should use slogx.ConsolidateAttrsWithOptions() with KeepDuplicates set instead.
+	DuplicateKeyMode DuplicateKeyMode
+
+	// DurationFormat determines how duration values in attributes are written.
+	//
+	// By default, durations are written as strings using their String() function. DurationFormatSeconds and
+	// DurationFormatMillis write them as numbers instead.
+	DurationFormat DurationFormat
+
+	// GroupSeparator is the separator used to join group and attribute keys when referring to attributes nested
+	// within groups.
+	//
+	// Wherever the other options describe using a single period (.) to separate group and attribute names, this
+	// separator is used instead. By default, a single period (.) is used.
+	GroupSeparator string
+
 	// IgnoreAttrs is a list of regular expressions to use for matching attributes which should not be printed.
 	//
 	// Note that this only applies to attributes and not defined parts of the record such as time, level and the
 	// message itself as they are always printed. Likewise, if NestedAttributes is true, the NestedAttributeAttr
 	// is always printed and if IncludeSource is true, the source is always printed.
 	//
+	// If a regular expression matches the key of a group (eg: GROUP or GROUP.NESTED), the entire group is not printed.
+	//
 	// If any regular expression does not compile, it is simply ignored.
 	IgnoreAttrs []string
 
 	// IncludeSource determines whether or not to include the source code location of the record in the output.
 	IncludeSource bool
 
+	// LevelAsNumber indicates whether or not to write the level as its raw integer value (eg: 4 for WARN) rather than
+	// as a string.
+	//
+	// If true, LevelFormatter is not called.
+	LevelAsNumber bool
+
 	// LevelAttr is the name of the JSON attribute to use for the level.
 	//
 	// If empty, defaults to JSONFormatterLevelAttr.
@@ -66,6 +120,21 @@ type JSONFormatterOptions struct {
 	// If nil, the level is printed using FormatLevelValueDefault().
 	LevelFormatter FormatLevelValueFn
 
+	// MaxValueLength is the maximum length, in bytes, of an attribute value.
+	//
+	// String values and the marshaled JSON of values of any other type are truncated to this length with a suffix
+	// indicating how many bytes were removed. Truncated marshaled values are written as JSON strings so the output
+	// remains valid JSON. Groups are never truncated as a whole; only their individual values are. The value is
+	// truncated after any attribute formatter has been called. If this is 0 or less, values are never truncated.
+	MaxValueLength int
+
+	// MaxRecordBytes is the maximum size, in bytes, of a formatted record.
+	//
+	// If a formatted record exceeds this size, it is handled according to OversizeRecordMode. This is useful for
+	// protecting transports which reject messages over a certain size. If this is 0 or less, the size of records is
+	// not limited.
+	MaxRecordBytes int
+
 	// MessageAttr is the name of the JSON attribute to use for the message.
 	//
 	// If empty, defaults to JSONFormatterMessageAttr.
@@ -86,11 +155,42 @@ type JSONFormatterOptions struct {
 	// If empty, defaults to JSONFormatterNestedAttributeAttr.
 	NestedAttributeAttr string
 
+	// OmitEmpty determines whether or not to skip attributes with empty values.
+	//
+	// Attributes are considered empty if their value is nil, an empty string or a group whose attributes are all
+	// empty. Explicitly set false and 0 values are still printed. Use OmitZero to skip those as well. Values are
+	// checked after any attribute formatter has been called.
+	OmitEmpty bool
+
+	// OmitTrailingNewline indicates whether or not to leave out the newline character normally appended to the end of
+	// each record.
+	//
+	// Handlers writing to files or consoles should leave this disabled while transports which frame messages
+	// themselves may wish to enable it.
+	OmitTrailingNewline bool
+
+	// OmitZero determines whether or not to skip attributes with zero values.
+	//
+	// This is a stricter version of OmitEmpty which also skips false, 0, zero durations, the zero time and any other
+	// value which is the zero value of its type.
+	OmitZero bool
+
+	// OversizeRecordMode determines what to do with a record whose formatted output exceeds MaxRecordBytes.
+	//
+	// By default, the record is replaced with a reduced record containing just the time, level, message and source
+	// along with a truncated=true attribute.
+	OversizeRecordMode OversizeRecordMode
+
 	// SortAttrs indicates whether or not to sort attributes in the output.
 	//
 	// Note that this *only* affects attributes and not the time, message, source or level.
 	SortAttrs bool
 
+	// SortAttrsCaseInsensitive indicates whether or not to ignore case when sorting attributes.
+	//
+	// This only applies if SortAttrs is true.
+	SortAttrsCaseInsensitive bool
+
 	// SourceAttr is the name of the JSON attribute to use for the source code location.
 	//
 	// If empty, defaults to JSONFormatterSourceAttr.
@@ -123,6 +223,38 @@ type JSONFormatterOptions struct {
 	//
 	// If nil, the time is printed using FormatTimeValueDefault().
 	TimeFormatter FormatTimeValueFn
+
+	// TimeZone is the location to convert the time of the record and any time values in attributes to before they are
+	// formatted.
+	//
+	// If nil, times are converted to UTC. Use time.Local to print times in the local time zone.
+	TimeZone *time.Location
+
+	// TypeMarshalers is a map of functions to call to marshal values of specific types into JSON.
+	//
+	// These are only used for values which are not one of the basic slog kinds (eg: string, int64, time, etc.). The
+	// function must return valid JSON. If no function is registered for a type or if the function returns an error or
+	// invalid JSON, the value is marshaled using json.Marshal() and, if that fails, it is printed as a string
+	// describing the error.
+	TypeMarshalers map[reflect.Type]func(any) ([]byte, error)
+
+	// ValueRedactPatterns is a list of regular expressions to use for matching sensitive data within string attribute
+	// values.
+	//
+	// Any part of a string value matching a pattern is replaced with RedactedValue. The name of any entry in
+	// BuiltinValueRedactPatterns (eg: email) may be used in place of a regular expression to enable that built-in
+	// pattern. Values are redacted after any attribute formatter has been called and before they are truncated.
+	//
+	// If any regular expression does not compile, it is simply ignored.
+	ValueRedactPatterns []string
+
+	// ValueTransformers is a map of functions to call to transform attribute values of a specific kind before they
+	// are formatted.
+	//
+	// The function registered for the kind of the resolved value is called before SpecificAttrFormatter or
+	// AttrFormatter (eg: to always render slog.KindTime values in a specific layout). Values such as []byte use
+	// slog.KindAny, so their transformer should check the underlying type using Value.Any().
+	ValueTransformers map[slog.Kind]func(slog.Value) slog.Value
 }
 
 // ContextWithJSONFormatterOptions adds the options to the given context and returns the new context.
@@ -133,8 +265,9 @@ func ContextWithJSONFormatterOptions(ctx context.Context, opts JSONFormatterOpti
 // DefaultJSONFormatterOptions returns a default set of options for the JSON formatter.
 func DefaultJSONFormatterOptions() JSONFormatterOptions {
 	return JSONFormatterOptions{
-		IgnoreAttrs: []string{},
-		LevelAttr:   JSONFormatterLevelAttr,
+		GroupSeparator: slogx.DefaultGroupSeparator,
+		IgnoreAttrs:    []string{},
+		LevelAttr:      JSONFormatterLevelAttr,
 		LevelFormatter: func(ctx context.Context, level slog.Leveler) (string, error) {
 			return strings.ToLower(slogx.Level(level.Level()).String()), nil
 		},
@@ -167,8 +300,10 @@ func JSONFormatterOptionsFromContext(ctx context.Context) *JSONFormatterOptions
 // jsonFormatter formats records for output as JSON.
 type jsonFormatter struct {
 	// unexported variables
+	attrPriority        map[string]int
 	ignoredAttrPatterns []*regexp.Regexp
 	options             JSONFormatterOptions
+	redactPatterns      []*regexp.Regexp
 }
 
 // DefaultJSONFormatter returns a JSON formatter with typical defaults already set.
@@ -191,14 +326,19 @@ func NewJSONFormatter(opts JSONFormatterOptions) *jsonFormatter {
 	if opts.MessageAttr == "" {
 		opts.MessageAttr = JSONFormatterMessageAttr
 	}
+	if opts.GroupSeparator == "" {
+		opts.GroupSeparator = slogx.DefaultGroupSeparator
+	}
 	if opts.NestAttributes && opts.NestedAttributeAttr == "" {
 		opts.NestedAttributeAttr = JSONFormatterNestedAttributeAttr
 	}
 
 	// create the formatter object
 	f := &jsonFormatter{
+		attrPriority:        attrPriorityIndex(opts.AttrPriority),
 		ignoredAttrPatterns: []*regexp.Regexp{},
 		options:             opts,
+		redactPatterns:      compileValueRedactPatterns(opts.ValueRedactPatterns),
 	}
 	for _, p := range opts.IgnoreAttrs {
 		regex, err := regexp.Compile(p)
@@ -213,10 +353,70 @@ func NewJSONFormatter(opts JSONFormatterOptions) *jsonFormatter {
 // handler.
 //
 // By default, duration values in attributes are formatted using the String() function and time values are formatted
-// in UTC time using the RFC3339 layout.
+// in TimeZone using the RFC3339 layout. Use the AttrTimeLayout and DurationFormat options to change this.
+//
+// If MaxRecordBytes is set and the formatted record exceeds it, the record is handled according to OversizeRecordMode
+// and ErrRecordTooLarge may be returned.
 func (f *jsonFormatter) FormatRecord(ctx context.Context, timestamp time.Time, level slogx.Level, pc uintptr,
 	msg string, attrs []slog.Attr) (*slogx.Buffer, error) {
 
+	return f.formatLimitedRecord(ctx, timestamp, level, pc, msg, attrs, false)
+}
+
+// FormatRecordAttrs combines the given handler attributes with the record's attributes, nesting the record's
+// attributes within the given groups, and formats the record, outputting it into the returned buffer.
+//
+// The output is identical to calling FormatRecord() with the consolidated attributes. The attributes are consolidated
+// into a new slice just as the handler would do; the only difference is that when SortAttrs is set, that slice is
+// sorted in place, whereas FormatRecord() sorts a resolved and deduplicated copy of the attributes it is given.
+func (f *jsonFormatter) FormatRecordAttrs(ctx context.Context, r slog.Record, attrs []slog.Attr,
+	groups []string) (*slogx.Buffer, error) {
+
+	keepDuplicates := f.KeepsDuplicateKeys()
+	consolidated := slogx.ConsolidateAttrsWithOptions(attrs, "", r, slogx.ConsolidateAttrsOptions{
+		Groups:         groups,
+		KeepDuplicates: keepDuplicates,
+	})
+
+	// the consolidated slice and any groups within it are freshly allocated, so they can be sorted in place
+	sorted := false
+	if f.options.SortAttrs && !keepDuplicates {
+		sortAttrsInPlace(consolidated, f.options.SortAttrsCaseInsensitive)
+		sorted = true
+	}
+	return f.formatLimitedRecord(ctx, r.Time, slogx.Level(r.Level), r.PC, r.Message, consolidated, sorted)
+}
+
+// KeepsDuplicateKeys returns whether or not the formatter needs to receive attributes with duplicate keys, which is
+// the case when DuplicateKeyMode is anything other than DuplicateKeyLastWins.
+func (f jsonFormatter) KeepsDuplicateKeys() bool {
+	return f.options.DuplicateKeyMode != DuplicateKeyLastWins
+}
+
+// formatLimitedRecord handles formatting the given record and outputting it into the returned buffer, limiting the
+// size of the output according to MaxRecordBytes.
+//
+// If sorted is true, the attributes have already been sorted and deduplicated.
+func (f *jsonFormatter) formatLimitedRecord(ctx context.Context, timestamp time.Time, level slogx.Level, pc uintptr,
+	msg string, attrs []slog.Attr, sorted bool) (*slogx.Buffer, error) {
+
+	buf, err := f.formatRecord(ctx, timestamp, level, pc, msg, attrs, sorted)
+	if err != nil {
+		return nil, err
+	}
+	return limitRecordSize(buf, f.options.MaxRecordBytes, f.options.OversizeRecordMode,
+		func() (*slogx.Buffer, error) {
+			return f.formatRecord(ctx, timestamp, level, pc, msg, []slog.Attr{slog.Bool("truncated", true)}, true)
+		})
+}
+
+// formatRecord handles formatting the given record and outputting it into the returned buffer without limiting the
+// size of the output.
+//
+// If sorted is true, the attributes have already been sorted and deduplicated.
+func (f *jsonFormatter) formatRecord(ctx context.Context, timestamp time.Time, level slogx.Level, pc uintptr,
+	msg string, attrs []slog.Attr, sorted bool) (*slogx.Buffer, error) {
+
 	var err error
 	var strVal string
 	buf := slogx.NewBuffer()
@@ -226,29 +426,32 @@ func (f *jsonFormatter) FormatRecord(ctx context.Context, timestamp time.Time, l
 	buf.WriteByte('{')
 
 	// write the time
-	if f.options.TimeFormatter != nil {
-		strVal, err = f.options.TimeFormatter(formatterCtx, level, timestamp)
-	} else {
-		strVal, err = FormatTimeValueDefault(formatterCtx, level, timestamp)
-	}
+	strVal, err = formatRecordTime(formatterCtx, level, timestamp, f.options.TimeFormatter,
+		f.options.TimeZone, f.options.DeterministicTime)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Fprintf(buf, `"%s":"%s"`, f.options.TimeAttr, strVal)
+	writeJSONKey(buf, f.options.TimeAttr)
+	writeJSONString(buf, strVal)
 
 	// write the level
-	if f.options.LevelFormatter != nil {
-		strVal, err = f.options.LevelFormatter(formatterCtx, level)
-	} else {
-		strVal, err = FormatLevelValueDefault(formatterCtx, level)
-	}
-	if err != nil {
-		return nil, err
-	}
 	if buf.Len() > 2 {
 		buf.WriteByte(',')
 	}
-	fmt.Fprintf(buf, `"%s":"%s"`, f.options.LevelAttr, strVal)
+	writeJSONKey(buf, f.options.LevelAttr)
+	if f.options.LevelAsNumber {
+		*buf = strconv.AppendInt(*buf, int64(level), 10)
+	} else {
+		if f.options.LevelFormatter != nil {
+			strVal, err = f.options.LevelFormatter(formatterCtx, level)
+		} else {
+			strVal, err = FormatLevelValueDefault(formatterCtx, level)
+		}
+		if err != nil {
+			return nil, err
+		}
+		writeJSONString(buf, strVal)
+	}
 
 	// add source to attribute list, if enabled
 	if f.options.IncludeSource {
@@ -263,12 +466,14 @@ func (f *jsonFormatter) FormatRecord(ctx context.Context, timestamp time.Time, l
 		if buf.Len() > 2 {
 			buf.WriteByte(',')
 		}
-		fmt.Fprintf(buf, `"%s":"%s"`, f.options.SourceAttr, strVal)
+		writeJSONKey(buf, f.options.SourceAttr)
+		writeJSONString(buf, strVal)
 	}
 
 	// add message to attribute list
 	if f.options.MessageFormatter != nil {
-		strVal, err = f.options.MessageFormatter(formatterCtx, level, msg)
+		strVal, err = formatMessage(formatterCtx, f.options.MessageFormatter, level, msg, attrs, f.options.GroupSeparator,
+			f.ignoredAttrPatterns, f.redactPatterns)
 	} else {
 		strVal = msg
 		err = nil
@@ -279,25 +484,34 @@ func (f *jsonFormatter) FormatRecord(ctx context.Context, timestamp time.Time, l
 	if buf.Len() > 2 {
 		buf.WriteByte(',')
 	}
-	fmt.Fprintf(buf, `"%s":"%s"`, f.options.MessageAttr, strVal)
+	writeJSONKey(buf, f.options.MessageAttr)
+	writeJSONString(buf, strVal)
+
+	// collect or rename attributes with duplicate keys, if requested
+	if f.options.DuplicateKeyMode != DuplicateKeyLastWins {
+		attrs = collectDuplicateKeys(attrs, f.options.DuplicateKeyMode)
+	}
 
-	// sort attributes, if requested
-	if f.options.SortAttrs {
-		attrs = slogx.SortAttrs(attrs)
+	// sort and prioritize attributes, if requested
+	if f.options.SortAttrs && !sorted {
+		attrs = slogx.SortAttrsWithOptions(attrs, slogx.SortAttrsOptions{
+			CaseInsensitive: f.options.SortAttrsCaseInsensitive,
+		})
 	}
+	attrs = prioritizeAttrs(attrs, "", f.options.GroupSeparator, f.attrPriority)
 
 	// loop through and print the attributes
 	if f.options.NestAttributes {
 		if buf.Len() > 2 {
 			buf.WriteByte(',')
 		}
-		fmt.Fprintf(buf, `"%s":{`, f.options.NestedAttributeAttr)
-		count := 0
+		writeJSONKey(buf, f.options.NestedAttributeAttr)
+		buf.WriteByte('{')
+		start := buf.Len()
 		for _, attr := range attrs {
-			if err := f.formatAttr(formatterCtx, buf, level, "", attr.Key, attr.Value, count > 0); err != nil {
+			if err := f.formatAttr(formatterCtx, buf, level, "", attr.Key, attr.Value, buf.Len() > start); err != nil {
 				return nil, err
 			}
-			count++
 		}
 		buf.WriteByte('}')
 	} else {
@@ -309,21 +523,24 @@ func (f *jsonFormatter) FormatRecord(ctx context.Context, timestamp time.Time, l
 	}
 
 	// close the JSON
-	buf.WriteString("}\n")
+	buf.WriteByte('}')
+	if !f.options.OmitTrailingNewline {
+		buf.WriteByte('\n')
+	}
 	return buf, nil
 }
 
 // formatAttr formats the given attribute key and value and returns the resulting string to print to the buffer.
 //
 // By default, duration values in attributes are formatted using the String() function and time values are formatted
-// in UTC time using the RFC3339 layout.
+// in TimeZone using the RFC3339 layout. Use the AttrTimeLayout and DurationFormat options to change this.
 func (f jsonFormatter) formatAttr(ctx context.Context, buf *slogx.Buffer, level slog.Leveler, group, attrKey string,
 	attrValue slog.Value, writeComma bool) error {
 
 	// create the full key path with the group
 	groupWithKey := attrKey
 	if group != "" {
-		groupWithKey = fmt.Sprintf("%s.%s", group, attrKey)
+		groupWithKey = group + f.options.GroupSeparator + attrKey
 	}
 
 	// ignore the given attribute if the group/key matches
@@ -333,57 +550,281 @@ func (f jsonFormatter) formatAttr(ctx context.Context, buf *slogx.Buffer, level
 		}
 	}
 
+	// write each value of an attribute with duplicate keys as an array
+	if attrValue.Kind() == slog.KindAny {
+		if values, ok := attrValue.Any().(jsonArrayValue); ok {
+			return f.formatArrayAttr(ctx, buf, level, group, attrKey, groupWithKey, values, writeComma)
+		}
+	}
+
 	// format the attribute using any formatter functions first
-	formattedKey := attrKey
-	formattedValue := attrValue
-	var err error
-	if fn, ok := f.options.SpecificAttrFormatter[groupWithKey]; ok && fn != nil {
-		formattedKey, formattedValue, err = fn(ctx, level, group, formattedKey, formattedValue)
+	formattedKey, formattedValue, err := f.applyAttrFormatters(ctx, level, group, attrKey, groupWithKey, attrValue)
+	if err != nil {
+		return err
+	}
+	if omitValue(formattedValue, f.options.OmitEmpty, f.options.OmitZero) {
+		return nil
+	}
+
+	// format the key/value
+	if writeComma {
+		buf.WriteByte(',')
+	}
+	writeJSONKey(buf, formattedKey)
+	return f.formatValue(ctx, buf, level, groupWithKey, formattedValue)
+}
+
+// formatArrayAttr formats the values of an attribute with duplicate keys as a JSON array.
+//
+// The attribute formatter functions are called for each value and the key returned for the first value is used.
+func (f jsonFormatter) formatArrayAttr(ctx context.Context, buf *slogx.Buffer, level slog.Leveler, group, attrKey,
+	groupWithKey string, values jsonArrayValue, writeComma bool) error {
+
+	formattedKey := ""
+	formattedValues := make([]slog.Value, 0, len(values))
+	for _, v := range values {
+		key, value, err := f.applyAttrFormatters(ctx, level, group, attrKey, groupWithKey, v)
 		if err != nil {
 			return err
 		}
-	} else if f.options.AttrFormatter != nil {
-		formattedKey, formattedValue, err = f.options.AttrFormatter(ctx, level, group, formattedKey, formattedValue)
-		if err != nil {
-			return err
+		if omitValue(value, f.options.OmitEmpty, f.options.OmitZero) {
+			continue
+		}
+		if len(formattedValues) == 0 {
+			formattedKey = key
 		}
+		formattedValues = append(formattedValues, value)
+	}
+	if len(formattedValues) == 0 {
+		return nil
 	}
 
-	// format the key/value
 	if writeComma {
 		buf.WriteByte(',')
 	}
+	writeJSONKey(buf, formattedKey)
+	buf.WriteByte('[')
+	for i, v := range formattedValues {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := f.formatValue(ctx, buf, level, groupWithKey, v); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// applyAttrFormatters transforms the given attribute value and calls any attribute formatter functions for the
+// attribute, returning the formatted key and value.
+func (f jsonFormatter) applyAttrFormatters(ctx context.Context, level slog.Leveler, group, attrKey,
+	groupWithKey string, attrValue slog.Value) (string, slog.Value, error) {
+
+	formattedValue := transformValue(attrValue.Resolve(), f.options.ValueTransformers)
+	if fn, ok := f.options.SpecificAttrFormatter[groupWithKey]; ok && fn != nil {
+		return fn(ctx, level, group, attrKey, formattedValue)
+	} else if f.options.AttrFormatter != nil {
+		return f.options.AttrFormatter(ctx, level, group, attrKey, formattedValue)
+	}
+	return attrKey, formattedValue, nil
+}
+
+// formatValue writes the given formatted attribute value to the buffer as JSON.
+func (f jsonFormatter) formatValue(ctx context.Context, buf *slogx.Buffer, level slog.Leveler, groupWithKey string,
+	formattedValue slog.Value) error {
+
 	switch formattedValue.Kind() {
 	case slog.KindBool:
-		fmt.Fprintf(buf, `"%s":%t`, formattedKey, formattedValue.Bool())
+		*buf = strconv.AppendBool(*buf, formattedValue.Bool())
 	case slog.KindString:
-		fmt.Fprintf(buf, `"%s":"%s"`, formattedKey, formattedValue.String())
+		writeJSONString(buf, truncateValue(redactValue(formattedValue.String(), f.redactPatterns),
+			f.options.MaxValueLength))
 	case slog.KindDuration:
-		fmt.Fprintf(buf, `"%s":"%s"`, formattedKey, formattedValue.Duration().String())
+		if f.options.DurationFormat == DurationFormatString {
+			writeJSONString(buf, formattedValue.Duration().String())
+		} else {
+			buf.WriteString(f.options.DurationFormat.Format(formattedValue.Duration()))
+		}
 	case slog.KindTime:
-		fmt.Fprintf(buf, `"%s":"%s"`, formattedKey, formattedValue.Time().UTC().Format(time.RFC3339))
+		switch layout := f.options.AttrTimeLayout; {
+		case isEpochTimeLayout(layout):
+			buf.WriteString(formatAttrTime(formattedValue.Time(), layout, f.options.TimeZone))
+		case layout == "":
+			buf.WriteByte('"')
+			*buf = timeIn(formattedValue.Time(), f.options.TimeZone).AppendFormat(*buf, time.RFC3339)
+			buf.WriteByte('"')
+		default:
+			writeJSONString(buf, formatAttrTime(formattedValue.Time(), layout, f.options.TimeZone))
+		}
 	case slog.KindFloat64:
-		fmt.Fprintf(buf, `"%s":%f`, formattedKey, formattedValue.Float64())
+		*buf = strconv.AppendFloat(*buf, formattedValue.Float64(), 'f', 6, 64)
 	case slog.KindInt64:
-		fmt.Fprintf(buf, `"%s":%d`, formattedKey, formattedValue.Int64())
+		*buf = strconv.AppendInt(*buf, formattedValue.Int64(), 10)
 	case slog.KindUint64:
-		fmt.Fprintf(buf, `"%s":%d`, formattedKey, formattedValue.Uint64())
+		*buf = strconv.AppendUint(*buf, formattedValue.Uint64(), 10)
 	case slog.KindGroup:
-		fmt.Fprintf(buf, `"%s":{`, formattedKey)
-		count := 0
+		buf.WriteByte('{')
+		start := buf.Len()
 		for _, attr := range formattedValue.Group() {
-			if err := f.formatAttr(ctx, buf, level, groupWithKey, attr.Key, attr.Value, count > 0); err != nil {
+			if err := f.formatAttr(ctx, buf, level, groupWithKey, attr.Key, attr.Value, buf.Len() > start); err != nil {
 				return err
 			}
-			count++
 		}
 		buf.WriteByte('}')
 	default:
-		marshalled, err := json.Marshal(formattedValue.Any())
-		if err != nil {
-			return err
+		marshalled := f.marshalValue(formattedValue.Any())
+		if f.options.MaxValueLength > 0 && len(marshalled) > f.options.MaxValueLength {
+			marshalled = marshalString(truncateValue(string(marshalled), f.options.MaxValueLength))
 		}
-		fmt.Fprintf(buf, `"%s":%s`, formattedKey, marshalled)
+		_, _ = buf.Write(marshalled)
 	}
 	return nil
 }
+
+// jsonArrayValue holds the values of attributes with the same key which are written as a JSON array.
+type jsonArrayValue []slog.Value
+
+// collectDuplicateKeys handles attributes with duplicate keys in the slice and any nested groups according to the
+// mode, resolving attribute values along the way.
+//
+// With DuplicateKeyArray, the values are collected into a single attribute holding a jsonArrayValue. With
+// DuplicateKeySuffix, each duplicate key is renamed to KEY#N.
+func collectDuplicateKeys(attrs []slog.Attr, mode DuplicateKeyMode) []slog.Attr {
+	counts := make(map[string]int, len(attrs))
+	for _, attr := range attrs {
+		counts[attr.Key]++
+	}
+
+	result := make([]slog.Attr, 0, len(attrs))
+	index := map[string]int{}
+	seen := map[string]int{}
+	for _, attr := range attrs {
+		v := attr.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			v = slog.GroupValue(collectDuplicateKeys(v.Group(), mode)...)
+		}
+		seen[attr.Key]++
+		switch {
+		case counts[attr.Key] == 1:
+			result = append(result, slog.Attr{Key: attr.Key, Value: v})
+		case mode == DuplicateKeySuffix:
+			key := attr.Key
+			if n := seen[attr.Key]; n > 1 {
+				key = fmt.Sprintf("%s#%d", key, n)
+			}
+			result = append(result, slog.Attr{Key: key, Value: v})
+		default:
+			if i, ok := index[attr.Key]; ok {
+				result[i].Value = slog.AnyValue(append(result[i].Value.Any().(jsonArrayValue), v))
+			} else {
+				index[attr.Key] = len(result)
+				result = append(result, slog.Attr{Key: attr.Key, Value: slog.AnyValue(jsonArrayValue{v})})
+			}
+		}
+	}
+	return result
+}
+
+// marshalValue marshals the given value into JSON.
+//
+// Any function registered for the value's type in TypeMarshalers is tried first, followed by json.Marshal(). The
+// output of a registered function is only used if it is valid JSON. If both fail, a string describing the type of
+// the value and the marshaling error is returned instead so that a single bad value does not cause the entire record
+// to be lost.
+func (f jsonFormatter) marshalValue(v any) []byte {
+	if fn, ok := f.options.TypeMarshalers[reflect.TypeOf(v)]; ok && fn != nil {
+		if marshalled, err := fn(v); err == nil && json.Valid(marshalled) {
+			return marshalled
+		}
+	}
+	marshalled, err := json.Marshal(v)
+	if err == nil {
+		return marshalled
+	}
+	return marshalString(fmt.Sprintf("<unmarshalable: %T: %s>", v, err.Error()))
+}
+
+// sortAttrsInPlace sorts the given attributes and any nested groups by key, comparing keys the same way as
+// slogx.SortAttrsWithOptions.
+//
+// The attributes must already be resolved and deduplicated and the slices must not be shared with anything else.
+func sortAttrsInPlace(attrs []slog.Attr, caseInsensitive bool) {
+	for _, attr := range attrs {
+		if attr.Value.Kind() == slog.KindGroup {
+			sortAttrsInPlace(attr.Value.Group(), caseInsensitive)
+		}
+	}
+	slices.SortStableFunc(attrs, func(a, b slog.Attr) int {
+		if caseInsensitive {
+			if c := strings.Compare(strings.ToLower(a.Key), strings.ToLower(b.Key)); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+}
+
+// marshalString marshals the given string into a JSON string without escaping HTML characters.
+func marshalString(s string) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return bytes.TrimRight(b.Bytes(), "\n")
+}
+
+// writeJSONKey writes the given key to the buffer as a quoted JSON string followed by a colon.
+func writeJSONKey(buf *slogx.Buffer, key string) {
+	writeJSONString(buf, key)
+	buf.WriteByte(':')
+}
+
+// writeJSONString writes the given string to the buffer as a quoted JSON string.
+//
+// Quotes, backslashes and control characters are escaped and invalid UTF-8 is replaced with the Unicode replacement
+// character. Unlike json.Marshal(), HTML characters are not escaped.
+func writeJSONString(buf *slogx.Buffer, s string) {
+	const hex = "0123456789abcdef"
+
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
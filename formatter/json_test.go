@@ -0,0 +1,386 @@
+package formatter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/formatter/formattertest"
+)
+
+func TestJSONFormatterUnmarshalableAttr(t *testing.T) {
+	f := formatter.DefaultJSONFormatter()
+	buf, err := f.FormatRecord(context.Background(), time.Now(), slogx.LevelInfo, 0, "this is a message",
+		[]slog.Attr{
+			slog.Any("channel", make(chan int)),
+			slog.String("key", "value"),
+		})
+	if err != nil {
+		t.Errorf("expected record to be formatted, got error: %s", err.Error())
+		return
+	}
+	defer buf.Free()
+
+	output := buf.String()
+	if !json.Valid(buf.Bytes()) {
+		t.Errorf("expected valid JSON, got: %s", output)
+	}
+	if !strings.Contains(output, `"channel":"<unmarshalable: chan int: `) {
+		t.Errorf("expected unmarshalable marker for channel attribute, got: %s", output)
+	}
+	if !strings.Contains(output, `"key":"value"`) {
+		t.Errorf("expected remaining attributes to be formatted, got: %s", output)
+	}
+}
+
+// point is a type used to test custom marshalers.
+type point struct {
+	X, Y int
+}
+
+func TestJSONFormatterTypeMarshalers(t *testing.T) {
+	pointMarshaler := func(v any) ([]byte, error) {
+		p := v.(point)
+		return []byte(fmt.Sprintf("[%d,%d]", p.X, p.Y)), nil
+	}
+	tests := map[string]struct {
+		marshaler func(any) ([]byte, error)
+		value     any
+		expected  string
+	}{
+		"registered": {
+			marshaler: pointMarshaler,
+			value:     point{X: 1, Y: 2},
+			expected:  `"value":[1,2]`,
+		},
+		"not registered": {
+			value:    point{X: 1, Y: 2},
+			expected: `"value":{"X":1,"Y":2}`,
+		},
+		"marshaler error": {
+			marshaler: func(v any) ([]byte, error) { return nil, errors.New("failed") },
+			value:     point{X: 1, Y: 2},
+			expected:  `"value":{"X":1,"Y":2}`,
+		},
+		"invalid json": {
+			marshaler: func(v any) ([]byte, error) { return []byte(`{"x":`), nil },
+			value:     point{X: 1, Y: 2},
+			expected:  `"value":{"X":1,"Y":2}`,
+		},
+		"unmarshalable": {
+			marshaler: func(v any) ([]byte, error) { return nil, errors.New("failed") },
+			value:     make(chan int),
+			expected:  `"value":"<unmarshalable: chan int: json: unsupported type: chan int>"`,
+		},
+	}
+	for name, tt := range tests {
+		opts := formatter.DefaultJSONFormatterOptions()
+		if tt.marshaler != nil {
+			opts.TypeMarshalers = map[reflect.Type]func(any) ([]byte, error){
+				reflect.TypeOf(tt.value): tt.marshaler,
+			}
+		}
+		output, err := formattertest.FormatToString(formatter.NewJSONFormatter(opts), slogx.LevelInfo, "message",
+			slog.Any("value", tt.value))
+		if err != nil {
+			t.Errorf("%s: failed to format record: %s", name, err.Error())
+			continue
+		}
+		if !json.Valid([]byte(output)) {
+			t.Errorf("%s: expected valid JSON, got: %s", name, output)
+		}
+		if !strings.Contains(output, tt.expected) {
+			t.Errorf("%s: expected output to contain %s, got: %s", name, tt.expected, output)
+		}
+	}
+}
+
+func TestJSONFormatterMaxValueLength(t *testing.T) {
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.MaxValueLength = 10
+	f := formatter.NewJSONFormatter(opts)
+	buf, err := f.FormatRecord(context.Background(), time.Now(), slogx.LevelInfo, 0, "this is a message",
+		[]slog.Attr{
+			slog.String("body", strings.Repeat("a", 25)),
+			slog.Any("list", []int{1, 2, 3, 4, 5, 6, 7, 8}),
+			slog.Group("group", slog.String("short", "value")),
+		})
+	if err != nil {
+		t.Errorf("expected record to be formatted, got error: %s", err.Error())
+		return
+	}
+	defer buf.Free()
+
+	output := buf.String()
+	if !json.Valid(buf.Bytes()) {
+		t.Errorf("expected valid JSON, got: %s", output)
+		return
+	}
+	for _, expected := range []string{
+		`"body":"aaaaaaaaaa…(truncated 15 bytes)"`,
+		`"list":"[1,2,3,4,5…(truncated 7 bytes)"`,
+		`"group":{"short":"value"}`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %s in output: %s", expected, output)
+		}
+	}
+}
+
+func TestJSONFormatterMaxRecordBytes(t *testing.T) {
+	attrs := []slog.Attr{slog.String("body", strings.Repeat("a", 500))}
+
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.MaxRecordBytes = 200
+	f := formatter.NewJSONFormatter(opts)
+	buf, err := f.FormatRecord(context.Background(), time.Now(), slogx.LevelInfo, 0, "this is a message", attrs)
+	if err != nil {
+		t.Errorf("expected record to be formatted, got error: %s", err.Error())
+		return
+	}
+	output := buf.String()
+	buf.Free()
+	if len(output) > opts.MaxRecordBytes || strings.Contains(output, "aaaa") ||
+		!strings.Contains(output, `"@msg":"this is a message"`) || !strings.Contains(output, `"truncated":true`) {
+		t.Errorf("expected reduced record, got: %s", output)
+	}
+
+	opts.OversizeRecordMode = formatter.OversizeRecordDrop
+	f = formatter.NewJSONFormatter(opts)
+	if _, err := f.FormatRecord(context.Background(), time.Now(), slogx.LevelInfo, 0, "this is a message",
+		attrs); !errors.Is(err, formatter.ErrRecordTooLarge) {
+		t.Errorf("expected record to be dropped, got error: %v", err)
+	}
+}
+
+func TestJSONFormatterEscapesStrings(t *testing.T) {
+	f := formatter.DefaultJSONFormatter()
+	buf, err := f.FormatRecord(context.Background(), time.Now(), slogx.LevelInfo, 0, "say \"hello\"\n",
+		[]slog.Attr{
+			slog.String("path", `C:\temp`),
+			slog.String("html", "<b>&</b>"),
+			slog.Int("int", -42),
+			slog.Uint64("uint", 42),
+			slog.Float64("float", 3.5),
+			slog.Bool("bool", true),
+			slog.Duration("duration", 1500*time.Millisecond),
+			slog.Time("time", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+		})
+	if err != nil {
+		t.Errorf("expected record to be formatted, got error: %s", err.Error())
+		return
+	}
+	defer buf.Free()
+
+	output := buf.String()
+	if !json.Valid(buf.Bytes()) {
+		t.Errorf("expected valid JSON, got: %s", output)
+		return
+	}
+	for _, expected := range []string{
+		`"@msg":"say \"hello\"\n"`,
+		`"path":"C:\\temp"`,
+		`"html":"<b>&</b>"`,
+		`"int":-42`,
+		`"uint":42`,
+		`"float":3.500000`,
+		`"bool":true`,
+		`"duration":"1.5s"`,
+		`"time":"2023-01-02T03:04:05Z"`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %s in output: %s", expected, output)
+		}
+	}
+}
+
+func BenchmarkJSONFormatRecord(b *testing.B) {
+	f := formatter.DefaultJSONFormatter()
+	now := time.Now()
+	attrs := []slog.Attr{
+		slog.String("string", "value"),
+		slog.Int("int", 42),
+		slog.Bool("bool", true),
+		slog.Float64("float", 3.14),
+		slog.Duration("duration", time.Second),
+		slog.Time("time", now),
+		slog.Group("group", slog.String("nested", "value")),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf, err := f.FormatRecord(context.Background(), now, slogx.LevelInfo, 0, "this is a message", attrs)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		buf.Free()
+	}
+}
+
+// benchmarkJSONRecordAttrs formats a record with several handler and record attributes, either passing them to
+// FormatRecordAttrs() or consolidating them and passing the result to FormatRecord() as handlers otherwise would.
+func benchmarkJSONRecordAttrs(b *testing.B, fused bool) {
+	var f formatter.RecordAttrsFormatter = formatter.DefaultJSONFormatter()
+	attrs := []slog.Attr{slog.String("service", "api"), slog.Int("pid", 1234), slog.String("id", "abc123")}
+	groups := []string{"request"}
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "request completed", 0)
+	r.AddAttrs(
+		slog.String("method", "GET"),
+		slog.String("path", "/api/v1/users"),
+		slog.Int("status", 200),
+		slog.Duration("elapsed", time.Millisecond),
+		slog.String("client", "127.0.0.1"),
+		slog.String("agent", "curl/8.0"),
+	)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf *slogx.Buffer
+		var err error
+		if fused {
+			buf, err = f.FormatRecordAttrs(ctx, r, attrs, groups)
+		} else {
+			consolidated := slogx.ConsolidateAttrsWithOptions(attrs, "", r, slogx.ConsolidateAttrsOptions{
+				Groups: groups,
+			})
+			buf, err = f.FormatRecord(ctx, r.Time, slogx.Level(r.Level), r.PC, r.Message, consolidated)
+		}
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		buf.Free()
+	}
+}
+
+func BenchmarkJSONFormatRecordAttrs(b *testing.B) {
+	benchmarkJSONRecordAttrs(b, true)
+}
+
+func BenchmarkJSONFormatRecordConsolidated(b *testing.B) {
+	benchmarkJSONRecordAttrs(b, false)
+}
+
+func TestJSONFormatterAttrPriority(t *testing.T) {
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.AttrPriority = []string{"request_id", "http.status", "user"}
+	opts.TimeFormatter = func(ctx context.Context, level slog.Leveler, t time.Time) (string, error) {
+		return "now", nil
+	}
+	f := formatter.NewJSONFormatter(opts)
+	output, err := formattertest.FormatToString(f, slogx.LevelInfo, "message",
+		slog.String("alpha", "a"),
+		slog.String("user", "frodo"),
+		slog.Group("http", slog.String("method", "GET"), slog.Int("status", 200)),
+		slog.String("request_id", "123"),
+	)
+	if err != nil {
+		t.Errorf("expected record to be formatted, got error: %s", err.Error())
+		return
+	}
+	expected := `{"@time":"now","@level":"info","@msg":"message","@attributes":{"request_id":"123","user":"frodo",` +
+		`"alpha":"a","http":{"status":200,"method":"GET"}}}` + "\n"
+	if output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+}
+
+func TestJSONFormatterLevelAsNumber(t *testing.T) {
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.LevelAsNumber = true
+	f := formatter.NewJSONFormatter(opts)
+	output, err := formattertest.FormatToString(f, slogx.LevelWarn, "message")
+	if err != nil {
+		t.Errorf("expected record to be formatted, got error: %s", err.Error())
+		return
+	}
+	if !json.Valid([]byte(output)) {
+		t.Errorf("expected valid JSON, got: %s", output)
+	}
+	if !strings.Contains(output, `"@level":4`) {
+		t.Errorf(`expected "@level":4 in output: %s`, output)
+	}
+}
+
+func TestJSONFormatterDeterministicTime(t *testing.T) {
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.DeterministicTime = true
+	opts.NestAttributes = false
+	f := formatter.NewJSONFormatter(opts)
+	output, err := formattertest.FormatToString(f, slogx.LevelInfo, "message", slog.Int("b", 2), slog.Int("a", 1))
+	if err != nil {
+		t.Errorf("expected record to be formatted, got error: %s", err.Error())
+		return
+	}
+	expected := `{"@time":"<time>","@level":"info","@msg":"message","a":1,"b":2}` + "\n"
+	if output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+}
+
+func TestJSONFormatterDuplicateKeyMode(t *testing.T) {
+	tests := []struct {
+		mode     formatter.DuplicateKeyMode
+		expected string
+	}{
+		{formatter.DuplicateKeyArray, `{"tag":["a","b","c"],"n":1,"g":{"x":[1,2]}}`},
+		{formatter.DuplicateKeySuffix, `{"tag":"a","tag#2":"b","n":1,"tag#3":"c","g":{"x":1,"x#2":2}}`},
+	}
+	for _, tt := range tests {
+		opts := formatter.DefaultJSONFormatterOptions()
+		opts.DuplicateKeyMode = tt.mode
+		opts.NestAttributes = false
+		opts.SortAttrs = false
+		opts.TimeFormatter = func(ctx context.Context, level slog.Leveler, t time.Time) (string, error) {
+			return "", nil
+		}
+		f := formatter.NewJSONFormatter(opts)
+		output, err := formattertest.FormatToString(f, slogx.LevelInfo, "message",
+			slog.String("tag", "a"),
+			slog.String("tag", "b"),
+			slog.Int("n", 1),
+			slog.String("tag", "c"),
+			slog.Group("g", slog.Int("x", 1), slog.Int("x", 2)),
+		)
+		if err != nil {
+			t.Errorf("expected record to be formatted, got error: %s", err.Error())
+			return
+		}
+		attrs := strings.TrimPrefix(strings.TrimSpace(output), `{"@time":"","@level":"info","@msg":"message",`)
+		if "{"+attrs != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, output)
+		}
+	}
+}
+
+func TestJSONFormatterOmitTrailingNewline(t *testing.T) {
+	tests := []struct {
+		omit     bool
+		expected bool
+	}{
+		{omit: false, expected: true},
+		{omit: true, expected: false},
+	}
+	for _, test := range tests {
+		f := formatter.NewJSONFormatter(formatter.JSONFormatterOptions{OmitTrailingNewline: test.omit})
+		output, err := formattertest.FormatToString(f, slogx.LevelInfo, "message")
+		if err != nil {
+			t.Errorf("expected record to be formatted, got error: %s", err.Error())
+			return
+		}
+		if strings.HasSuffix(output, "}\n") != test.expected || !json.Valid([]byte(output)) {
+			t.Errorf("expected trailing newline to be %t with OmitTrailingNewline=%t, got: %q", test.expected,
+				test.omit, output)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package formatter
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"log/slog"
+
+	"github.com/fatih/color"
+	"go.innotegrity.dev/slogx"
+)
+
+// prettyFormatterOptionsContext can be used to retrieve the options used by the formatter from the context.
+type prettyFormatterOptionsContext struct{}
+
+// PrettyFormatterOptions holds the options for the pretty formatter.
+type PrettyFormatterOptions struct {
+	// AttrTimeLayout is the layout to use when printing time values in attributes.
+	//
+	// Time values are converted to TimeZone before they are printed. Use AttrTimeLayoutUnix or AttrTimeLayoutUnixMilli
+	// to print them as a number since the Unix epoch instead. If empty, time.RFC3339 is used.
+	AttrTimeLayout string
+
+	// DeterministicTime indicates whether or not to print DeterministicTimeValue in place of the time of the record.
+	//
+	// This makes the output stable for golden-file tests. The time part of the header is replaced,
+	// while time values in attributes are still printed as usual. Combine this with SortAttributes and EnableColor set
+	// to false for fully deterministic output.
+	DeterministicTime bool
+
+	// DurationFormat determines how duration values in attributes are printed.
+	//
+	// By default, durations are printed using their String() function.
+	DurationFormat DurationFormat
+
+	// EnableColor determines whether or not to enable colorized output.
+	//
+	// When enabled, attribute keys are printed using KeyColor.
+	EnableColor bool
+
+	// GroupSeparator is the separator used to join group and attribute keys when referring to attributes nested
+	// within groups.
+	//
+	// Wherever the other options describe using a single period (.) to separate group and attribute names, this
+	// separator is used instead. By default, a single period (.) is used.
+	GroupSeparator string
+
+	// HeaderParts is the order in which to print the various parts of the header line.
+	//
+	// Only ConsoleFormatterLevelPart, ConsoleFormatterMessagePart, ConsoleFormatterSourcePart and
+	// ConsoleFormatterTimePart are supported. If any other string is specified, it is simply printed as-is.
+	//
+	// By default the header will be "TimePart LevelPart SourcePart > MessagePart".
+	HeaderParts []ConsoleFormatterPart
+
+	// IgnoreAttrs is a list of regular expressions to use for matching attributes which should not be printed.
+	//
+	// Attributes nested within groups are matched using a single period (.) to separate the group and attribute names
+	// (eg: GROUP.ATTRIBUTE). If a regular expression matches the key of a group, the entire group is not printed. If any
+	// regular expression does not compile, it is simply ignored.
+	IgnoreAttrs []string
+
+	// Indent is printed at the start of each attribute line.
+	//
+	// By default, 4 spaces are used.
+	Indent string
+
+	// KeyColor is the color to use when printing attribute keys.
+	//
+	// This only applies if EnableColor is true. If nil, keys are printed in high-intensity blue.
+	KeyColor *color.Color
+
+	// LevelFormatter is the middleware formatting function to call to format the level.
+	//
+	// If nil, the level is printed using FormatLevelValueDefault().
+	LevelFormatter FormatLevelValueFn
+
+	// MaxGroupDepth is the maximum number of nested groups to flatten into attribute keys.
+	//
+	// Any group nested deeper is printed on a single line with the group encoded as a JSON object as its value. If
+	// this is 0 or less, groups are always flattened.
+	MaxGroupDepth int
+
+	// MessageFormatter is the middlware formatting function to call to format the message.
+	//
+	// If nil, the message is printed as-is.
+	MessageFormatter FormatMessageValueFn
+
+	// SortAttributes determines whether or not to sort attributes in the output.
+	SortAttributes bool
+
+	// SourceFormatter is the middleware formatting function to call to format the source code location where the record
+	// was created.
+	//
+	// If nil, the source code location is printed using FormatSourceValueDefault().
+	SourceFormatter FormatSourceValueFn
+
+	// TimeFormatter is the middleware formatting function to call to the time of the record.
+	//
+	// If nil, the time is printed using FormatTimeValueDefault().
+	TimeFormatter FormatTimeValueFn
+
+	// TimeZone is the location to convert the time of the record and any time values in attributes to before they are
+	// formatted.
+	//
+	// If nil, times are converted to UTC. Use time.Local to print times in the local time zone.
+	TimeZone *time.Location
+
+	// ValueTransformers is a map of functions to call to transform attribute values of a specific kind before they
+	// are printed.
+	//
+	// Values such as []byte use slog.KindAny, so their transformer should check the underlying type using Value.Any().
+	ValueTransformers map[slog.Kind]func(slog.Value) slog.Value
+}
+
+// ContextWithPrettyFormatterOptions adds the options to the given context and returns the new context.
+func ContextWithPrettyFormatterOptions(ctx context.Context, opts PrettyFormatterOptions) context.Context {
+	return context.WithValue(ctx, prettyFormatterOptionsContext{}, &opts)
+}
+
+// DefaultPrettyFormatterOptions returns a default set of options for the pretty formatter.
+func DefaultPrettyFormatterOptions() PrettyFormatterOptions {
+	return PrettyFormatterOptions{
+		GroupSeparator: slogx.DefaultGroupSeparator,
+		HeaderParts: []ConsoleFormatterPart{
+			ConsoleFormatterTimePart,
+			ConsoleFormatterLevelPart,
+			ConsoleFormatterSourcePart,
+			">",
+			ConsoleFormatterMessagePart,
+		},
+		IgnoreAttrs:     []string{},
+		Indent:          "    ",
+		LevelFormatter:  FormatLevelValueDefault,
+		SortAttributes:  true,
+		SourceFormatter: FormatSourceValueDefault,
+		TimeFormatter: func(ctx context.Context, level slog.Leveler, t time.Time) (string, error) {
+			return t.Format("03:04:05PM"), nil
+		},
+	}
+}
+
+// PrettyFormatterOptionsFromContext retrieves the formatter options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func PrettyFormatterOptionsFromContext(ctx context.Context) *PrettyFormatterOptions {
+	o := ctx.Value(prettyFormatterOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*PrettyFormatterOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultPrettyFormatterOptions()
+	return &opts
+}
+
+// prettyFormatter formats records as a header line followed by an indented line for each attribute, which is
+// typically easier to read than the console formatter during local development.
+//
+// For example:
+//
+//	03:04:05PM INF main.go:12 > request completed
+//	    http.method: GET
+//	    http.status: 200
+type prettyFormatter struct {
+	// unexported variables
+	ignoredAttrPatterns []*regexp.Regexp
+	options             PrettyFormatterOptions
+}
+
+// DefaultPrettyFormatter returns a pretty formatter with typical defaults already set.
+//
+// If colorize is true, the level, source and attribute keys are colorized using the same colors as the console
+// formatter.
+func DefaultPrettyFormatter(colorize bool) *prettyFormatter {
+	options := DefaultPrettyFormatterOptions()
+	if colorize {
+		options.EnableColor = true
+		options.HeaderParts = []ConsoleFormatterPart{
+			ConsoleFormatterTimePart,
+			ConsoleFormatterLevelPart,
+			ConsoleFormatterSourcePart,
+			ConsoleFormatterPart(color.New(color.FgHiWhite).Sprint(">")),
+			ConsoleFormatterMessagePart,
+		}
+		options.LevelFormatter = ColorizeLevelFormatter
+		options.SourceFormatter = ColorizeSourceFormatter
+	}
+	return NewPrettyFormatter(options)
+}
+
+// NewPrettyFormatter creates and returns a new pretty formatter.
+func NewPrettyFormatter(opts PrettyFormatterOptions) *prettyFormatter {
+	// set default options
+	if len(opts.HeaderParts) == 0 {
+		opts.HeaderParts = []ConsoleFormatterPart{
+			ConsoleFormatterTimePart,
+			ConsoleFormatterLevelPart,
+			ConsoleFormatterSourcePart,
+			">",
+			ConsoleFormatterMessagePart,
+		}
+	}
+	if opts.GroupSeparator == "" {
+		opts.GroupSeparator = slogx.DefaultGroupSeparator
+	}
+	if opts.Indent == "" {
+		opts.Indent = "    "
+	}
+	if opts.KeyColor == nil {
+		opts.KeyColor = color.New(color.FgHiBlue)
+	}
+
+	// create the formatter object
+	f := &prettyFormatter{
+		ignoredAttrPatterns: []*regexp.Regexp{},
+		options:             opts,
+	}
+	for _, p := range opts.IgnoreAttrs {
+		regex, err := regexp.Compile(p)
+		if err == nil {
+			f.ignoredAttrPatterns = append(f.ignoredAttrPatterns, regex)
+		}
+	}
+	return f
+}
+
+// FormatRecord handles formatting the given record and outputting it into the returned buffer for consumption by a
+// handler.
+//
+// The header line is always followed by a newline character, as is each attribute line.
+func (f *prettyFormatter) FormatRecord(ctx context.Context, timestamp time.Time, level slogx.Level, pc uintptr,
+	msg string, attrs []slog.Attr) (*slogx.Buffer, error) {
+
+	var err error
+	var strVal string
+	buf := slogx.NewBuffer()
+	formatterCtx := ContextWithPrettyFormatterOptions(ctx, f.options)
+
+	// print the header line
+	for _, part := range f.options.HeaderParts {
+		switch part {
+		case ConsoleFormatterLevelPart:
+			if f.options.LevelFormatter != nil {
+				strVal, err = f.options.LevelFormatter(formatterCtx, level)
+			} else {
+				strVal, err = FormatLevelValueDefault(formatterCtx, level)
+			}
+		case ConsoleFormatterMessagePart:
+			if f.options.MessageFormatter != nil {
+				strVal, err = formatMessage(formatterCtx, f.options.MessageFormatter, level, msg, attrs,
+					f.options.GroupSeparator, f.ignoredAttrPatterns, nil)
+			} else {
+				strVal, err = msg, nil
+			}
+		case ConsoleFormatterSourcePart:
+			if f.options.SourceFormatter != nil {
+				strVal, err = f.options.SourceFormatter(formatterCtx, level, pc)
+			} else {
+				strVal, err = FormatSourceValueDefault(formatterCtx, level, pc)
+			}
+		case ConsoleFormatterTimePart:
+			strVal, err = formatRecordTime(formatterCtx, level, timestamp, f.options.TimeFormatter,
+				f.options.TimeZone, f.options.DeterministicTime)
+		default:
+			strVal, err = string(part), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strVal == "" {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(strVal)
+	}
+	buf.WriteByte('\n')
+
+	// print each attribute on its own line
+	if f.options.SortAttributes {
+		attrs = slogx.SortAttrs(attrs)
+	}
+	flattenOpts := slogx.FlattenAttrsOptions{
+		MaxGroupDepth: f.options.MaxGroupDepth,
+		Separator:     f.options.GroupSeparator,
+	}
+	for _, attr := range slogx.FlattenAttrsWithOptions(attrs, flattenOpts) {
+		if f.isIgnored(attr.Key) {
+			continue
+		}
+		key := attr.Key
+		if f.options.EnableColor {
+			key = f.options.KeyColor.Sprint(key)
+		}
+		value := f.formatValue(transformValue(attr.Value.Resolve(), f.options.ValueTransformers))
+		value = strings.ReplaceAll(value, "\n", "\n"+f.options.Indent+f.options.Indent)
+		fmt.Fprintf(buf, "%s%s: %s\n", f.options.Indent, key, value)
+	}
+	return buf, nil
+}
+
+// IsColorized returns whether or not the formatter is enabled for colorizing the output.
+func (f prettyFormatter) IsColorized() bool {
+	return f.options.EnableColor
+}
+
+// formatValue returns the given attribute value formatted as a string.
+func (f prettyFormatter) formatValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindDuration:
+		return f.options.DurationFormat.Format(v.Duration())
+	case slog.KindTime:
+		return formatAttrTime(v.Time(), f.options.AttrTimeLayout, f.options.TimeZone)
+	case slog.KindAny:
+		if tm, ok := v.Any().(encoding.TextMarshaler); ok {
+			if output, err := tm.MarshalText(); err == nil {
+				return string(output)
+			}
+		}
+		return fmt.Sprintf("%+v", v.Any())
+	default:
+		return v.String()
+	}
+}
+
+// isIgnored determines whether or not the attribute with the given key should not be printed.
+func (f prettyFormatter) isIgnored(key string) bool {
+	return isIgnoredKey(key, f.options.GroupSeparator, f.ignoredAttrPatterns)
+}
@@ -0,0 +1,38 @@
+package formatter_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/formatter/formattertest"
+)
+
+func TestPrettyFormatter(t *testing.T) {
+	opts := formatter.DefaultPrettyFormatterOptions()
+	opts.HeaderParts = []formatter.ConsoleFormatterPart{
+		formatter.ConsoleFormatterLevelPart,
+		">",
+		formatter.ConsoleFormatterMessagePart,
+	}
+	opts.Indent = "  "
+	opts.IgnoreAttrs = []string{`^secret$`}
+	f := formatter.NewPrettyFormatter(opts)
+	output, err := formattertest.FormatToString(f, slogx.LevelWarn, "request completed",
+		slog.String("secret", "hidden"),
+		slog.Group("http", slog.String("method", "GET"), slog.Int("status", 200)),
+		slog.String("body", "line one\nline two"),
+	)
+	if err != nil {
+		t.Errorf("failed to format record: %s", err.Error())
+		return
+	}
+	expected := "WRN > request completed\n" +
+		"  body: line one\n    line two\n" +
+		"  http.method: GET\n" +
+		"  http.status: 200\n"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
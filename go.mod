@@ -6,14 +6,14 @@ require (
 	github.com/fatih/color v1.15.0
 	github.com/go-resty/resty/v2 v2.9.1
 	github.com/mattn/go-colorable v0.1.13
+	github.com/mattn/go-isatty v0.0.17
 	go.innotegrity.dev/async v0.1.1
 	go.innotegrity.dev/errorx v1.0.15
 	go.innotegrity.dev/generic v0.1.1
 	go.innotegrity.dev/runtimex v0.1.0
+	golang.org/x/sys v0.12.0
 )
 
 require (
-	github.com/mattn/go-isatty v0.0.17 // indirect
 	golang.org/x/net v0.15.0 // indirect
-	golang.org/x/sys v0.12.0 // indirect
 )
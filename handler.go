@@ -3,8 +3,19 @@ package slogx
 import (
 	"context"
 	"log/slog"
+	"slices"
 )
 
+// AttrReplacingHandler should be implemented by handlers which are able to replace existing attributes with the same
+// key when attributes are added instead of appending duplicates.
+type AttrReplacingHandler interface {
+	slog.Handler
+
+	// WithReplacedAttrs should return a new handler with the given attributes added to it, replacing the value of
+	// any existing attribute with the same key within the handler's current group.
+	WithReplacedAttrs([]slog.Attr) slog.Handler
+}
+
 // LevelVarHandler should be implemented by handlers that use a dynamic level via a LevelVar object.
 type LevelVarHandler interface {
 	slog.Handler
@@ -13,6 +24,15 @@ type LevelVarHandler interface {
 	Level() *LevelVar
 }
 
+// MinLevelHandler should be implemented by handlers which pass records onto other handlers so that the lowest level
+// any of them logs can be determined.
+type MinLevelHandler interface {
+	slog.Handler
+
+	// MinLevel should return the lowest level at which the handler logs records.
+	MinLevel() slog.Level
+}
+
 // ShutdownableHandler should be implemented by handlers which allocate resources that need cleaning up before an
 // application exits.
 type ShutdownableHandler interface {
@@ -91,3 +111,27 @@ func HandlerOptionsFromContext(ctx context.Context, name string) any {
 	}
 	return nil
 }
+
+// groupStackContextKey is used to store a handler's active group stack in a standard Go context object.
+type groupStackContextKey struct{}
+
+// ContextWithGroupStack copies the given context and returns a new context with the given group stack stored in it.
+//
+// Handlers call this before formatting a record so that formatters can access the chain of groups created by
+// calling WithGroup() on the handler or logger. A clipped copy of the groups is stored, so neither modifying nor
+// appending to the slice returned by GroupStackFromContext() affects the handler's own group stack.
+func ContextWithGroupStack(ctx context.Context, groups []string) context.Context {
+	return context.WithValue(ctx, groupStackContextKey{}, slices.Clip(slices.Clone(groups)))
+}
+
+// GroupStackFromContext retrieves the group stack stored in the given context, if it exists.
+//
+// The first group in the slice is the outermost group. If no group stack is stored in the context, nil is returned.
+func GroupStackFromContext(ctx context.Context) []string {
+	if v := ctx.Value(groupStackContextKey{}); v != nil {
+		if groups, ok := v.([]string); ok {
+			return groups
+		}
+	}
+	return nil
+}
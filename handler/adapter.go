@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"slices"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// AdapterLevelNameKey is the key of the attribute added by the adapter handler to hold the slogx name of the level.
+const AdapterLevelNameKey = "level_name"
+
+// adapterHandler is a handler which sits in front of a third-party slog.Handler, adding slogx level naming and
+// shutdown semantics.
+//
+// Third-party handlers only know the standard slog levels, so they typically print extended levels such as
+// slogx.LevelNotice as "INFO+2". For any record whose level is not one of the standard slog levels, the adapter adds
+// an attribute named AdapterLevelNameKey holding the slogx name of the level (eg: NOTICE) before passing it onto the
+// next handler. When the next handler is one of the standard library handlers, consider setting its ReplaceAttr
+// option to slogx.LevelReplaceAttr instead.
+//
+// The attribute is always added at the top level of the record. To do so, once a group has been added to the adapter
+// using WithGroup(), the adapter keeps track of the groups and of any attributes added within them itself rather than
+// passing them onto the next handler, and adds them to each record instead.
+type adapterHandler struct {
+	// unexported variables
+	attrs  []slog.Attr
+	groups []string
+	next   slog.Handler
+}
+
+// NewAdapter creates a new handler object.
+func NewAdapter(next slog.Handler) *adapterHandler {
+	return &adapterHandler{
+		attrs:  []slog.Attr{},
+		groups: []string{},
+		next:   next,
+	}
+}
+
+// Enabled returns whether or not the next handler would log this message.
+func (h adapterHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next == nil {
+		return false
+	}
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle sends the record onto the next handler, adding the name of the level if it is not a standard slog level.
+func (h *adapterHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.next == nil {
+		return nil
+	}
+	var levelName []slog.Attr
+	switch r.Level {
+	case slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError:
+	default:
+		levelName = []slog.Attr{slog.String(AdapterLevelNameKey, slogx.Level(r.Level).String())}
+	}
+	if len(h.groups) == 0 {
+		if len(levelName) > 0 {
+			r = r.Clone()
+			r.AddAttrs(levelName...)
+		}
+		return h.next.Handle(ctx, r)
+	}
+
+	// nest the record's attributes within the groups ourselves so the level name stays at the top level
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	nr.AddAttrs(levelName...)
+	nr.AddAttrs(slogx.ConsolidateAttrsWithOptions(h.attrs, "", r, slogx.ConsolidateAttrsOptions{
+		DeferResolve:   true,
+		Groups:         h.groups,
+		KeepDuplicates: true,
+	})...)
+	return h.next.Handle(ctx, nr)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+//
+// If the next handler does not implement slogx.ShutdownableHandler, this does nothing.
+func (h adapterHandler) Shutdown(continueOnError bool) error {
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+//
+// Until a group is added, the attributes are passed onto the next handler. If there is no next handler, the existing
+// object is returned instead.
+func (h adapterHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next == nil {
+		return &h
+	}
+	if len(h.groups) == 0 {
+		return &adapterHandler{
+			attrs:  h.attrs,
+			groups: h.groups,
+			next:   h.next.WithAttrs(attrs),
+		}
+	}
+	return &adapterHandler{
+		attrs:  append(slices.Clip(h.attrs), slogx.NestAttrs(h.groups, attrs)...),
+		groups: h.groups,
+		next:   h.next,
+	}
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+//
+// The group is not passed onto the next handler so that the level name can still be added at the top level. If there
+// is no next handler or the name is empty, the existing object is returned instead.
+func (h adapterHandler) WithGroup(name string) slog.Handler {
+	if h.next == nil || name == "" {
+		return &h
+	}
+	return &adapterHandler{
+		attrs:  h.attrs,
+		groups: append(slices.Clip(h.groups), name),
+		next:   h.next,
+	}
+}
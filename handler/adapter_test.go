@@ -0,0 +1,66 @@
+package handler_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestAdapter(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewAdapter(slog.NewTextHandler(&output, &slog.HandlerOptions{
+		Level: slogx.LevelTrace,
+	}))
+	logger := slogx.Wrap(slog.New(h))
+
+	logger.Notice("notice message")
+	logger.Info("info message")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Errorf("expected 2 lines, got: %s", output.String())
+		return
+	}
+	if !strings.Contains(lines[0], "level_name=NOTICE") {
+		t.Errorf("expected level name for extended level, got: %s", lines[0])
+	}
+	if strings.Contains(lines[1], "level_name") {
+		t.Errorf("expected no level name for standard level, got: %s", lines[1])
+	}
+	if err := h.Shutdown(false); err != nil {
+		t.Errorf("expected shutdown to succeed, got: %s", err.Error())
+	}
+}
+
+func TestAdapterWithGroup(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewAdapter(slog.NewTextHandler(&output, &slog.HandlerOptions{
+		Level: slogx.LevelTrace,
+	}))
+	logger := slogx.Wrap(slog.New(h).With("app", "test").WithGroup("req").With("id", 1).WithGroup("user"))
+
+	logger.Notice("notice message", "name", "frodo")
+	logger.Notice("empty message")
+	logger.Info("info message", "name", "sam")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	expected := []string{
+		`level=INFO+2 msg="notice message" app=test level_name=NOTICE req.id=1 req.user.name=frodo`,
+		`level=INFO+2 msg="empty message" app=test level_name=NOTICE req.id=1`,
+		`level=INFO msg="info message" app=test req.id=1 req.user.name=sam`,
+	}
+	if len(lines) != len(expected) {
+		t.Errorf("expected %d lines, got: %s", len(expected), output.String())
+		return
+	}
+	for i, line := range lines {
+		if _, after, _ := strings.Cut(line, " "); after != expected[i] {
+			t.Errorf("expected line %q, got %q", expected[i], after)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+
+	"log/slog"
+
+	"go.innotegrity.dev/generic"
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+// compileAttrPatterns compiles the given list of regular expressions, ignoring any which do not compile.
+func compileAttrPatterns(patterns []string) []*regexp.Regexp {
+	result := []*regexp.Regexp{}
+	for _, p := range patterns {
+		regex, err := regexp.Compile(p)
+		if err == nil {
+			result = append(result, regex)
+		}
+	}
+	return result
+}
+
+// consolidateAttrs combines the handler's attributes with the attributes from the record, nesting the record's
+// attributes within the handler's groups, and removes any attributes whose key matches one of the given patterns.
+//
+// Attribute values are only resolved once the attributes to remove have been dropped so that any expensive
+// slog.LogValuer values for removed attributes are never evaluated. Attributes with duplicate keys are kept if the
+// formatter implements formatter.DuplicateKeyFormatter and needs them.
+func consolidateAttrs(attrs []slog.Attr, groups []string, r slog.Record, patterns []*regexp.Regexp,
+	f formatter.BufferFormatter) []slog.Attr {
+
+	keepDuplicates := false
+	if df, ok := f.(formatter.DuplicateKeyFormatter); ok {
+		keepDuplicates = df.KeepsDuplicateKeys()
+	}
+	return removeAttrs(slogx.ConsolidateAttrsWithOptions(attrs, "", r, slogx.ConsolidateAttrsOptions{
+		DeferResolve:   len(patterns) > 0,
+		Groups:         groups,
+		KeepDuplicates: keepDuplicates,
+	}), "", patterns)
+}
+
+// nameAttrs returns the attributes a handler starts with, which hold the name of the handler under the given key so
+// that it is added to every record.
+//
+// If either the name or the key is empty, no attributes are returned.
+func nameAttrs(name, key string) []slog.Attr {
+	if name == "" || key == "" {
+		return []slog.Attr{}
+	}
+	return []slog.Attr{slog.String(key, name)}
+}
+
+// removeAttrs removes any attributes whose key matches one of the given patterns from the slice and any nested groups.
+//
+// Nested attributes are matched using their full key path with a single period (.) separating groups and attribute
+// names (eg: GROUP.ATTRIBUTE). If a group matches, the entire group is removed. Groups which are left empty after
+// their attributes are removed are removed as well. The values of any attributes which are kept are resolved.
+func removeAttrs(attrs []slog.Attr, group string, patterns []*regexp.Regexp) []slog.Attr {
+	if len(patterns) == 0 {
+		return attrs
+	}
+
+	result := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		groupWithKey := attr.Key
+		if group != "" {
+			groupWithKey = fmt.Sprintf("%s.%s", group, attr.Key)
+		}
+		if matchesAnyPattern(groupWithKey, patterns) {
+			continue
+		}
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() == slog.KindGroup {
+			groupAttrs := removeAttrs(attr.Value.Group(), groupWithKey, patterns)
+			if len(groupAttrs) == 0 {
+				continue
+			}
+			attr = slog.Group(attr.Key, generic.AnySlice(groupAttrs)...)
+		}
+		result = append(result, attr)
+	}
+	return result
+}
+
+// replaceAttrs adds the new attributes to the handler's attributes, nesting them within the handler's groups, and
+// removes any duplicates so that only the last value for each key is kept at the position of the first.
+//
+// Attribute values are left unresolved so that any slog.LogValuer is still evaluated when each record is handled.
+func replaceAttrs(attrs []slog.Attr, groups []string, newAttrs []slog.Attr) []slog.Attr {
+	var r slog.Record
+	r.AddAttrs(newAttrs...)
+	return slogx.ConsolidateAttrsWithOptions(attrs, "", r, slogx.ConsolidateAttrsOptions{
+		DeferResolve: true,
+		Groups:       groups,
+	})
+}
+
+// resolveAttrs resolves the values of the given attributes and any attributes nested within groups.
+//
+// This ensures that any slog.LogValuer values are only evaluated once when the same attributes are passed to multiple
+// formatters.
+func resolveAttrs(attrs []slog.Attr) []slog.Attr {
+	result := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() == slog.KindGroup {
+			attr = slog.Group(attr.Key, generic.AnySlice(resolveAttrs(attr.Value.Group()))...)
+		}
+		result = append(result, attr)
+	}
+	return result
+}
+
+// matchesAnyPattern determines whether or not the given string matches any of the given patterns.
+func matchesAnyPattern(s string, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
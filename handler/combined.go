@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+// combinedHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type combinedHandlerOptionsContext struct{}
+
+// CombinedHandlerOptions holds the options for the combined console and JSON handler.
+type CombinedHandlerOptions struct {
+	// ConsoleFormatter specifies the formatter to use to format the record before writing it to ConsoleWriter.
+	//
+	// If no formatter is supplied, a colorized formatter.DefaultConsoleFormatter is used to format the output.
+	ConsoleFormatter formatter.ColorBufferFormatter
+
+	// ConsoleWriter is where to write the console output to.
+	//
+	// By default, console output is written to os.Stdout if not supplied.
+	ConsoleWriter io.Writer
+
+	// ForceColor prevents colorized output from being stripped when ConsoleWriter is not a terminal.
+	ForceColor bool
+
+	// ForceTTY treats ConsoleWriter as a terminal even if it cannot be detected as one.
+	//
+	// See ConsoleHandlerOptions.ForceTTY for details.
+	ForceTTY bool
+
+	// JSONFormatter specifies the formatter to use to format the record before writing it to JSONWriter.
+	//
+	// If no formatter is supplied, formatter.DefaultJSONFormatter is used to format the output.
+	JSONFormatter formatter.BufferFormatter
+
+	// JSONWriter is where to write the JSON output to.
+	//
+	// This is a required option.
+	JSONWriter io.Writer
+
+	// Level is the minimum log level to write to the handler.
+	//
+	// If this is nil, it defaults to slogx.LevelInfo.
+	Level *slogx.LevelVar
+
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+}
+
+// ContextWithCombinedHandlerOptions adds the options to the given context and returns the new context.
+func ContextWithCombinedHandlerOptions(ctx context.Context, opts CombinedHandlerOptions) context.Context {
+	return context.WithValue(ctx, combinedHandlerOptionsContext{}, &opts)
+}
+
+// DefaultCombinedHandlerOptions returns a default set of options for the handler.
+func DefaultCombinedHandlerOptions() CombinedHandlerOptions {
+	return CombinedHandlerOptions{
+		ConsoleFormatter: formatter.DefaultConsoleFormatter(true),
+		ConsoleWriter:    os.Stdout,
+		JSONFormatter:    formatter.DefaultJSONFormatter(),
+		Level:            slogx.NewLevelVar(slogx.LevelInfo),
+	}
+}
+
+// CombinedHandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func CombinedHandlerOptionsFromContext(ctx context.Context) *CombinedHandlerOptions {
+	o := ctx.Value(combinedHandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*CombinedHandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultCombinedHandlerOptions()
+	return &opts
+}
+
+// combinedHandler is a log handler that writes each record to a console writer and a JSON writer.
+//
+// This produces the same output as a multi handler wrapping a console handler and a JSON handler, but the attributes
+// for each record are only consolidated and resolved once rather than once per handler.
+type combinedHandler struct {
+	attrs     []slog.Attr
+	closed    *bool
+	groups    []string
+	options   CombinedHandlerOptions
+	writeLock *sync.Mutex
+}
+
+// NewCombinedHandler creates a new handler object.
+func NewCombinedHandler(opts CombinedHandlerOptions) (*combinedHandler, error) {
+	// validate required options
+	if opts.JSONWriter == nil {
+		return nil, ErrMissingJSONWriter
+	}
+
+	// set default options
+	if opts.ConsoleFormatter == nil {
+		opts.ConsoleFormatter = formatter.DefaultConsoleFormatter(true)
+	}
+	if opts.ConsoleWriter == nil {
+		opts.ConsoleWriter = os.Stdout
+	}
+	if opts.ConsoleFormatter.IsColorized() {
+		opts.ConsoleWriter = colorizeWriter(opts.ConsoleWriter, opts.ForceColor, opts.ForceTTY)
+	}
+	if opts.JSONFormatter == nil {
+		opts.JSONFormatter = formatter.DefaultJSONFormatter()
+	}
+	if opts.Level == nil {
+		opts.Level = slogx.NewLevelVar(slogx.LevelInfo)
+	}
+
+	// create the handler
+	return &combinedHandler{
+		attrs:     nameAttrs(opts.Name, opts.NameAttrKey),
+		closed:    new(bool),
+		groups:    []string{},
+		options:   opts,
+		writeLock: &sync.Mutex{},
+	}, nil
+}
+
+// Enabled determines whether or not the given level is enabled in this handler.
+func (h combinedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slogx.Level(level) >= h.options.Level.Level()
+}
+
+// Handle actually handles writing the record to the console and JSON writers.
+//
+// Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
+// If a duplicate is encountered, the last value found will be used for the attribute's value, unless the JSON
+// formatter keeps duplicates, in which case they are only removed for the console formatter. Both records are
+// written even if writing to one of the writers fails.
+func (h *combinedHandler) Handle(ctx context.Context, r slog.Record) error {
+	handlerCtx := slogx.ContextWithGroupStack(ContextWithCombinedHandlerOptions(ctx, h.options), h.groups)
+	jsonAttrs := resolveAttrs(consolidateAttrs(h.attrs, h.groups, r, nil, h.options.JSONFormatter))
+	consoleAttrs := jsonAttrs
+	if df, ok := h.options.JSONFormatter.(formatter.DuplicateKeyFormatter); ok && df.KeepsDuplicateKeys() {
+		consoleAttrs = slogx.UniqAttrs(jsonAttrs)
+	}
+
+	// format the output into buffers
+	consoleBuf, err := h.options.ConsoleFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC,
+		r.Message, consoleAttrs)
+	if err != nil {
+		return err
+	}
+	jsonBuf, err := h.options.JSONFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
+		jsonAttrs)
+	if err != nil {
+		return err
+	}
+
+	// write the buffers to the outputs
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	if *h.closed {
+		return ErrHandlerClosed
+	}
+	_, consoleErr := h.options.ConsoleWriter.Write(consoleBuf.Bytes())
+	_, jsonErr := h.options.JSONWriter.Write(jsonBuf.Bytes())
+	return errors.Join(consoleErr, jsonErr)
+}
+
+// Level returns a pointer to the handler's level for updating.
+func (h combinedHandler) Level() *slogx.LevelVar {
+	return h.options.Level
+}
+
+// Shutdown is responsible for cleaning up resources used by the handler.
+//
+// Once the handler has been shut down, any further records are rejected with ErrHandlerClosed.
+func (h combinedHandler) Shutdown(continueOnError bool) error {
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	if *h.closed {
+		return nil
+	}
+	*h.closed = true
+	for _, writer := range []io.Writer{h.options.ConsoleWriter, h.options.JSONWriter} {
+		if w, ok := writer.(io.WriteCloser); ok {
+			if err := w.Close(); err != nil && !continueOnError {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+func (h combinedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := &combinedHandler{
+		attrs:     h.attrs,
+		closed:    h.closed,
+		groups:    h.groups,
+		options:   h.options,
+		writeLock: h.writeLock,
+	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
+	return newHandler
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+func (h combinedHandler) WithGroup(name string) slog.Handler {
+	newHandler := &combinedHandler{
+		attrs:     h.attrs,
+		closed:    h.closed,
+		groups:    h.groups,
+		options:   h.options,
+		writeLock: h.writeLock,
+	}
+	if name != "" {
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
+	}
+	return newHandler
+}
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h combinedHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*combinedHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
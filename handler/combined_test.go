@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestCombinedHandler(t *testing.T) {
+	var consoleOutput, jsonOutput bytes.Buffer
+	h, err := handler.NewCombinedHandler(handler.CombinedHandlerOptions{
+		ConsoleFormatter: formatter.DefaultConsoleFormatter(false),
+		ConsoleWriter:    &consoleOutput,
+		JSONWriter:       &jsonOutput,
+	})
+	if err != nil {
+		t.Errorf("failed to create handler: %s", err.Error())
+		return
+	}
+	logger := slogx.Wrap(slog.New(h))
+
+	calls := 0
+	logger.Info("request completed", slogx.Lazy("status", func() any {
+		calls++
+		return 200
+	}))
+
+	if !strings.Contains(consoleOutput.String(), "request completed status=200") {
+		t.Errorf("unexpected console output: %s", consoleOutput.String())
+	}
+	if !strings.Contains(jsonOutput.String(), `"status":200`) {
+		t.Errorf("unexpected JSON output: %s", jsonOutput.String())
+	}
+	if calls != 1 {
+		t.Errorf("expected lazy attribute to be resolved once, got %d", calls)
+	}
+}
+
+func BenchmarkCombinedHandler(b *testing.B) {
+	h, err := handler.NewCombinedHandler(handler.CombinedHandlerOptions{
+		ConsoleWriter: io.Discard,
+		ForceColor:    true,
+		JSONWriter:    io.Discard,
+		Level:         slogx.NewLevelVar(slogx.LevelTrace),
+	})
+	if err != nil {
+		b.Errorf("failed to create handler: %s", err.Error())
+		return
+	}
+	benchmarkConsoleJSONHandler(b, h)
+}
+
+func BenchmarkMultiHandlerConsoleJSON(b *testing.B) {
+	h := handler.NewMultiHandler(handler.MultiHandlerOptions{},
+		handler.NewConsoleHandler(handler.ConsoleHandlerOptions{
+			ForceColor: true,
+			Level:      slogx.NewLevelVar(slogx.LevelTrace),
+			Writer:     io.Discard,
+		}),
+		handler.NewJSONHandler(handler.JSONHandlerOptions{
+			Level:  slogx.NewLevelVar(slogx.LevelTrace),
+			Writer: io.Discard,
+		}),
+	)
+	benchmarkConsoleJSONHandler(b, h)
+}
+
+// benchmarkConsoleJSONHandler logs a typical record with the given handler.
+func benchmarkConsoleJSONHandler(b *testing.B, h slog.Handler) {
+	logger := slogx.Wrap(slog.New(h).With(slog.String("service", "api")).WithGroup("request"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request completed",
+			slog.String("method", "GET"),
+			slog.String("path", "/api/v1/users"),
+			slog.Int("status", 200),
+			slog.Group("client", slog.String("ip", "127.0.0.1"), slog.String("agent", "curl/8.0")),
+		)
+	}
+}
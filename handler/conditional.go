@@ -12,6 +12,17 @@ import (
 // ConditionMatchesFn is called to determine whether or not the given record should be logged.
 type ConditionMatchesFn func(ctx context.Context, r slog.Record) bool
 
+// MatchExactLevels returns a condition function which matches records whose level is in the given set.
+//
+// Unlike a minimum level, this allows only specific levels to be sent to a handler (eg: notice and error but not
+// warning). Levels are matched exactly, so slogx.LevelError does not match a record at slogx.LevelError+1. The set is
+// typically created using slogx.ParseLevelSet().
+func MatchExactLevels(set map[slogx.Level]bool) ConditionMatchesFn {
+	return func(ctx context.Context, r slog.Record) bool {
+		return set[slogx.Level(r.Level)]
+	}
+}
+
 // Condition defines the condition(s) which must all be true in order to log a message to the given handler.
 //
 // If no conditions are specified, the handler will always be used to log the messages.
@@ -19,6 +30,7 @@ type Condition struct {
 	// unexported variables
 	handler    slog.Handler
 	matcherFns []ConditionMatchesFn
+	minLevel   slog.Leveler
 }
 
 // NewCondition defines one or more functions to call to determine whether or not to log to the given handler.
@@ -42,6 +54,7 @@ func (c Condition) WithCondition(matcher ...ConditionMatchesFn) *Condition {
 	return &Condition{
 		matcherFns: append(c.matcherFns, matcher...),
 		handler:    c.handler,
+		minLevel:   c.minLevel,
 	}
 }
 
@@ -50,9 +63,31 @@ func (c Condition) WithHandler(handler slog.Handler) *Condition {
 	return &Condition{
 		matcherFns: c.matcherFns,
 		handler:    handler,
+		minLevel:   c.minLevel,
 	}
 }
 
+// WithMinLevel sets the minimum level a record must have in order for the condition to be evaluated and returns a
+// new condition.
+//
+// Because the level is known before the record is created, this allows the conditional handler to skip creating
+// records which could never be logged. If nil, only the underlying handler's level is considered.
+func (c Condition) WithMinLevel(level slog.Leveler) *Condition {
+	return &Condition{
+		matcherFns: c.matcherFns,
+		handler:    c.handler,
+		minLevel:   level,
+	}
+}
+
+// enabled determines whether or not the given level could be logged by the condition's handler.
+func (c Condition) enabled(ctx context.Context, l slog.Level) bool {
+	if c.minLevel != nil && l < c.minLevel.Level() {
+		return false
+	}
+	return c.handler != nil && c.handler.Enabled(ctx, l)
+}
+
 // conditionalHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
 type conditionalHandlerOptionsContext struct{}
 
@@ -115,9 +150,17 @@ func NewConditionalHandler(opts ConditionalHandlerOptions, cond ...*Condition) *
 	}
 }
 
-// Enabled always returns true for this handler as this functionality is handled directly by the Handle function.
+// Enabled determines whether or not at least one condition's handler is enabled for the given level.
+//
+// Since conditions are evaluated against the record itself, this is only a best-effort check: a record at an enabled
+// level may still not match any condition.
 func (h conditionalHandler) Enabled(ctx context.Context, l slog.Level) bool {
-	return true
+	for _, c := range h.conditions {
+		if c.enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
 }
 
 // Handle is responsible for finding one or more matching handlers to write the record to.
@@ -134,6 +177,24 @@ func (h *conditionalHandler) Handle(ctx context.Context, r slog.Record) error {
 	return nil
 }
 
+// MinLevel returns the lowest level at which any condition's handler logs records.
+//
+// Any minimum level set on a condition using WithMinLevel() is taken into account. Since conditions are evaluated
+// against the record itself, this is only a best-effort value: a record at the returned level may still not match any
+// condition. Handlers which do not report their level directly are checked using Enabled(). If there are no
+// conditions, slogx.LevelDisabled is returned.
+func (h conditionalHandler) MinLevel() slog.Level {
+	level := slog.Level(slogx.LevelDisabled)
+	for _, c := range h.conditions {
+		conditionLevel := handlerMinLevel(c.handler)
+		if c.minLevel != nil {
+			conditionLevel = max(conditionLevel, c.minLevel.Level())
+		}
+		level = min(level, conditionLevel)
+	}
+	return level
+}
+
 // Shutdown is responsible for cleaning up resources used by the handler.
 func (h conditionalHandler) Shutdown(continueOnError bool) error {
 	for _, c := range h.conditions {
@@ -155,7 +216,7 @@ func (h conditionalHandler) Shutdown(continueOnError bool) error {
 func (h conditionalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	conditions := []*Condition{}
 	for _, c := range h.conditions {
-		conditions = append(conditions, NewCondition(c.handler.WithAttrs(attrs), c.matcherFns...))
+		conditions = append(conditions, c.WithHandler(c.handler.WithAttrs(attrs)))
 	}
 	handler := NewConditionalHandler(h.options, conditions...)
 	handler.futures = h.futures
@@ -166,7 +227,7 @@ func (h conditionalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 func (h conditionalHandler) WithGroup(name string) slog.Handler {
 	conditions := []*Condition{}
 	for _, c := range h.conditions {
-		conditions = append(conditions, NewCondition(c.handler.WithGroup(name), c.matcherFns...))
+		conditions = append(conditions, c.WithHandler(c.handler.WithGroup(name)))
 	}
 	handler := NewConditionalHandler(h.options, conditions...)
 	handler.futures = h.futures
@@ -176,7 +237,7 @@ func (h conditionalHandler) WithGroup(name string) slog.Handler {
 // handle is responsible for actually writing the record to the appropriate handler(s).
 func (h conditionalHandler) handle(ctx context.Context, r slog.Record) error {
 	for _, c := range h.conditions {
-		if h.matchesAll(ctx, r, c.matcherFns) && c.handler.Enabled(ctx, r.Level) {
+		if c.enabled(ctx, r.Level) && h.matchesAll(ctx, r, c.matcherFns) {
 			if err := c.handler.Handle(ctx, r); err != nil && !h.options.ContinueOnError {
 				return err
 			}
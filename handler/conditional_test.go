@@ -0,0 +1,42 @@
+package handler_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestConditionalHandlerMatchExactLevels(t *testing.T) {
+	set, err := slogx.ParseLevelSet("notice,error")
+	if err != nil {
+		t.Errorf("failed to parse level set: %s", err.Error())
+		return
+	}
+	var output bytes.Buffer
+	h := handler.NewConditionalHandler(handler.DefaultConditionalHandlerOptions(),
+		handler.NewCondition(handler.NewWriterHandler(handler.WriterHandlerOptions{
+			Level:  slogx.NewLevelVar(slogx.LevelTrace),
+			Writer: &output,
+		}), handler.MatchExactLevels(set)))
+	logger := slogx.Wrap(slog.New(h))
+
+	logger.Info("info message")
+	logger.Notice("notice message")
+	logger.Warn("warning message")
+	logger.Error("error message")
+
+	for _, expected := range []string{"notice message", "error message"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected %q in output: %s", expected, output.String())
+		}
+	}
+	for _, unexpected := range []string{"info message", "warning message"} {
+		if strings.Contains(output.String(), unexpected) {
+			t.Errorf("did not expect %q in output: %s", unexpected, output.String())
+		}
+	}
+}
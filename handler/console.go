@@ -4,12 +4,13 @@ import (
 	"context"
 	"io"
 	"os"
+	"slices"
 	"sync"
 
 	"log/slog"
 
 	"github.com/mattn/go-colorable"
-	"go.innotegrity.dev/generic"
+	"github.com/mattn/go-isatty"
 	"go.innotegrity.dev/slogx"
 	"go.innotegrity.dev/slogx/formatter"
 )
@@ -19,11 +20,42 @@ type consoleHandlerOptionsContext struct{}
 
 // ConsoleHandlerOptions holds the options for the console handler.
 type ConsoleHandlerOptions struct {
+	// ErrorWriter is where to write records at slogx.LevelError and above to.
+	//
+	// This allows errors to be written to os.Stderr while all other records are written to os.Stdout, for example. If
+	// nil, all records are written to Writer.
+	ErrorWriter io.Writer
+
+	// ForceColor prevents colorized output from being stripped when the writer is not a terminal.
+	//
+	// By default, if the formatter is colorized but the writer is not a terminal (eg: output is redirected to a file or
+	// pipe), any ANSI color sequences are stripped from the output so they do not end up in captured logs.
+	ForceColor bool
+
+	// ForceTTY treats the writers as terminals even if they cannot be detected as one.
+	//
+	// Terminal detection can fail in some environments such as CI logs or MSYS terminals on Windows. When this is true,
+	// any *os.File writer is wrapped so that ANSI color sequences are translated for the console on Windows, and color
+	// sequences are never stripped from the output. On other platforms, this behaves the same as ForceColor.
+	ForceTTY bool
+
 	// Level is the minimum log level to write to the handler.
 	//
 	// If this is nil, it defaults to slogx.LevelInfo.
 	Level *slogx.LevelVar
 
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
 	// RecordFormatter specifies the formatter to use to format the record before writing it to the writer.
 	//
 	// If no formatter is supplied, a colorized formatter.DefaultConsoleFormatter is used to format the output.
@@ -64,12 +96,15 @@ func (o *ConsoleHandlerOptions) AddToContext(ctx context.Context) context.Contex
 }
 
 // consoleHandler is a log handler that writes records to an io.Writer, typically a console in a specified format.
+//
+// This is a specialization of the writer handler which wraps stdout and stderr for colorized output when the formatter
+// is colorized. Use NewWriterHandler() for formatters that do not support colorization.
 type consoleHandler struct {
-	activeGroup string
-	attrs       []slog.Attr
-	groups      []string
-	options     ConsoleHandlerOptions
-	writeLock   *sync.Mutex
+	attrs     []slog.Attr
+	closed    *bool
+	groups    []string
+	options   ConsoleHandlerOptions
+	writeLock *sync.Mutex
 }
 
 // NewConsoleHandler creates a new handler object.
@@ -81,14 +116,17 @@ func NewConsoleHandler(opts ConsoleHandlerOptions) *consoleHandler {
 	if opts.Writer == nil {
 		opts.Writer = os.Stdout
 	}
-	if (opts.Writer == os.Stdout || opts.Writer == os.Stderr) && opts.RecordFormatter != nil &&
-		opts.RecordFormatter.IsColorized() {
-		opts.Writer = colorable.NewColorable(opts.Writer.(*os.File))
+	if opts.RecordFormatter == nil || opts.RecordFormatter.IsColorized() {
+		opts.Writer = colorizeWriter(opts.Writer, opts.ForceColor, opts.ForceTTY)
+		if opts.ErrorWriter != nil {
+			opts.ErrorWriter = colorizeWriter(opts.ErrorWriter, opts.ForceColor, opts.ForceTTY)
+		}
 	}
 
 	// create the handler
 	return &consoleHandler{
-		attrs:     []slog.Attr{},
+		attrs:     nameAttrs(opts.Name, opts.NameAttrKey),
+		closed:    new(bool),
 		groups:    []string{},
 		options:   opts,
 		writeLock: &sync.Mutex{},
@@ -105,8 +143,8 @@ func (h consoleHandler) Enabled(ctx context.Context, level slog.Level) bool {
 // Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
 // If a duplicate is encountered, the last value found will be used for the attribute's value.
 func (h *consoleHandler) Handle(ctx context.Context, r slog.Record) error {
-	handlerCtx := h.options.AddToContext(ctx)
-	attrs := slogx.ConsolidateAttrs(h.attrs, h.activeGroup, r)
+	handlerCtx := slogx.ContextWithGroupStack(h.options.AddToContext(ctx), h.groups)
+	attrs := consolidateAttrs(h.attrs, h.groups, r, nil, h.options.RecordFormatter)
 
 	// format the output into a buffer
 	var buf *slogx.Buffer
@@ -122,18 +160,35 @@ func (h *consoleHandler) Handle(ctx context.Context, r slog.Record) error {
 		return err
 	}
 
-	// write the buffer to the output
+	// write the buffer to the output for the record's level
+	w := h.options.Writer
+	if h.options.ErrorWriter != nil && slogx.Level(r.Level) >= slogx.LevelError {
+		w = h.options.ErrorWriter
+	}
 	h.writeLock.Lock()
 	defer h.writeLock.Unlock()
-	_, err = h.options.Writer.Write(buf.Bytes())
+	if *h.closed {
+		return ErrHandlerClosed
+	}
+	_, err = w.Write(buf.Bytes())
 	return err
 }
 
 // Shutdown is responsible for cleaning up resources used by the handler.
+//
+// Once the handler has been shut down, any further records are rejected with ErrHandlerClosed.
 func (h consoleHandler) Shutdown(continueOnError bool) error {
-	if w, ok := h.options.Writer.(io.WriteCloser); ok {
-		if err := w.Close(); err != nil {
-			return err
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	if *h.closed {
+		return nil
+	}
+	*h.closed = true
+	for _, writer := range []io.Writer{h.options.Writer, h.options.ErrorWriter} {
+		if w, ok := writer.(io.WriteCloser); ok {
+			if err := w.Close(); err != nil && !continueOnError {
+				return err
+			}
 		}
 	}
 	return nil
@@ -148,16 +203,12 @@ func (h consoleHandler) Level() *slogx.LevelVar {
 func (h consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	newHandler := &consoleHandler{
 		attrs:     h.attrs,
+		closed:    h.closed,
 		groups:    h.groups,
 		options:   h.options,
 		writeLock: h.writeLock,
 	}
-	if h.activeGroup == "" {
-		newHandler.attrs = append(newHandler.attrs, attrs...)
-	} else {
-		newHandler.attrs = append(newHandler.attrs, slog.Group(h.activeGroup, generic.AnySlice(attrs)...))
-		newHandler.activeGroup = h.activeGroup
-	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
 	return newHandler
 }
 
@@ -165,13 +216,56 @@ func (h consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 func (h *consoleHandler) WithGroup(name string) slog.Handler {
 	newHandler := &consoleHandler{
 		attrs:     h.attrs,
+		closed:    h.closed,
 		groups:    h.groups,
 		options:   h.options,
 		writeLock: h.writeLock,
 	}
 	if name != "" {
-		newHandler.groups = append(newHandler.groups, name)
-		newHandler.activeGroup = name
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
 	}
 	return newHandler
 }
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h consoleHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*consoleHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
+
+// colorizeWriter wraps the writer so that colorized output is handled correctly for the type of writer.
+//
+// Terminals are wrapped so that ANSI color sequences are translated on platforms which require it. Any other writer
+// has ANSI color sequences stripped from the output unless force is true. If tty is true, any file is treated as a
+// terminal.
+func colorizeWriter(w io.Writer, force, tty bool) io.Writer {
+	if f, ok := w.(*os.File); ok && (tty || isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())) {
+		return colorable.NewColorable(f)
+	}
+	if force || tty {
+		return w
+	}
+	return nonColorableWriter{
+		Writer: colorable.NewNonColorable(w),
+		w:      w,
+	}
+}
+
+// nonColorableWriter strips ANSI color sequences from the output while still allowing the underlying writer to be
+// closed when the handler is shut down.
+type nonColorableWriter struct {
+	io.Writer
+
+	// unexported variables
+	w io.Writer
+}
+
+// Close closes the underlying writer if it supports being closed.
+func (w nonColorableWriter) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
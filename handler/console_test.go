@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+// colorFormatter is a colorized formatter which simply prints the message in red.
+type colorFormatter struct{}
+
+func (f colorFormatter) FormatRecord(ctx context.Context, timestamp time.Time, level slogx.Level, pc uintptr,
+	msg string, attrs []slog.Attr) (*slogx.Buffer, error) {
+
+	buf := slogx.NewBuffer()
+	buf.WriteString("\x1b[31m" + msg + "\x1b[0m\n")
+	return buf, nil
+}
+
+func (f colorFormatter) IsColorized() bool {
+	return true
+}
+
+func TestConsoleHandlerStripsColorForNonTerminal(t *testing.T) {
+	tests := []struct {
+		forceColor bool
+		forceTTY   bool
+		expected   string
+	}{
+		{forceColor: false, expected: "message\n"},
+		{forceColor: true, expected: "\x1b[31mmessage\x1b[0m\n"},
+		{forceTTY: true, expected: "\x1b[31mmessage\x1b[0m\n"},
+	}
+	for _, test := range tests {
+		var output bytes.Buffer
+		logger := slog.New(handler.NewConsoleHandler(handler.ConsoleHandlerOptions{
+			ForceColor:      test.forceColor,
+			ForceTTY:        test.forceTTY,
+			RecordFormatter: colorFormatter{},
+			Writer:          &output,
+		}))
+		logger.Info("message")
+		if output.String() != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, output.String())
+		}
+	}
+}
+
+func TestConsoleHandlerForceTTYFile(t *testing.T) {
+	for _, forceTTY := range []bool{false, true} {
+		f, err := os.CreateTemp(t.TempDir(), "console")
+		if err != nil {
+			t.Errorf("failed to create file: %s", err.Error())
+			return
+		}
+		logger := slog.New(handler.NewConsoleHandler(handler.ConsoleHandlerOptions{
+			ForceTTY:        forceTTY,
+			RecordFormatter: colorFormatter{},
+			Writer:          f,
+		}))
+		logger.Info("message")
+		f.Close()
+
+		output, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Errorf("failed to read file: %s", err.Error())
+			return
+		}
+		if colored := bytes.Contains(output, []byte("\x1b[31m")); colored != forceTTY {
+			t.Errorf("ForceTTY=%t: unexpected output %q", forceTTY, output)
+		}
+	}
+}
+
+func TestConsoleHandlerErrorWriter(t *testing.T) {
+	var output, errorOutput bytes.Buffer
+	logger := slogx.Wrap(slog.New(handler.NewConsoleHandler(handler.ConsoleHandlerOptions{
+		ErrorWriter:     &errorOutput,
+		RecordFormatter: colorFormatter{},
+		Writer:          &output,
+	})))
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+	logger.Fatal("fatal")
+	if expected := "info\nwarn\n"; output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+	if expected := "error\nfatal\n"; errorOutput.String() != expected {
+		t.Errorf("expected %q, got %q", expected, errorOutput.String())
+	}
+}
+
+func TestConsoleHandlerClosed(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewConsoleHandler(handler.ConsoleHandlerOptions{
+		RecordFormatter: colorFormatter{},
+		Writer:          &output,
+	})
+	child := h.WithAttrs([]slog.Attr{slog.String("key", "value")})
+	if err := h.Shutdown(false); err != nil {
+		t.Errorf("failed to shut down handler: %s", err.Error())
+		return
+	}
+	for _, sh := range []slog.Handler{h, child} {
+		err := sh.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0))
+		if !errors.Is(err, handler.ErrHandlerClosed) {
+			t.Errorf("expected ErrHandlerClosed, got: %v", err)
+		}
+	}
+	if output.Len() != 0 {
+		t.Errorf("expected no output after shutdown, got: %q", output.String())
+	}
+	if err := h.Shutdown(false); err != nil {
+		t.Errorf("expected repeated shutdown to succeed, got: %s", err.Error())
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// contextAttrsHandler is a handler which adds the attributes stored in the context using slogx.ContextWithAttrs() to
+// every record passed onto the next handler.
+type contextAttrsHandler struct {
+	// unexported variables
+	next slog.Handler
+}
+
+// NewContextAttrsHandler creates a new handler object.
+//
+// The attributes from the context are added to the record ahead of the record's own attributes, so they are nested
+// within any groups added to the handler using WithGroup() just like the record's attributes. When attributes share a
+// key, handlers which remove duplicates keep the last value, which means:
+//   - attributes passed to the logging call override those stored in the context
+//   - attributes stored by a later call to slogx.ContextWithAttrs() override those stored by an earlier call
+//   - attributes stored in the context override those added using With() at the same level
+func NewContextAttrsHandler(next slog.Handler) *contextAttrsHandler {
+	return &contextAttrsHandler{
+		next: next,
+	}
+}
+
+// Enabled returns whether or not the next handler would log this message.
+func (h contextAttrsHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next == nil {
+		return false
+	}
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle adds the attributes stored in the context to the record and sends it onto the next handler.
+func (h *contextAttrsHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.next == nil {
+		return nil
+	}
+	attrs := slogx.AttrsFromContext(ctx)
+	if len(attrs) == 0 {
+		return h.next.Handle(ctx, r)
+	}
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	nr.AddAttrs(attrs...)
+	r.Attrs(func(attr slog.Attr) bool {
+		nr.AddAttrs(attr)
+		return true
+	})
+	return h.next.Handle(ctx, nr)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+func (h contextAttrsHandler) Shutdown(continueOnError bool) error {
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h contextAttrsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next != nil {
+		return &contextAttrsHandler{
+			next: h.next.WithAttrs(attrs),
+		}
+	}
+	return &h
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h contextAttrsHandler) WithGroup(name string) slog.Handler {
+	if h.next != nil {
+		return &contextAttrsHandler{
+			next: h.next.WithGroup(name),
+		}
+	}
+	return &h
+}
@@ -0,0 +1,64 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestContextAttrsHandler(t *testing.T) {
+	var output bytes.Buffer
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.NestAttributes = false
+	logger := slog.New(handler.NewContextAttrsHandler(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: formatter.NewJSONFormatter(opts),
+		Writer:          &output,
+	})))
+
+	ctx := slogx.ContextWithAttrs(context.Background(), slog.String("tenant", "shire"), slog.String("user", "frodo"))
+	nested := slogx.ContextWithAttrs(ctx, slog.String("tenant", "mordor"))
+	sibling := slogx.ContextWithAttrs(ctx, slog.String("request", "abc"))
+
+	tests := []struct {
+		ctx      context.Context
+		args     []any
+		expected string
+	}{
+		{
+			ctx:      nested,
+			expected: `"tenant":"mordor","user":"frodo"}`,
+		},
+		{
+			ctx:      nested,
+			args:     []any{"user", "sam"},
+			expected: `"tenant":"mordor","user":"sam"}`,
+		},
+		{
+			ctx:      sibling,
+			expected: `"request":"abc","tenant":"shire","user":"frodo"}`,
+		},
+		{
+			ctx:      context.Background(),
+			expected: `"@msg":"message"}`,
+		},
+	}
+	for _, test := range tests {
+		output.Reset()
+		logger.InfoContext(test.ctx, "message", test.args...)
+		if !strings.Contains(output.String(), test.expected) {
+			t.Errorf("expected %s in output: %s", test.expected, output.String())
+		}
+	}
+
+	output.Reset()
+	logger.WithGroup("request").InfoContext(ctx, "message", "id", 1)
+	if expected := `"request":{"id":1,"tenant":"shire","user":"frodo"}}`; !strings.Contains(output.String(), expected) {
+		t.Errorf("expected %s in output: %s", expected, output.String())
+	}
+}
@@ -0,0 +1,394 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"log/slog"
+
+	"github.com/go-resty/resty/v2"
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+// elasticsearchIndexLayoutRegex matches the time layouts within an Elasticsearch index name template.
+var elasticsearchIndexLayoutRegex = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// elasticsearchHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type elasticsearchHandlerOptionsContext struct{}
+
+// ElasticsearchHandlerOptions holds the options for the Elasticsearch handler.
+type ElasticsearchHandlerOptions struct {
+	// APIKey is the base64-encoded API key to use to authenticate with Elasticsearch.
+	//
+	// If set, this takes precedence over Username and Password.
+	APIKey string
+
+	// BatchFlushInterval is the maximum amount of time to hold records in a batch before posting them.
+	//
+	// By default, this is set to 5 seconds.
+	BatchFlushInterval time.Duration
+
+	// BatchSize is the maximum number of records to post to Elasticsearch in a single bulk request.
+	//
+	// By default, this is set to 100.
+	BatchSize int
+
+	// ContinueOnError determines whether or not to ignore failures indexing individual records within a bulk request.
+	//
+	// If false, the first failure reported in the bulk response is returned as an error. Either way, the number of
+	// records which failed to be indexed can be retrieved using FailedRecords(). Failures of the bulk request as a
+	// whole are always returned as an error.
+	ContinueOnError bool
+
+	// HTTPClient allows for the use of a custom HTTP client for posting the records to Elasticsearch.
+	//
+	// If nil, a default resty client is used.
+	HTTPClient *resty.Client
+
+	// IgnoreAttrs is a list of regular expressions to use for matching attributes which should not be sent to
+	// Elasticsearch.
+	//
+	// Attributes nested within groups are matched using a single period (.) to separate the group and attribute names
+	// (eg: GROUP.ATTRIBUTE). If a group matches, the entire group is removed. Attributes are removed before the record
+	// is formatted and before their values are resolved, so any slog.LogValuer of a removed attribute is never
+	// evaluated. If any regular expression does not compile, it is simply ignored.
+	IgnoreAttrs []string
+
+	// Index is the name of the index to write records to.
+	//
+	// Any text within curly braces is treated as a time layout and replaced with the UTC time at which the batch is
+	// posted (eg: logs-{2006.01.02}). This is a required option.
+	Index string
+
+	// Level is the minimum log level to write to the handler.
+	//
+	// If this is nil, it defaults to slogx.LevelInfo.
+	Level *slogx.LevelVar
+
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
+	// Password is the password to use to authenticate with Elasticsearch using basic authentication.
+	Password string
+
+	// RecordFormatter specifies the formatter to use to format the record before sending it to Elasticsearch.
+	//
+	// The formatter must produce a single JSON object per record. If no formatter is supplied,
+	// formatter.DefaultJSONFormatter is used to format the output.
+	RecordFormatter formatter.BufferFormatter
+
+	// URL is the base URL of the Elasticsearch cluster (eg: https://localhost:9200).
+	//
+	// This is a required option.
+	URL string
+
+	// Username is the username to use to authenticate with Elasticsearch using basic authentication.
+	//
+	// If empty, basic authentication is not used.
+	Username string
+}
+
+// ContextWithElasticsearchHandlerOptions adds the options to the given context and returns the new context.
+func ContextWithElasticsearchHandlerOptions(ctx context.Context, opts ElasticsearchHandlerOptions) context.Context {
+	return context.WithValue(ctx, elasticsearchHandlerOptionsContext{}, &opts)
+}
+
+// DefaultElasticsearchHandlerOptions returns a default set of options for the handler.
+func DefaultElasticsearchHandlerOptions() ElasticsearchHandlerOptions {
+	return ElasticsearchHandlerOptions{
+		BatchFlushInterval: 5 * time.Second,
+		BatchSize:          100,
+		HTTPClient:         resty.New(),
+		IgnoreAttrs:        []string{},
+		Level:              slogx.NewLevelVar(slogx.LevelInfo),
+		RecordFormatter:    formatter.DefaultJSONFormatter(),
+	}
+}
+
+// ElasticsearchHandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func ElasticsearchHandlerOptionsFromContext(ctx context.Context) *ElasticsearchHandlerOptions {
+	o := ctx.Value(elasticsearchHandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*ElasticsearchHandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultElasticsearchHandlerOptions()
+	return &opts
+}
+
+// elasticsearchBulkResponse holds the parts of an Elasticsearch bulk API response used to detect failures.
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// elasticsearchState holds the batch of records shared between an Elasticsearch handler and any handlers created from
+// it.
+type elasticsearchState struct {
+	done     chan struct{}
+	failed   atomic.Uint64
+	lock     sync.Mutex
+	records  [][]byte
+	shutdown sync.Once
+	wg       sync.WaitGroup
+}
+
+// elasticsearchHandler is a log handler that writes batches of records to Elasticsearch using the bulk API.
+type elasticsearchHandler struct {
+	attrs               []slog.Attr
+	groups              []string
+	ignoredAttrPatterns []*regexp.Regexp
+	options             ElasticsearchHandlerOptions
+	state               *elasticsearchState
+}
+
+// NewElasticsearchHandler creates a new handler object.
+//
+// Batches are posted in a separate goroutine every BatchFlushInterval. You should be sure to call the Shutdown()
+// function or use the slogx.Shutdown() function to ensure any pending records have been written.
+func NewElasticsearchHandler(opts ElasticsearchHandlerOptions) (*elasticsearchHandler, error) {
+	// validate required options
+	if opts.Index == "" {
+		return nil, ErrMissingIndex
+	}
+	if opts.URL == "" {
+		return nil, ErrMissingURL
+	}
+
+	// set default options
+	if opts.BatchFlushInterval <= 0 {
+		opts.BatchFlushInterval = 5 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = resty.New()
+	}
+	if opts.Level == nil {
+		opts.Level = slogx.NewLevelVar(slogx.LevelInfo)
+	}
+
+	// create the handler
+	h := &elasticsearchHandler{
+		attrs:               nameAttrs(opts.Name, opts.NameAttrKey),
+		groups:              []string{},
+		ignoredAttrPatterns: compileAttrPatterns(opts.IgnoreAttrs),
+		options:             opts,
+		state: &elasticsearchState{
+			done:    make(chan struct{}),
+			records: make([][]byte, 0, opts.BatchSize),
+		},
+	}
+	h.state.wg.Add(1)
+	go h.flushPeriodically()
+	return h, nil
+}
+
+// Enabled determines whether or not the given level is enabled in this handler.
+func (h elasticsearchHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slogx.Level(level) >= h.options.Level.Level()
+}
+
+// FailedRecords returns the number of records Elasticsearch has reported as failing to be indexed.
+func (h elasticsearchHandler) FailedRecords() uint64 {
+	return h.state.failed.Load()
+}
+
+// Flush posts any records in the current batch to Elasticsearch.
+func (h elasticsearchHandler) Flush(ctx context.Context) error {
+	h.state.lock.Lock()
+	records := h.state.records
+	h.state.records = make([][]byte, 0, h.options.BatchSize)
+	h.state.lock.Unlock()
+	if len(records) == 0 {
+		return nil
+	}
+	return h.post(ctx, records)
+}
+
+// Handle actually handles adding the record to the batch, posting the batch to Elasticsearch if it is full.
+//
+// Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
+// If a duplicate is encountered, the last value found will be used for the attribute's value.
+func (h *elasticsearchHandler) Handle(ctx context.Context, r slog.Record) error {
+	handlerCtx := slogx.ContextWithGroupStack(ContextWithElasticsearchHandlerOptions(ctx, h.options), h.groups)
+	attrs := consolidateAttrs(h.attrs, h.groups, r, h.ignoredAttrPatterns, h.options.RecordFormatter)
+
+	// format the output into a buffer
+	var buf *slogx.Buffer
+	var err error
+	if h.options.RecordFormatter != nil {
+		buf, err = h.options.RecordFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
+			attrs)
+	} else {
+		f := formatter.DefaultJSONFormatter()
+		buf, err = f.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
+	}
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	// add the record to the batch
+	h.state.lock.Lock()
+	h.state.records = append(h.state.records, bytes.Clone(buf.Bytes()))
+	full := len(h.state.records) >= h.options.BatchSize
+	h.state.lock.Unlock()
+	if full {
+		// the batch holds records from other callers too, so don't let this caller cancel posting it
+		return h.Flush(context.WithoutCancel(ctx))
+	}
+	return nil
+}
+
+// Level returns a pointer to the handler's level for updating.
+func (h elasticsearchHandler) Level() *slogx.LevelVar {
+	return h.options.Level
+}
+
+// Shutdown is responsible for cleaning up resources used by the handler.
+//
+// Any records remaining in the batch are posted to Elasticsearch.
+func (h elasticsearchHandler) Shutdown(continueOnError bool) error {
+	h.state.shutdown.Do(func() {
+		close(h.state.done)
+	})
+	h.state.wg.Wait()
+	return h.Flush(context.Background())
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+func (h elasticsearchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := &elasticsearchHandler{
+		attrs:               h.attrs,
+		groups:              h.groups,
+		ignoredAttrPatterns: h.ignoredAttrPatterns,
+		options:             h.options,
+		state:               h.state,
+	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
+	return newHandler
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+func (h elasticsearchHandler) WithGroup(name string) slog.Handler {
+	newHandler := &elasticsearchHandler{
+		attrs:               h.attrs,
+		groups:              h.groups,
+		ignoredAttrPatterns: h.ignoredAttrPatterns,
+		options:             h.options,
+		state:               h.state,
+	}
+	if name != "" {
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
+	}
+	return newHandler
+}
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h elasticsearchHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*elasticsearchHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
+
+// flushPeriodically posts the batch to Elasticsearch every BatchFlushInterval until the handler is shut down.
+func (h elasticsearchHandler) flushPeriodically() {
+	defer h.state.wg.Done()
+	ticker := time.NewTicker(h.options.BatchFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = h.Flush(context.Background())
+		case <-h.state.done:
+			return
+		}
+	}
+}
+
+// index returns the name of the index to post a batch to at the given time.
+func (h elasticsearchHandler) index(t time.Time) string {
+	return elasticsearchIndexLayoutRegex.ReplaceAllStringFunc(h.options.Index, func(s string) string {
+		return t.UTC().Format(s[1 : len(s)-1])
+	})
+}
+
+// post posts the given records to Elasticsearch as a single bulk request and checks the response for failures.
+func (h elasticsearchHandler) post(ctx context.Context, records [][]byte) error {
+	req := h.options.HTTPClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", BatchEncodingElasticsearchBulk.ContentType()).
+		SetBody(BatchEncodingElasticsearchBulk.encode(records))
+	if h.options.APIKey != "" {
+		req.SetHeader("Authorization", "ApiKey "+h.options.APIKey)
+	} else if h.options.Username != "" {
+		req.SetBasicAuth(h.options.Username, h.options.Password)
+	}
+	url := fmt.Sprintf("%s/%s/_bulk", strings.TrimRight(h.options.URL, "/"), h.index(time.Now()))
+	resp, err := req.Post(url)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 400 {
+		return fmt.Errorf("failed to write records - HTTP status code %d", resp.StatusCode())
+	}
+
+	// check for any records which failed to be indexed
+	var bulkResp elasticsearchBulkResponse
+	if err := json.Unmarshal(resp.Body(), &bulkResp); err != nil {
+		return fmt.Errorf("failed to parse bulk response: %s", err.Error())
+	}
+	if !bulkResp.Errors {
+		return nil
+	}
+	var firstErr error
+	for _, item := range bulkResp.Items {
+		for _, result := range item {
+			if result.Status < 300 && result.Error == nil {
+				continue
+			}
+			h.state.failed.Add(1)
+			if firstErr == nil {
+				if result.Error != nil {
+					firstErr = fmt.Errorf("failed to index record - %s: %s", result.Error.Type, result.Error.Reason)
+				} else {
+					firstErr = fmt.Errorf("failed to index record - HTTP status code %d", result.Status)
+				}
+			}
+		}
+	}
+	if firstErr != nil && !h.options.ContinueOnError {
+		return firstErr
+	}
+	return nil
+}
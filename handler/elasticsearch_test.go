@@ -0,0 +1,122 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestElasticsearchHandlerBulkErrors(t *testing.T) {
+	tests := []struct {
+		continueOnError bool
+		expectError     bool
+	}{
+		{continueOnError: false, expectError: true},
+		{continueOnError: true, expectError: false},
+	}
+	for _, test := range tests {
+		var lock sync.Mutex
+		var paths, bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			lock.Lock()
+			paths = append(paths, r.URL.Path)
+			bodies = append(bodies, string(body))
+			lock.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},` +
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`))
+		}))
+
+		h, err := handler.NewElasticsearchHandler(handler.ElasticsearchHandlerOptions{
+			BatchFlushInterval: time.Hour,
+			BatchSize:          2,
+			ContinueOnError:    test.continueOnError,
+			Index:              "logs-{2006}",
+			URL:                server.URL,
+		})
+		if err != nil {
+			t.Errorf("failed to create Elasticsearch handler: %s", err.Error())
+			server.Close()
+			return
+		}
+		logger := slog.New(h)
+		logger.Info("first message")
+		err = h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "second message", 0))
+		if (err != nil) != test.expectError {
+			t.Errorf("expected error: %t, got: %v", test.expectError, err)
+		}
+		if h.FailedRecords() != 1 {
+			t.Errorf("expected 1 failed record, got %d", h.FailedRecords())
+		}
+		_ = slogx.Wrap(logger).Shutdown(false)
+		server.Close()
+
+		lock.Lock()
+		if len(paths) != 1 {
+			t.Errorf("expected 1 bulk request, got %d", len(paths))
+		} else {
+			expectedPath := "/logs-" + time.Now().UTC().Format("2006") + "/_bulk"
+			if paths[0] != expectedPath {
+				t.Errorf("expected path %s, got %s", expectedPath, paths[0])
+			}
+			lines := strings.Split(strings.TrimSpace(bodies[0]), "\n")
+			if len(lines) != 4 || lines[0] != `{"index":{}}` || !strings.Contains(lines[1], "first message") {
+				t.Errorf("unexpected bulk body: %s", bodies[0])
+			}
+		}
+		lock.Unlock()
+	}
+}
+
+func TestElasticsearchHandlerCancelledContext(t *testing.T) {
+	var lock sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		bodies = append(bodies, string(body))
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	h, err := handler.NewElasticsearchHandler(handler.ElasticsearchHandlerOptions{
+		BatchFlushInterval: time.Hour,
+		BatchSize:          2,
+		Index:              "logs",
+		URL:                server.URL,
+	})
+	if err != nil {
+		t.Errorf("failed to create Elasticsearch handler: %s", err.Error())
+		return
+	}
+	defer h.Shutdown(false)
+
+	// the record filling the batch is logged with a cancelled context, but the whole batch must still be posted
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "first", 0)); err != nil {
+		t.Errorf("failed to handle record: %s", err.Error())
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "second", 0)); err != nil {
+		t.Errorf("expected batch to be posted despite the cancelled context, got: %s", err.Error())
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(bodies) != 1 || !strings.Contains(bodies[0], "first") || !strings.Contains(bodies[0], "second") {
+		t.Errorf("expected both records to be posted in a single request, got: %v", bodies)
+	}
+}
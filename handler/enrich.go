@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// enrichHandler is a handler which adds a fixed set of attributes to every record passed onto the next handler.
+//
+// This is typically used for static information such as the hostname, process ID or build version of the
+// application. Unlike slogx.Logger.With(), the attributes are added at the handler so they are also added to records
+// created elsewhere and logged using slogx.Logger.LogRecord().
+type enrichHandler struct {
+	// unexported variables
+	next slog.Handler
+}
+
+// NewEnrichHandler creates a new handler object.
+//
+// The attributes are added to the next handler once when the handler is created rather than being copied into every
+// record. They are always added at the root level, even if groups are later added to the handler.
+func NewEnrichHandler(next slog.Handler, attrs ...slog.Attr) *enrichHandler {
+	if next != nil && len(attrs) > 0 {
+		next = next.WithAttrs(attrs)
+	}
+	return &enrichHandler{
+		next: next,
+	}
+}
+
+// Enabled returns whether or not the next handler would log this message.
+func (h enrichHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next == nil {
+		return false
+	}
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle sends the record onto the next handler with the attributes added.
+func (h *enrichHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.next == nil {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+func (h enrichHandler) Shutdown(continueOnError bool) error {
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h enrichHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next != nil {
+		return &enrichHandler{
+			next: h.next.WithAttrs(attrs),
+		}
+	}
+	return &h
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h enrichHandler) WithGroup(name string) slog.Handler {
+	if h.next != nil {
+		return &enrichHandler{
+			next: h.next.WithGroup(name),
+		}
+	}
+	return &h
+}
@@ -0,0 +1,46 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestEnrichHandler(t *testing.T) {
+	var output bytes.Buffer
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.NestAttributes = false
+	h := handler.NewEnrichHandler(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: formatter.NewJSONFormatter(opts),
+		Writer:          &output,
+	}), slog.String("hostname", "shire"), slog.Int("pid", 1234))
+	logger := slogx.Wrap(slog.New(h))
+
+	logger.WithGroup("request").Info("grouped message", slog.String("id", "abc"))
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "external record", 0)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Errorf("failed to handle record: %s", err.Error())
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Errorf("expected 2 records, got %d: %s", len(lines), output.String())
+		return
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"hostname":"shire"`) || !strings.Contains(line, `"pid":1234`) {
+			t.Errorf("expected enriched attributes at the root of the record, got: %s", line)
+		}
+	}
+	if !strings.Contains(lines[0], `"request":{"id":"abc"}`) {
+		t.Errorf("expected grouped attributes to be passed through, got: %s", lines[0])
+	}
+}
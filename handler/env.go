@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+const (
+	// EnvFormatVar is the suffix of the environment variable which selects the output format.
+	EnvFormatVar = "FORMAT"
+
+	// EnvLevelVar is the suffix of the environment variable which sets the minimum log level.
+	EnvLevelVar = "LEVEL"
+
+	// EnvFileVar is the suffix of the environment variable which sets the log file to write to.
+	EnvFileVar = "FILE"
+
+	// EnvHTTPURLVar is the suffix of the environment variable which sets the HTTP endpoint to post records to.
+	EnvHTTPURLVar = "HTTP_URL"
+)
+
+// HandlerFromEnv builds a handler from environment variables, each of which is named with the given prefix followed
+// by the variable suffix (eg: a prefix of "LOG_" reads LOG_FORMAT, LOG_LEVEL, etc.).
+//
+// The following variables are recognized:
+//
+//	FORMAT - the format of the output: "console" (colorized), "text" (console without color) or "json"; if empty,
+//	         "console" is used when writing to stdout and "json" is used when writing to a file
+//	LEVEL - the minimum level to log, parsed with slogx.ParseLevel(); if empty, slogx.LevelInfo is used
+//	FILE - the name of a log file to write to; if set, records are written to the file instead of stdout
+//	HTTP_URL - the URL of an HTTP endpoint to post JSON records to; if set, records are posted in addition to being
+//	           written to stdout or the file
+//
+// The level applies to every handler that is created. If more than one handler is created, they are combined using
+// a multi handler.
+func HandlerFromEnv(prefix string) (slog.Handler, error) {
+	format := strings.ToLower(os.Getenv(prefix + EnvFormatVar))
+	filename := os.Getenv(prefix + EnvFileVar)
+	url := os.Getenv(prefix + EnvHTTPURLVar)
+
+	// parse the level
+	level := slogx.LevelInfo
+	if l := os.Getenv(prefix + EnvLevelVar); l != "" {
+		var err error
+		level, err = slogx.ParseLevel(l)
+		if err != nil {
+			return nil, fmt.Errorf("%s%s: %s", prefix, EnvLevelVar, err.Error())
+		}
+	}
+	levelVar := slogx.NewLevelVar(level)
+
+	// create the stdout or file handler
+	handlers := []slog.Handler{}
+	if filename != "" {
+		var f formatter.BufferFormatter
+		switch format {
+		case "", "json":
+			f = formatter.DefaultJSONFormatter()
+		case "console", "text":
+			f = formatter.DefaultConsoleFormatter(false)
+		default:
+			return nil, fmt.Errorf("%s%s: %s: unknown format", prefix, EnvFormatVar, format)
+		}
+		h, err := NewFileHandler(FileHandlerOptions{
+			Filename:        filename,
+			Level:           levelVar,
+			RecordFormatter: f,
+		})
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, h)
+	} else {
+		switch format {
+		case "", "console":
+			handlers = append(handlers, NewConsoleHandler(ConsoleHandlerOptions{
+				Level:           levelVar,
+				RecordFormatter: formatter.DefaultConsoleFormatter(true),
+			}))
+		case "text":
+			handlers = append(handlers, NewConsoleHandler(ConsoleHandlerOptions{
+				Level:           levelVar,
+				RecordFormatter: formatter.DefaultConsoleFormatter(false),
+			}))
+		case "json":
+			handlers = append(handlers, NewJSONHandler(JSONHandlerOptions{
+				Level: levelVar,
+			}))
+		default:
+			return nil, fmt.Errorf("%s%s: %s: unknown format", prefix, EnvFormatVar, format)
+		}
+	}
+
+	// create the HTTP handler
+	if url != "" {
+		h, err := NewHTTPHandler(HTTPHandlerOptions{
+			Level: levelVar,
+			URL:   url,
+		})
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, h)
+	}
+
+	if len(handlers) == 1 {
+		return handlers[0], nil
+	}
+	return NewMultiHandler(DefaultMultiHandlerOptions(), handlers...), nil
+}
@@ -0,0 +1,31 @@
+package handler_test
+
+import (
+	"testing"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestHandlerFromEnv(t *testing.T) {
+	t.Setenv("TEST_LOG_FORMAT", "json")
+	t.Setenv("TEST_LOG_LEVEL", "debug")
+	h, err := handler.HandlerFromEnv("TEST_LOG_")
+	if err != nil {
+		t.Errorf("failed to create handler from environment: %s", err.Error())
+		return
+	}
+	lh, ok := h.(slogx.LevelVarHandler)
+	if !ok {
+		t.Errorf("expected handler to implement slogx.LevelVarHandler")
+		return
+	}
+	if lh.Level().Level() != slogx.LevelDebug {
+		t.Errorf("expected level %s, got %s", slogx.LevelDebug, lh.Level().Level())
+	}
+
+	t.Setenv("TEST_LOG_FORMAT", "bogus")
+	if _, err := handler.HandlerFromEnv("TEST_LOG_"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,35 @@
+package handler
+
+import "errors"
+
+// ErrHandlerClosed is returned when a record is handled after the handler has been shut down.
+var ErrHandlerClosed = errors.New("handler has been shut down")
+
+// ErrDropRecord can be returned by a PipeHandlerFn to drop the record rather than passing it onto the next handler.
+var ErrDropRecord = errors.New("record dropped")
+
+// The following errors are returned by the handler constructors when a required option is missing.
+//
+// Use errors.Is() to check for them.
+var (
+	// ErrMissingAddress is returned when the Address option of the net handler is empty.
+	ErrMissingAddress = errors.New("address is required and cannot be empty")
+
+	// ErrMissingBucket is returned when the Bucket option of the S3 handler is empty.
+	ErrMissingBucket = errors.New("bucket is required and cannot be empty")
+
+	// ErrMissingFilename is returned when the Filename option of the file handler is empty.
+	ErrMissingFilename = errors.New("filename is required and cannot be empty")
+
+	// ErrMissingIndex is returned when the Index option of the Elasticsearch handler is empty.
+	ErrMissingIndex = errors.New("index is required and cannot be empty")
+
+	// ErrMissingJSONWriter is returned when the JSONWriter option of the combined handler is nil.
+	ErrMissingJSONWriter = errors.New("JSON writer is required and cannot be empty")
+
+	// ErrMissingSource is returned when the Source option of the event log handler is empty.
+	ErrMissingSource = errors.New("source is required and cannot be empty")
+
+	// ErrMissingURL is returned when the URL option of the Elasticsearch, HTTP or Loki handler is empty.
+	ErrMissingURL = errors.New("URL is required and cannot be empty")
+)
@@ -0,0 +1,233 @@
+//go:build windows
+
+package handler
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// InstallEventLogSource registers the given source name with the Windows Event Log using EventCreate.exe as the
+// message file so that messages can be written to it by the event log handler.
+//
+// This typically requires administrator privileges and only needs to be done once, usually when installing the
+// application or service.
+func InstallEventLogSource(source string) error {
+	return eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+}
+
+// RemoveEventLogSource removes the given source name from the Windows Event Log.
+func RemoveEventLogSource(source string) error {
+	return eventlog.Remove(source)
+}
+
+// eventLogHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type eventLogHandlerOptionsContext struct{}
+
+// EventLogHandlerOptions holds the options for the Windows Event Log handler.
+type EventLogHandlerOptions struct {
+	// EventID is the ID of the event to use for each message.
+	//
+	// When the source is installed using InstallEventLogSource(), this must be between 1 and 1000. By default, this
+	// is set to 1.
+	EventID uint32
+
+	// Level is the minimum log level to write to the handler.
+	//
+	// If this is nil, it defaults to slogx.LevelInfo.
+	Level *slogx.LevelVar
+
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
+	// RecordFormatter specifies the formatter to use to format the body of the event message.
+	//
+	// If no formatter is supplied, an uncolorized console formatter without a trailing newline is used to format the
+	// output.
+	RecordFormatter formatter.BufferFormatter
+
+	// Source is the name of the event source to write messages as.
+	//
+	// This is a required option. The source should be registered using InstallEventLogSource().
+	Source string
+}
+
+// ContextWithEventLogHandlerOptions adds the options to the given context and returns the new context.
+func ContextWithEventLogHandlerOptions(ctx context.Context, opts EventLogHandlerOptions) context.Context {
+	return context.WithValue(ctx, eventLogHandlerOptionsContext{}, &opts)
+}
+
+// DefaultEventLogHandlerOptions returns a default set of options for the handler.
+func DefaultEventLogHandlerOptions() EventLogHandlerOptions {
+	return EventLogHandlerOptions{
+		EventID:         1,
+		Level:           slogx.NewLevelVar(slogx.LevelInfo),
+		RecordFormatter: defaultEventLogFormatter(),
+	}
+}
+
+// EventLogHandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func EventLogHandlerOptionsFromContext(ctx context.Context) *EventLogHandlerOptions {
+	o := ctx.Value(eventLogHandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*EventLogHandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultEventLogHandlerOptions()
+	return &opts
+}
+
+// defaultEventLogFormatter returns the formatter used when no formatter is supplied in the options.
+func defaultEventLogFormatter() formatter.BufferFormatter {
+	opts := formatter.DefaultConsoleFormatterOptions()
+	opts.OmitTrailingNewline = true
+	return formatter.NewConsoleFormatter(opts)
+}
+
+// eventLogHandler is a log handler that writes records to the Windows Event Log.
+//
+// Records at slogx.LevelError and above are written as error events, records at slogx.LevelWarn and above are written
+// as warning events and all other records are written as informational events.
+type eventLogHandler struct {
+	attrs     []slog.Attr
+	groups    []string
+	log       *eventlog.Log
+	options   EventLogHandlerOptions
+	writeLock *sync.Mutex
+}
+
+// NewEventLogHandler creates a new handler object.
+func NewEventLogHandler(opts EventLogHandlerOptions) (*eventLogHandler, error) {
+	// validate required options
+	if opts.Source == "" {
+		return nil, ErrMissingSource
+	}
+
+	// set default options
+	if opts.EventID == 0 {
+		opts.EventID = 1
+	}
+	if opts.Level == nil {
+		opts.Level = slogx.NewLevelVar(slogx.LevelInfo)
+	}
+
+	// open the event log
+	log, err := eventlog.Open(opts.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	// create the handler
+	return &eventLogHandler{
+		attrs:     nameAttrs(opts.Name, opts.NameAttrKey),
+		groups:    []string{},
+		log:       log,
+		options:   opts,
+		writeLock: &sync.Mutex{},
+	}, nil
+}
+
+// Enabled determines whether or not the given level is enabled in this handler.
+func (h eventLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slogx.Level(level) >= h.options.Level.Level()
+}
+
+// Handle actually handles writing the record to the event log.
+//
+// Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
+// If a duplicate is encountered, the last value found will be used for the attribute's value.
+func (h *eventLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	handlerCtx := slogx.ContextWithGroupStack(ContextWithEventLogHandlerOptions(ctx, h.options), h.groups)
+	attrs := consolidateAttrs(h.attrs, h.groups, r, nil, h.options.RecordFormatter)
+
+	// format the output into a buffer
+	var buf *slogx.Buffer
+	var err error
+	if h.options.RecordFormatter != nil {
+		buf, err = h.options.RecordFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
+			attrs)
+	} else {
+		f := defaultEventLogFormatter()
+		buf, err = f.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
+	}
+	if err != nil {
+		return err
+	}
+
+	// write the message using the event type matching the level
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	switch level := slogx.Level(r.Level); {
+	case level >= slogx.LevelError:
+		return h.log.Error(h.options.EventID, buf.String())
+	case level >= slogx.LevelWarn:
+		return h.log.Warning(h.options.EventID, buf.String())
+	default:
+		return h.log.Info(h.options.EventID, buf.String())
+	}
+}
+
+// Level returns a pointer to the handler's level for updating.
+func (h eventLogHandler) Level() *slogx.LevelVar {
+	return h.options.Level
+}
+
+// Shutdown is responsible for cleaning up resources used by the handler.
+func (h eventLogHandler) Shutdown(continueOnError bool) error {
+	return h.log.Close()
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+func (h eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := &eventLogHandler{
+		attrs:     h.attrs,
+		groups:    h.groups,
+		log:       h.log,
+		options:   h.options,
+		writeLock: h.writeLock,
+	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
+	return newHandler
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+func (h eventLogHandler) WithGroup(name string) slog.Handler {
+	newHandler := &eventLogHandler{
+		attrs:     h.attrs,
+		groups:    h.groups,
+		log:       h.log,
+		options:   h.options,
+		writeLock: h.writeLock,
+	}
+	if name != "" {
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
+	}
+	return newHandler
+}
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h eventLogHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*eventLogHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
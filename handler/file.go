@@ -2,16 +2,20 @@ package handler
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"log/slog"
 
-	"go.innotegrity.dev/generic"
 	"go.innotegrity.dev/slogx"
 	"go.innotegrity.dev/slogx/formatter"
 )
@@ -21,11 +25,50 @@ type fileHandlerOptionsContext struct{}
 
 // FileHandlerOptions holds options for the file handler.
 type FileHandlerOptions struct {
+	// AsyncQueueSize is the maximum number of records which can be queued for writing when async is enabled.
+	//
+	// By default, this is set to 1000.
+	AsyncQueueSize int
+
+	// Clock is the function used to get the current time when deciding whether to rotate the file because
+	// RotationInterval has elapsed.
+	//
+	// This is intended for tests, which can supply a fake clock and advance it to a rotation boundary rather than
+	// sleeping. If nil, time.Now is used.
+	Clock func() time.Time
+
+	// DetectExternalRotation determines whether or not to check if the file was rotated by an external tool before
+	// each record is written.
+	//
+	// When enabled, Filename is checked before each write and the file is reopened if it was moved or removed. If the
+	// file was truncated in place (eg: logrotate's copytruncate), the tracked file size is reset instead. This costs
+	// an extra stat call per record, so it is disabled by default. Alternatively, use Reopen() when the external tool
+	// signals that it has rotated the file.
+	DetectExternalRotation bool
+
 	// DirMode is the mode to use when creating directories.
 	//
 	// By default, directories will be created with mode 0755.
 	DirMode fs.FileMode
 
+	// DropOnFull determines whether or not to drop records when async is enabled and the queue is full.
+	//
+	// By default, Handle() blocks until there is room in the queue. If this is true, records are dropped rather than
+	// blocking the caller and the number of dropped records can be retrieved using DroppedRecords().
+	DropOnFull bool
+
+	// DroppedSummaryInterval is how often to write a summary record to the file with the number of records dropped
+	// since the last summary when async is enabled and DropOnFull is true.
+	//
+	// By default, this is set to 10 seconds. A summary is only written if records were actually dropped.
+	DroppedSummaryInterval time.Duration
+
+	// EnableAsync will write records to the file in a separate goroutine.
+	//
+	// Records are formatted by the caller and queued for writing. When async is enabled, you should be sure to call
+	// the Shutdown() function or use the slogx.Shutdown() function to ensure any queued records have been written.
+	EnableAsync bool
+
 	// Filename is the name of the log file to write to.
 	//
 	// This is a required option.
@@ -36,6 +79,15 @@ type FileHandlerOptions struct {
 	// By default, files will be created with mode 0640.
 	FileMode fs.FileMode
 
+	// IgnoreAttrs is a list of regular expressions to use for matching attributes which should not be written to the
+	// file.
+	//
+	// Attributes nested within groups are matched using a single period (.) to separate the group and attribute names
+	// (eg: GROUP.ATTRIBUTE). If a group matches, the entire group is removed. Attributes are removed before the record
+	// is formatted and before their values are resolved, so any slog.LogValuer of a removed attribute is never
+	// evaluated. If any regular expression does not compile, it is simply ignored.
+	IgnoreAttrs []string
+
 	// Level is the minimum log level to write to the handler.
 	//
 	// If this is nil, it defaults to slogx.LevelInfo.
@@ -53,10 +105,40 @@ type FileHandlerOptions struct {
 	// never be rotated.
 	MaxFileSize int64
 
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
 	// RecordFormatter specifies the formatter to use to format the record before sending it to Slack.
 	//
 	// If no formatter is supplied, formatter.DefaultJSONFormatter is used to format the output.
 	RecordFormatter formatter.BufferFormatter
+
+	// RotationInterval indicates how often the file is rotated regardless of its size.
+	//
+	// Intervals are aligned to the zero time in UTC (eg: 24 hours rotates at midnight UTC and 1 hour at the top of
+	// each hour). The file is rotated when the first record is written at or after an interval boundary, so an empty
+	// file is never rotated. By default, this is 0 and the file is only rotated based on MaxFileSize.
+	RotationInterval time.Duration
+
+	// SyncOnLevel is the minimum level of records which are synced to disk using File.Sync() as soon as they are
+	// written.
+	//
+	// Records are normally left in the operating system's cache to be written to disk later, so they can be lost if
+	// the system crashes. Syncing forces the data to be physically written, which is far slower than a normal write,
+	// so set this to a high level such as slogx.LevelError to make the records which matter for auditing durable
+	// without slowing down every record. When async is enabled, Handle() waits until such a record has been written
+	// and synced rather than returning once it is queued, and the record is never dropped when the queue is full. If
+	// nil, which is the default, records are never synced.
+	SyncOnLevel slog.Leveler
 }
 
 // ContextWithFileHandlerOptions adds the options to the given context and returns the new context.
@@ -67,12 +149,16 @@ func ContextWithFileHandlerOptions(ctx context.Context, opts FileHandlerOptions)
 // DefaultFileHandlerOptions returns a default set of options for the handler.
 func DefaultFileHandlerOptions() FileHandlerOptions {
 	return FileHandlerOptions{
-		DirMode:         0755,
-		FileMode:        0640,
-		Level:           slogx.NewLevelVar(slogx.LevelInfo),
-		MaxFileCount:    5,
-		MaxFileSize:     10000000,
-		RecordFormatter: formatter.DefaultJSONFormatter(),
+		AsyncQueueSize:         1000,
+		Clock:                  time.Now,
+		DirMode:                0755,
+		DroppedSummaryInterval: 10 * time.Second,
+		FileMode:               0640,
+		IgnoreAttrs:            []string{},
+		Level:                  slogx.NewLevelVar(slogx.LevelInfo),
+		MaxFileCount:           5,
+		MaxFileSize:            10000000,
+		RecordFormatter:        formatter.DefaultJSONFormatter(),
 	}
 }
 
@@ -90,31 +176,59 @@ func FileHandlerOptionsFromContext(ctx context.Context) *FileHandlerOptions {
 	return &opts
 }
 
+// fileState holds the open file and async queue shared between a file handler and any handlers created from it.
+type fileState struct {
+	closed          bool
+	currentFileSize int64
+	dropped         atomic.Uint64
+	file            *os.File
+	nextRotation    time.Time
+	queue           chan fileQueueItem
+	queueLock       sync.RWMutex
+	wg              sync.WaitGroup
+	writeLock       sync.Mutex
+}
+
+// fileQueueItem holds a formatted record queued for writing by the async goroutine.
+type fileQueueItem struct {
+	buf *slogx.Buffer
+
+	// done receives the result of writing and syncing the record if the record should be synced.
+	done chan error
+}
+
 // fileHandler is a log handler that writes records to a file.
 type fileHandler struct {
-	activeGroup     string
-	attrs           []slog.Attr
-	currentFileSize *int64
-	file            *os.File
-	groups          []string
-	options         FileHandlerOptions
-	writeLock       *sync.Mutex
+	attrs               []slog.Attr
+	groups              []string
+	ignoredAttrPatterns []*regexp.Regexp
+	options             FileHandlerOptions
+	state               *fileState
 }
 
 // NewFileHandler creates a new handler object.
 func NewFileHandler(opts FileHandlerOptions) (*fileHandler, error) {
 	// validate required options
 	if opts.Filename == "" {
-		return nil, errors.New("filename is required and cannot be empty")
+		return nil, ErrMissingFilename
 	}
 
 	// set default options
+	if opts.AsyncQueueSize <= 0 {
+		opts.AsyncQueueSize = 1000
+	}
 	if opts.DirMode == 0 {
 		opts.DirMode = 0755
 	}
+	if opts.DroppedSummaryInterval <= 0 {
+		opts.DroppedSummaryInterval = 10 * time.Second
+	}
 	if opts.FileMode == 0 {
 		opts.FileMode = 0640
 	}
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
 	if opts.Level == nil {
 		opts.Level = slogx.NewLevelVar(slogx.LevelInfo)
 	}
@@ -126,14 +240,24 @@ func NewFileHandler(opts FileHandlerOptions) (*fileHandler, error) {
 	}
 
 	// create the handler
-	currentFileSize := int64(0)
-	return &fileHandler{
-		attrs:           []slog.Attr{},
-		currentFileSize: &currentFileSize,
-		groups:          []string{},
-		options:         opts,
-		writeLock:       &sync.Mutex{},
-	}, nil
+	h := &fileHandler{
+		attrs:               nameAttrs(opts.Name, opts.NameAttrKey),
+		groups:              []string{},
+		ignoredAttrPatterns: compileAttrPatterns(opts.IgnoreAttrs),
+		options:             opts,
+		state:               &fileState{},
+	}
+	if opts.EnableAsync {
+		h.state.queue = make(chan fileQueueItem, opts.AsyncQueueSize)
+		h.state.wg.Add(1)
+		go h.processQueue()
+	}
+	return h, nil
+}
+
+// DroppedRecords returns the total number of records dropped because the async queue was full.
+func (h fileHandler) DroppedRecords() uint64 {
+	return h.state.dropped.Load()
 }
 
 // Enabled determines whether or not the given level is enabled in this handler.
@@ -146,25 +270,27 @@ func (h fileHandler) Enabled(ctx context.Context, level slog.Level) bool {
 // Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
 // If a duplicate is encountered, the last value found will be used for the attribute's value.
 func (h *fileHandler) Handle(ctx context.Context, r slog.Record) error {
-	handlerCtx := ContextWithFileHandlerOptions(ctx, h.options)
-	attrs := slogx.ConsolidateAttrs(h.attrs, h.activeGroup, r)
+	handlerCtx := slogx.ContextWithGroupStack(ContextWithFileHandlerOptions(ctx, h.options), h.groups)
+	attrs := consolidateAttrs(h.attrs, h.groups, r, h.ignoredAttrPatterns, h.options.RecordFormatter)
 
 	// format the output into a buffer
-	var buf *slogx.Buffer
-	var err error
-	if h.options.RecordFormatter != nil {
-		buf, err = h.options.RecordFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
-			attrs)
-	} else {
-		f := formatter.DefaultJSONFormatter()
-		buf, err = f.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
-	}
+	buf, err := h.format(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
 	if err != nil {
 		return err
 	}
 
-	// write the buffer to the file
-	return h.write(buf)
+	// write the buffer to the file or queue it for writing
+	syncWrite := h.options.SyncOnLevel != nil && r.Level >= h.options.SyncOnLevel.Level()
+	if !h.options.EnableAsync {
+		defer buf.Free()
+		h.state.queueLock.RLock()
+		defer h.state.queueLock.RUnlock()
+		if h.state.closed {
+			return ErrHandlerClosed
+		}
+		return h.write(buf, syncWrite)
+	}
+	return h.enqueue(buf, syncWrite)
 }
 
 // Level returns a pointer to the handler's level for updating.
@@ -172,10 +298,79 @@ func (h fileHandler) Level() *slogx.LevelVar {
 	return h.options.Level
 }
 
+// InstallReopenSignalHandler listens for the given signals and reopens the file using Reopen() each time one is
+// received.
+//
+// If no signals are supplied, syscall.SIGHUP is used, which is what logrotate and similar tools typically send after
+// moving a log file. If the file cannot be reopened, it is opened again when the next record is written. The returned
+// function stops listening for the signals and should be called when the handler is no longer needed.
+func (h fileHandler) InstallReopenSignalHandler(sigs ...os.Signal) func() {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, sigs...)
+	go func() {
+		for {
+			select {
+			case <-signals:
+				_ = h.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// Reopen closes the file and opens it again using the configured filename.
+//
+// This allows the file to be moved by an external tool such as logrotate, after which records are written to a new
+// file with the original name. Records written before the file is reopened go to the moved file. If the file cannot be
+// opened, the error is returned and the file is opened again when the next record is written.
+func (h fileHandler) Reopen() error {
+	h.state.queueLock.RLock()
+	defer h.state.queueLock.RUnlock()
+	if h.state.closed {
+		return ErrHandlerClosed
+	}
+
+	h.state.writeLock.Lock()
+	defer h.state.writeLock.Unlock()
+	if h.state.file != nil {
+		err := h.state.file.Close()
+		h.state.file = nil
+		if err != nil {
+			return err
+		}
+	}
+	return h.openFile()
+}
+
 // Shutdown is responsible for cleaning up resources used by the handler.
+//
+// When async is enabled, any queued records are written to the file before it is closed. Once the handler has been
+// shut down, any further records are rejected with ErrHandlerClosed.
 func (h fileHandler) Shutdown(continueOnError bool) error {
-	if h.file != nil {
-		h.file.Close()
+	h.state.queueLock.Lock()
+	if !h.state.closed {
+		h.state.closed = true
+		if h.options.EnableAsync {
+			close(h.state.queue)
+		}
+	}
+	h.state.queueLock.Unlock()
+	h.state.wg.Wait()
+
+	h.state.writeLock.Lock()
+	defer h.state.writeLock.Unlock()
+	if h.state.file != nil {
+		h.state.file.Close()
+		h.state.file = nil
 	}
 	return nil
 }
@@ -183,39 +378,133 @@ func (h fileHandler) Shutdown(continueOnError bool) error {
 // WithAttrs creates a new handler from the existing one adding the given attributes to it.
 func (h fileHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	newHandler := &fileHandler{
-		attrs:           h.attrs,
-		currentFileSize: h.currentFileSize,
-		file:            h.file,
-		groups:          h.groups,
-		options:         h.options,
-		writeLock:       h.writeLock,
-	}
-	if h.activeGroup == "" {
-		newHandler.attrs = append(newHandler.attrs, attrs...)
-	} else {
-		newHandler.attrs = append(newHandler.attrs, slog.Group(h.activeGroup, generic.AnySlice(attrs)...))
-		newHandler.activeGroup = h.activeGroup
+		attrs:               h.attrs,
+		groups:              h.groups,
+		ignoredAttrPatterns: h.ignoredAttrPatterns,
+		options:             h.options,
+		state:               h.state,
 	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
 	return newHandler
 }
 
 // WithGroup creates a new handler from the existing one adding the given group to it.
 func (h fileHandler) WithGroup(name string) slog.Handler {
 	newHandler := &fileHandler{
-		attrs:           h.attrs,
-		currentFileSize: h.currentFileSize,
-		file:            h.file,
-		groups:          h.groups,
-		options:         h.options,
-		writeLock:       h.writeLock,
+		attrs:               h.attrs,
+		groups:              h.groups,
+		ignoredAttrPatterns: h.ignoredAttrPatterns,
+		options:             h.options,
+		state:               h.state,
 	}
 	if name != "" {
-		newHandler.groups = append(newHandler.groups, name)
-		newHandler.activeGroup = name
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
 	}
 	return newHandler
 }
 
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h fileHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*fileHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
+
+// enqueue queues the buffer for writing by the async goroutine.
+//
+// If syncWrite is true, the function waits until the buffer has been written and synced. Otherwise, if the queue is
+// full and DropOnFull is true, the buffer is dropped instead.
+func (h *fileHandler) enqueue(buf *slogx.Buffer, syncWrite bool) error {
+	item := fileQueueItem{buf: buf}
+	if syncWrite {
+		item.done = make(chan error, 1)
+	}
+	if err := h.pushQueue(item); err != nil {
+		return err
+	}
+	if item.done != nil {
+		return <-item.done
+	}
+	return nil
+}
+
+// pushQueue adds the item to the queue, blocking if the queue is full unless DropOnFull is true and the item does not
+// need to be synced.
+func (h *fileHandler) pushQueue(item fileQueueItem) error {
+	h.state.queueLock.RLock()
+	defer h.state.queueLock.RUnlock()
+	if h.state.closed {
+		item.buf.Free()
+		return ErrHandlerClosed
+	}
+	if !h.options.DropOnFull || item.done != nil {
+		h.state.queue <- item
+		return nil
+	}
+	select {
+	case h.state.queue <- item:
+	default:
+		item.buf.Free()
+		h.state.dropped.Add(1)
+	}
+	return nil
+}
+
+// format formats the record into a buffer using the configured formatter.
+func (h *fileHandler) format(ctx context.Context, t time.Time, level slogx.Level, pc uintptr, msg string,
+	attrs []slog.Attr) (*slogx.Buffer, error) {
+
+	if h.options.RecordFormatter != nil {
+		return h.options.RecordFormatter.FormatRecord(ctx, t, level, pc, msg, attrs)
+	}
+	f := formatter.DefaultJSONFormatter()
+	return f.FormatRecord(ctx, t, level, pc, msg, attrs)
+}
+
+// processQueue writes queued records to the file until the queue is closed, periodically writing a summary of any
+// dropped records.
+func (h *fileHandler) processQueue() {
+	defer h.state.wg.Done()
+	ticker := time.NewTicker(h.options.DroppedSummaryInterval)
+	defer ticker.Stop()
+
+	reported := uint64(0)
+	writeSummary := func() {
+		dropped := h.state.dropped.Load()
+		if dropped == reported {
+			return
+		}
+		ctx := ContextWithFileHandlerOptions(context.Background(), h.options)
+		buf, err := h.format(ctx, time.Now(), slogx.LevelWarn, 0,
+			fmt.Sprintf("dropped %d records", dropped-reported),
+			[]slog.Attr{slog.Uint64("dropped", dropped-reported)})
+		if err != nil {
+			return
+		}
+		_ = h.write(buf, false)
+		buf.Free()
+		reported = dropped
+	}
+
+	for {
+		select {
+		case item, ok := <-h.state.queue:
+			if !ok {
+				writeSummary()
+				return
+			}
+			err := h.write(item.buf, item.done != nil)
+			item.buf.Free()
+			if item.done != nil {
+				item.done <- err
+			}
+		case <-ticker.C:
+			writeSummary()
+		}
+	}
+}
+
 // openFile opens the log file for writing or creates it and any parent folders if they do not exist.
 func (h *fileHandler) openFile() error {
 	// make sure parent folder exists
@@ -244,16 +533,48 @@ func (h *fileHandler) openFile() error {
 	}
 
 	// save the file handle and size
-	*h.currentFileSize = info.Size()
-	h.file = file
+	h.state.currentFileSize = info.Size()
+	h.state.file = file
+	h.state.nextRotation = h.nextRotation()
 	return nil
 }
 
+// nextRotation returns the time of the next interval boundary at which the file should be rotated or the zero time if
+// time-based rotation is disabled.
+func (h *fileHandler) nextRotation() time.Time {
+	if h.options.RotationInterval <= 0 {
+		return time.Time{}
+	}
+	return h.options.Clock().Truncate(h.options.RotationInterval).Add(h.options.RotationInterval)
+}
+
+// reopenIfRotated reopens the file if it was moved or removed by an external tool or resets the tracked file size if
+// it was truncated.
+func (h *fileHandler) reopenIfRotated() error {
+	current, err := h.state.file.Stat()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(h.options.Filename)
+	if err == nil && os.SameFile(current, info) {
+		if info.Size() < h.state.currentFileSize {
+			h.state.currentFileSize = info.Size()
+		}
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	h.state.file.Close()
+	h.state.file = nil
+	return h.openFile()
+}
+
 // rotateFiles rotates the current log file and existing log files and opens a new file for writing.
 func (h *fileHandler) rotateFiles() error {
 	// close existing log file
-	if h.file != nil {
-		h.file.Close()
+	if h.state.file != nil {
+		h.state.file.Close()
 	}
 
 	// rotate previous files
@@ -294,27 +615,40 @@ func (h *fileHandler) rotateFiles() error {
 	return h.openFile()
 }
 
-// write handles writing the buffer contents to the file.
-func (h *fileHandler) write(buf *slogx.Buffer) error {
-	h.writeLock.Lock()
-	defer h.writeLock.Unlock()
+// write handles writing the buffer contents to the file, syncing the file afterwards if syncWrite is true.
+func (h *fileHandler) write(buf *slogx.Buffer, syncWrite bool) error {
+	h.state.writeLock.Lock()
+	defer h.state.writeLock.Unlock()
 
 	// open the file if it's not already open
-	if h.file == nil {
+	if h.state.file == nil {
 		if err := h.openFile(); err != nil {
 			return err
 		}
+	} else if h.options.DetectExternalRotation {
+		if err := h.reopenIfRotated(); err != nil {
+			return err
+		}
 	}
 
-	// rotate logs if message will cause the file to exceed the maximum desired size
-	if (*h.currentFileSize + int64(buf.Len())) > h.options.MaxFileSize {
+	// rotate logs if message will cause the file to exceed the maximum desired size or the rotation interval elapsed
+	if (h.state.currentFileSize + int64(buf.Len())) > h.options.MaxFileSize {
 		if err := h.rotateFiles(); err != nil {
 			return err
 		}
+	} else if !h.state.nextRotation.IsZero() && !h.options.Clock().Before(h.state.nextRotation) {
+		if h.state.currentFileSize == 0 {
+			h.state.nextRotation = h.nextRotation()
+		} else if err := h.rotateFiles(); err != nil {
+			return err
+		}
 	}
 
 	// write message to file
-	bytesWritten, err := h.file.Write(buf.Bytes())
-	*h.currentFileSize += int64(bytesWritten)
-	return err
+	bytesWritten, err := h.state.file.Write(buf.Bytes())
+	h.state.currentFileSize += int64(bytesWritten)
+	if err != nil || !syncWrite {
+		return err
+	}
+	return h.state.file.Sync()
 }
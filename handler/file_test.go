@@ -2,6 +2,9 @@ package handler_test
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -88,3 +91,226 @@ func TestFileHandler(t *testing.T) {
 		),
 	)
 }
+
+func TestFileHandlerIgnoreAttrs(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ignore.log")
+	fileHandler, err := handler.NewFileHandler(handler.FileHandlerOptions{
+		Filename:    filename,
+		IgnoreAttrs: []string{`^password$`, `^request\.headers$`, `\.token$`, `^expensive$`},
+		Level:       slogx.NewLevelVar(slogx.LevelInfo),
+	})
+	if err != nil {
+		t.Errorf("failed to create File Handler: %s", err.Error())
+		return
+	}
+	logger := slog.New(fileHandler)
+	resolved := 0
+	logger.Info("login",
+		slogx.Lazy("expensive", func() any { resolved++; return "value" }),
+		slog.String("user", "frodo"),
+		slog.String("password", "secret"),
+		slog.Group("request",
+			slog.String("method", "POST"),
+			slog.Group("headers", slog.String("cookie", "yum")),
+		),
+		slog.Group("auth", slog.String("token", "abc123")),
+	)
+	if err := fileHandler.Shutdown(true); err != nil {
+		t.Errorf("failed to shut down File Handler: %s", err.Error())
+		return
+	}
+
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("failed to read log file: %s", err.Error())
+		return
+	}
+	output := string(contents)
+	for _, unexpected := range []string{"secret", "headers", "cookie", "auth", "abc123"} {
+		if strings.Contains(output, unexpected) {
+			t.Errorf("expected %q to be ignored: %s", unexpected, output)
+		}
+	}
+	for _, expected := range []string{"frodo", "POST"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output: %s", expected, output)
+		}
+	}
+	if resolved != 0 {
+		t.Errorf("expected ignored attribute not to be resolved, resolved %d times", resolved)
+	}
+}
+
+func TestFileHandlerReopen(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "reopen.log")
+	fileHandler, err := handler.NewFileHandler(handler.FileHandlerOptions{
+		Filename: filename,
+	})
+	if err != nil {
+		t.Errorf("failed to create File Handler: %s", err.Error())
+		return
+	}
+	logger := slog.New(fileHandler)
+	logger.Info("before rotation")
+
+	// simulate logrotate moving the file out of the way
+	if err := os.Rename(filename, filename+".1"); err != nil {
+		t.Errorf("failed to move log file: %s", err.Error())
+		return
+	}
+	if err := fileHandler.Reopen(); err != nil {
+		t.Errorf("failed to reopen log file: %s", err.Error())
+		return
+	}
+	logger.Info("after rotation")
+	if err := fileHandler.Shutdown(true); err != nil {
+		t.Errorf("failed to shut down File Handler: %s", err.Error())
+		return
+	}
+	if err := fileHandler.Reopen(); !errors.Is(err, handler.ErrHandlerClosed) {
+		t.Errorf("expected ErrHandlerClosed after shutdown, got %v", err)
+	}
+
+	rotated, err := os.ReadFile(filename + ".1")
+	if err != nil {
+		t.Errorf("failed to read rotated log file: %s", err.Error())
+		return
+	}
+	current, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("failed to read log file: %s", err.Error())
+		return
+	}
+	if !strings.Contains(string(rotated), "before rotation") || strings.Contains(string(rotated), "after rotation") {
+		t.Errorf("unexpected rotated log file contents: %s", rotated)
+	}
+	if !strings.Contains(string(current), "after rotation") || strings.Contains(string(current), "before rotation") {
+		t.Errorf("unexpected log file contents: %s", current)
+	}
+}
+
+func TestFileHandlerDetectExternalRotation(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "external.log")
+	fileHandler, err := handler.NewFileHandler(handler.FileHandlerOptions{
+		DetectExternalRotation: true,
+		Filename:               filename,
+	})
+	if err != nil {
+		t.Errorf("failed to create File Handler: %s", err.Error())
+		return
+	}
+	logger := slog.New(fileHandler)
+	logger.Info("before move")
+	if err := os.Rename(filename, filename+".1"); err != nil {
+		t.Errorf("failed to move log file: %s", err.Error())
+		return
+	}
+	logger.Info("after move")
+
+	// simulate logrotate's copytruncate
+	if err := os.Truncate(filename, 0); err != nil {
+		t.Errorf("failed to truncate log file: %s", err.Error())
+		return
+	}
+	logger.Info("after truncate")
+	if err := fileHandler.Shutdown(true); err != nil {
+		t.Errorf("failed to shut down File Handler: %s", err.Error())
+		return
+	}
+
+	rotated, err := os.ReadFile(filename + ".1")
+	if err != nil {
+		t.Errorf("failed to read rotated log file: %s", err.Error())
+		return
+	}
+	current, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("failed to read log file: %s", err.Error())
+		return
+	}
+	if !strings.Contains(string(rotated), "before move") || strings.Contains(string(rotated), "after move") {
+		t.Errorf("unexpected rotated log file contents: %s", rotated)
+	}
+	if !strings.HasPrefix(string(current), "{") || !strings.Contains(string(current), "after truncate") ||
+		strings.Contains(string(current), "after move") {
+		t.Errorf("unexpected log file contents: %q", current)
+	}
+}
+
+func TestFileHandlerRotationInterval(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 30, 0, 0, time.UTC)
+	filename := filepath.Join(t.TempDir(), "interval.log")
+	fileHandler, err := handler.NewFileHandler(handler.FileHandlerOptions{
+		Clock:            func() time.Time { return now },
+		Filename:         filename,
+		RotationInterval: time.Hour,
+	})
+	if err != nil {
+		t.Errorf("failed to create File Handler: %s", err.Error())
+		return
+	}
+	logger := slog.New(fileHandler)
+	logger.Info("first record")
+	now = now.Add(29 * time.Minute)
+	logger.Info("second record")
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filename), "interval_1.log")); !os.IsNotExist(err) {
+		t.Errorf("expected log file not to be rotated before the interval boundary")
+		return
+	}
+	now = now.Add(time.Minute)
+	logger.Info("third record")
+	if err := fileHandler.Shutdown(true); err != nil {
+		t.Errorf("failed to shut down File Handler: %s", err.Error())
+		return
+	}
+
+	rotated, err := os.ReadFile(filepath.Join(filepath.Dir(filename), "interval_1.log"))
+	if err != nil {
+		t.Errorf("failed to read rotated log file: %s", err.Error())
+		return
+	}
+	current, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("failed to read log file: %s", err.Error())
+		return
+	}
+	if !strings.Contains(string(rotated), "second record") || strings.Contains(string(rotated), "third record") {
+		t.Errorf("unexpected rotated log file contents: %s", rotated)
+	}
+	if !strings.Contains(string(current), "third record") || strings.Contains(string(current), "second record") {
+		t.Errorf("unexpected log file contents: %s", current)
+	}
+}
+
+func TestFileHandlerMissingFilename(t *testing.T) {
+	if _, err := handler.NewFileHandler(handler.FileHandlerOptions{}); !errors.Is(err, handler.ErrMissingFilename) {
+		t.Errorf("expected ErrMissingFilename, got: %v", err)
+	}
+}
+
+func TestFileHandlerSyncOnLevel(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sync.log")
+	fileHandler, err := handler.NewFileHandler(handler.FileHandlerOptions{
+		EnableAsync: true,
+		Filename:    filename,
+		SyncOnLevel: slogx.LevelError,
+	})
+	if err != nil {
+		t.Errorf("failed to create File Handler: %s", err.Error())
+		return
+	}
+	defer fileHandler.Shutdown(true)
+
+	// the error record is written and synced before Error() returns, along with everything queued before it
+	logger := slog.New(fileHandler)
+	logger.Info("queued record")
+	logger.Error("synced record")
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("failed to read log file: %s", err.Error())
+		return
+	}
+	if !strings.Contains(string(contents), "queued record") || !strings.Contains(string(contents), "synced record") {
+		t.Errorf("unexpected log file contents: %s", contents)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build !windows
+
+package handler_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestFileHandlerAsyncDropOnFull(t *testing.T) {
+	// opening a FIFO for writing blocks until it is opened for reading, which blocks the async goroutine so that the
+	// queue fills up
+	filename := filepath.Join(t.TempDir(), "async.log")
+	if err := syscall.Mkfifo(filename, 0600); err != nil {
+		t.Skipf("failed to create FIFO: %s", err.Error())
+	}
+	fileHandler, err := handler.NewFileHandler(handler.FileHandlerOptions{
+		AsyncQueueSize: 1,
+		DropOnFull:     true,
+		EnableAsync:    true,
+		Filename:       filename,
+	})
+	if err != nil {
+		t.Errorf("failed to create File Handler: %s", err.Error())
+		return
+	}
+	logger := slog.New(fileHandler)
+	total := 10
+	for i := 0; i < total; i++ {
+		logger.Info("queued message")
+	}
+
+	// at most one record is held by the blocked goroutine and one by the queue
+	dropped := int(fileHandler.DroppedRecords())
+	if dropped < total-2 {
+		t.Errorf("expected at least %d records to be dropped, got %d", total-2, dropped)
+		return
+	}
+
+	// unblock the goroutine and read everything written until the handler closes the file
+	contents := make(chan []byte)
+	go func() {
+		f, err := os.Open(filename)
+		if err != nil {
+			contents <- nil
+			return
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		contents <- b
+	}()
+	if err := fileHandler.Shutdown(true); err != nil {
+		t.Errorf("failed to shut down File Handler: %s", err.Error())
+		return
+	}
+	logger.Info("message after shutdown")
+	output := string(<-contents)
+
+	if written := strings.Count(output, "queued message"); written != total-dropped {
+		t.Errorf("expected %d records to be written, got %d: %s", total-dropped, written, output)
+	}
+	summary := fmt.Sprintf(`"@msg":"dropped %d records","@attributes":{"dropped":%d}}`, dropped, dropped)
+	if strings.Count(output, `"@msg":"dropped`) != 1 || !strings.Contains(output, summary) {
+		t.Errorf("expected a single dropped records summary %s in output: %s", summary, output)
+	}
+	if strings.Contains(output, "after shutdown") {
+		t.Errorf("expected records after shutdown to be ignored")
+	}
+}
@@ -1,23 +1,99 @@
 package handler
 
 import (
+	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"go.innotegrity.dev/async"
-	"go.innotegrity.dev/generic"
 	"go.innotegrity.dev/slogx"
 	"go.innotegrity.dev/slogx/formatter"
 )
 
+// BatchEncoding determines how a batch of records is assembled into the body of a single request.
+type BatchEncoding int
+
+const (
+	// BatchEncodingNDJSON writes each record on its own line (newline-delimited JSON).
+	BatchEncodingNDJSON BatchEncoding = iota
+
+	// BatchEncodingArray writes the records as the elements of a JSON array.
+	BatchEncodingArray
+
+	// BatchEncodingElasticsearchBulk writes each record on its own line preceded by an index action line as expected
+	// by the Elasticsearch _bulk API.
+	BatchEncodingElasticsearchBulk
+)
+
+// ContentType returns the mime type of a request body assembled using the encoding.
+func (e BatchEncoding) ContentType() string {
+	if e == BatchEncodingArray {
+		return "application/json"
+	}
+	return "application/x-ndjson"
+}
+
+// encode assembles the given formatted records into a single request body using the encoding.
+//
+// Any trailing newline is removed from each record before it is added to the body.
+func (e BatchEncoding) encode(records [][]byte) []byte {
+	var body bytes.Buffer
+	if e == BatchEncodingArray {
+		body.WriteByte('[')
+	}
+	for i, record := range records {
+		record = bytes.TrimRight(record, "\r\n")
+		switch e {
+		case BatchEncodingArray:
+			if i > 0 {
+				body.WriteByte(',')
+			}
+			body.Write(record)
+		case BatchEncodingElasticsearchBulk:
+			body.WriteString(`{"index":{}}`)
+			body.WriteByte('\n')
+			body.Write(record)
+			body.WriteByte('\n')
+		default:
+			body.Write(record)
+			body.WriteByte('\n')
+		}
+	}
+	if e == BatchEncodingArray {
+		body.WriteByte(']')
+	}
+	return body.Bytes()
+}
+
 // httpHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
 type httpHandlerOptionsContext struct{}
 
 // HTTPHandlerOptions holds the options for the HTTP handler.
 type HTTPHandlerOptions struct {
+	// BatchEncoding determines how a batch of records is assembled into the body of a single request.
+	//
+	// The Content-Type of the request is set to match the encoding rather than using ContentType. This only applies
+	// if BatchSize is greater than 1. By default, this is set to BatchEncodingNDJSON.
+	BatchEncoding BatchEncoding
+
+	// BatchFlushInterval is the maximum amount of time to hold records in a batch before posting them.
+	//
+	// This only applies if BatchSize is greater than 1. By default, this is set to 5 seconds.
+	BatchFlushInterval time.Duration
+
+	// BatchSize is the maximum number of records to post to the HTTP endpoint in a single request.
+	//
+	// Records are held until the batch is full or BatchFlushInterval has passed. When batching is enabled, you should
+	// be sure to call the Shutdown() function or use the slogx.Shutdown() function to ensure any pending records have
+	// been written. If this is 1 or less, each record is posted individually.
+	BatchSize int
+
 	// ContentType is the mime type to pass to the HTTP endpoint.
 	//
 	// By default, this is set to application/json as it is assumed the message being sent will be in JSON format.
@@ -34,11 +110,32 @@ type HTTPHandlerOptions struct {
 	// If nil, a default resty client is used.
 	HTTPClient *resty.Client
 
+	// IgnoreAttrs is a list of regular expressions to use for matching attributes which should not be sent to the HTTP
+	// listener.
+	//
+	// Attributes nested within groups are matched using a single period (.) to separate the group and attribute names
+	// (eg: GROUP.ATTRIBUTE). If a group matches, the entire group is removed. Attributes are removed before the record
+	// is formatted and before their values are resolved, so any slog.LogValuer of a removed attribute is never
+	// evaluated. If any regular expression does not compile, it is simply ignored.
+	IgnoreAttrs []string
+
 	// Level is the minimum log level to write to the handler.
 	//
 	// If this is nil, it defaults to slogx.LevelInfo.
 	Level *slogx.LevelVar
 
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
 	// RecordFormatter specifies the formatter to use to format the record before sending it to the HTTP listener.
 	//
 	// If no formatter is supplied, formatter.DefaultJSONFormatter is used to format the output.
@@ -53,10 +150,12 @@ type HTTPHandlerOptions struct {
 // DefaultHTTPHandlerOptions returns a default set of options for the handler.
 func DefaultHTTPHandlerOptions() HTTPHandlerOptions {
 	return HTTPHandlerOptions{
-		ContentType:     "application/json",
-		HTTPClient:      resty.New(),
-		Level:           slogx.NewLevelVar(slogx.LevelInfo),
-		RecordFormatter: formatter.DefaultJSONFormatter(),
+		BatchFlushInterval: 5 * time.Second,
+		ContentType:        "application/json",
+		HTTPClient:         resty.New(),
+		IgnoreAttrs:        []string{},
+		Level:              slogx.NewLevelVar(slogx.LevelInfo),
+		RecordFormatter:    formatter.DefaultJSONFormatter(),
 	}
 }
 
@@ -79,23 +178,36 @@ func (o *HTTPHandlerOptions) AddToContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, httpHandlerOptionsContext{}, o)
 }
 
+// httpBatch holds the batch of records shared between an HTTP handler and any handlers created from it.
+type httpBatch struct {
+	done     chan struct{}
+	lock     sync.Mutex
+	records  [][]byte
+	shutdown sync.Once
+	wg       sync.WaitGroup
+}
+
 // httpHandler is a log handler that writes records to an HTTP endpoint.
 type httpHandler struct {
-	activeGroup string
-	attrs       []slog.Attr
-	futures     []async.Future
-	groups      []string
-	options     HTTPHandlerOptions
+	attrs               []slog.Attr
+	batch               *httpBatch
+	futures             []async.Future
+	groups              []string
+	ignoredAttrPatterns []*regexp.Regexp
+	options             HTTPHandlerOptions
 }
 
 // NewHTTPHandler creates a new handler object.
 func NewHTTPHandler(opts HTTPHandlerOptions) (*httpHandler, error) {
 	// validate required options
 	if opts.URL == "" {
-		return nil, errors.New("URL is required and cannot be empty")
+		return nil, ErrMissingURL
 	}
 
 	// set default options
+	if opts.BatchFlushInterval <= 0 {
+		opts.BatchFlushInterval = 5 * time.Second
+	}
 	if opts.ContentType == "" {
 		opts.ContentType = "application/json"
 	}
@@ -107,12 +219,22 @@ func NewHTTPHandler(opts HTTPHandlerOptions) (*httpHandler, error) {
 	}
 
 	// create the handler
-	return &httpHandler{
-		attrs:   []slog.Attr{},
-		futures: []async.Future{},
-		groups:  []string{},
-		options: opts,
-	}, nil
+	h := &httpHandler{
+		attrs:               nameAttrs(opts.Name, opts.NameAttrKey),
+		futures:             []async.Future{},
+		groups:              []string{},
+		ignoredAttrPatterns: compileAttrPatterns(opts.IgnoreAttrs),
+		options:             opts,
+	}
+	if opts.BatchSize > 1 {
+		h.batch = &httpBatch{
+			done:    make(chan struct{}),
+			records: make([][]byte, 0, opts.BatchSize),
+		}
+		h.batch.wg.Add(1)
+		go h.flushPeriodically()
+	}
+	return h, nil
 }
 
 // Enabled determines whether or not the given level is enabled in this handler.
@@ -122,16 +244,25 @@ func (h httpHandler) Enabled(ctx context.Context, level slog.Level) bool {
 
 // Handle actually handles posting the record to the HTTP listener.
 //
+// When async is disabled, the context is passed on to the HTTP request so that posting the record is aborted if the
+// context is cancelled. When async is enabled, cancelling the context does not abort posting the record. Cancelling
+// the context never aborts posting a batch since it also holds records logged by other callers.
+//
 // Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
 // If a duplicate is encountered, the last value found will be used for the attribute's value.
 func (h *httpHandler) Handle(ctx context.Context, r slog.Record) error {
-	handlerCtx := h.options.AddToContext(ctx)
+	handlerCtx := slogx.ContextWithGroupStack(h.options.AddToContext(ctx), h.groups)
+	if h.batch != nil {
+		return h.addToBatch(handlerCtx, r)
+	}
 	if !h.options.EnableAsync {
 		return h.handle(handlerCtx, r)
 	}
 
+	// the record is posted after Handle returns, so cancelling the context must not abort the post
+	asyncCtx := context.WithoutCancel(handlerCtx)
 	future := async.Exec(func() any {
-		return h.handle(handlerCtx, r)
+		return h.handle(asyncCtx, r)
 	})
 	h.futures = append(h.futures, future)
 	return nil
@@ -143,69 +274,144 @@ func (h httpHandler) Level() *slogx.LevelVar {
 }
 
 // Shutdown is responsible for cleaning up resources used by the handler.
+//
+// If batching is enabled, any records remaining in the batch are posted to the HTTP listener.
 func (h httpHandler) Shutdown(continueOnError bool) error {
+	var err error
+	if h.batch != nil {
+		h.batch.shutdown.Do(func() {
+			close(h.batch.done)
+		})
+		h.batch.wg.Wait()
+		err = h.flush(context.Background())
+	}
 	for _, f := range h.futures {
 		if f != nil {
 			f.Await()
 		}
 	}
-	return nil
+	return err
 }
 
 // WithAttrs creates a new handler from the existing one adding the given attributes to it.
 func (h httpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	newHandler := &httpHandler{
-		attrs:   h.attrs,
-		futures: h.futures,
-		groups:  h.groups,
-		options: h.options,
-	}
-	if h.activeGroup == "" {
-		newHandler.attrs = append(newHandler.attrs, attrs...)
-	} else {
-		newHandler.attrs = append(newHandler.attrs, slog.Group(h.activeGroup, generic.AnySlice(attrs)...))
-		newHandler.activeGroup = h.activeGroup
+		attrs:               h.attrs,
+		batch:               h.batch,
+		futures:             h.futures,
+		groups:              h.groups,
+		ignoredAttrPatterns: h.ignoredAttrPatterns,
+		options:             h.options,
 	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
 	return newHandler
 }
 
 // WithGroup creates a new handler from the existing one adding the given group to it.
 func (h httpHandler) WithGroup(name string) slog.Handler {
 	newHandler := &httpHandler{
-		attrs:   h.attrs,
-		futures: h.futures,
-		groups:  h.groups,
-		options: h.options,
+		attrs:               h.attrs,
+		batch:               h.batch,
+		futures:             h.futures,
+		groups:              h.groups,
+		ignoredAttrPatterns: h.ignoredAttrPatterns,
+		options:             h.options,
 	}
 	if name != "" {
-		newHandler.groups = append(newHandler.groups, name)
-		newHandler.activeGroup = name
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
 	}
 	return newHandler
 }
 
-// handle is responsible for actually posting the message to the HTTP listener.
-func (h httpHandler) handle(ctx context.Context, r slog.Record) error {
-	attrs := slogx.ConsolidateAttrs(h.attrs, h.activeGroup, r)
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h httpHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*httpHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
 
-	// format the output into a buffer
-	var buf *slogx.Buffer
-	var err error
+// addToBatch formats the record and adds it to the batch, posting the batch to the HTTP listener if it is full.
+//
+// When async is enabled, a full batch is posted in a separate goroutine.
+func (h *httpHandler) addToBatch(ctx context.Context, r slog.Record) error {
+	buf, err := h.format(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	h.batch.lock.Lock()
+	h.batch.records = append(h.batch.records, bytes.Clone(buf.Bytes()))
+	full := len(h.batch.records) >= h.options.BatchSize
+	h.batch.lock.Unlock()
+	if !full {
+		return nil
+	}
+	// the batch holds records from other callers too, so don't let this caller cancel posting it
+	flushCtx := context.WithoutCancel(ctx)
+	if !h.options.EnableAsync {
+		return h.flush(flushCtx)
+	}
+	future := async.Exec(func() any {
+		return h.flush(flushCtx)
+	})
+	h.futures = append(h.futures, future)
+	return nil
+}
+
+// flush posts any records in the batch to the HTTP listener.
+func (h httpHandler) flush(ctx context.Context) error {
+	h.batch.lock.Lock()
+	records := h.batch.records
+	h.batch.records = make([][]byte, 0, h.options.BatchSize)
+	h.batch.lock.Unlock()
+	if len(records) == 0 {
+		return nil
+	}
+	return h.post(ctx, h.options.BatchEncoding.ContentType(), h.options.BatchEncoding.encode(records))
+}
+
+// flushPeriodically posts the batch to the HTTP listener every BatchFlushInterval until the handler is shut down.
+func (h httpHandler) flushPeriodically() {
+	defer h.batch.wg.Done()
+	ticker := time.NewTicker(h.options.BatchFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = h.flush(context.Background())
+		case <-h.batch.done:
+			return
+		}
+	}
+}
+
+// format formats the record into a buffer for posting to the HTTP listener.
+func (h httpHandler) format(ctx context.Context, r slog.Record) (*slogx.Buffer, error) {
+	attrs := consolidateAttrs(h.attrs, h.groups, r, h.ignoredAttrPatterns, h.options.RecordFormatter)
 	if h.options.RecordFormatter != nil {
-		buf, err = h.options.RecordFormatter.FormatRecord(ctx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
-			attrs)
-	} else {
-		f := formatter.DefaultJSONFormatter()
-		buf, err = f.FormatRecord(ctx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
+		return h.options.RecordFormatter.FormatRecord(ctx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
 	}
+	f := formatter.DefaultJSONFormatter()
+	return f.FormatRecord(ctx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
+}
+
+// handle is responsible for actually posting the message to the HTTP listener.
+func (h httpHandler) handle(ctx context.Context, r slog.Record) error {
+	buf, err := h.format(ctx, r)
 	if err != nil {
 		return err
 	}
+	return h.post(ctx, h.options.ContentType, buf.String())
+}
 
-	// post the message to the HTTP listener
+// post posts the given body to the HTTP listener.
+func (h httpHandler) post(ctx context.Context, contentType string, body any) error {
 	resp, err := h.options.HTTPClient.R().
-		SetHeader("Content-Type", h.options.ContentType).
-		SetBody(buf.String()).
+		SetContext(ctx).
+		SetHeader("Content-Type", contentType).
+		SetBody(body).
 		Post(h.options.URL)
 	if err != nil {
 		return err
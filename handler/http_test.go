@@ -3,8 +3,13 @@ package handler_test
 // TODO: implement testing and benchmarks
 
 import (
+	"context"
 	"errors"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -90,3 +95,163 @@ func TestHTTPHandler(t *testing.T) {
 	)
 
 }
+
+func TestHTTPHandlerCancelledContext(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	h, err := handler.NewHTTPHandler(handler.HTTPHandlerOptions{
+		URL: server.URL,
+	})
+	if err != nil {
+		t.Errorf("failed to create HTTP Handler: %s", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	err = h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "this is a message", 0))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context cancelled error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected handler to return promptly, took %s", elapsed)
+	}
+}
+
+func TestHTTPHandlerBatchCancelledContext(t *testing.T) {
+	var lock sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	h, err := handler.NewHTTPHandler(handler.HTTPHandlerOptions{
+		BatchFlushInterval: time.Hour,
+		BatchSize:          2,
+		RecordFormatter:    messageOnlyFormatter{},
+		URL:                server.URL,
+	})
+	if err != nil {
+		t.Errorf("failed to create HTTP Handler: %s", err.Error())
+		return
+	}
+	defer h.Shutdown(false)
+
+	// the record filling the batch is logged with a cancelled context, but the whole batch must still be posted
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "first", 0)); err != nil {
+		t.Errorf("failed to handle record: %s", err.Error())
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "second", 0)); err != nil {
+		t.Errorf("expected batch to be posted despite the cancelled context, got: %s", err.Error())
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	expected := `{"@msg":"first"}` + "\n" + `{"@msg":"second"}` + "\n"
+	if len(bodies) != 1 || bodies[0] != expected {
+		t.Errorf("expected both records to be posted in a single request, got: %q", bodies)
+	}
+}
+
+// messageOnlyFormatter is a formatter which simply writes the message as a JSON object.
+type messageOnlyFormatter struct{}
+
+func (f messageOnlyFormatter) FormatRecord(ctx context.Context, timestamp time.Time, level slogx.Level, pc uintptr,
+	msg string, attrs []slog.Attr) (*slogx.Buffer, error) {
+
+	buf := slogx.NewBuffer()
+	buf.WriteString(`{"@msg":"` + msg + `"}` + "\n")
+	return buf, nil
+}
+
+func TestHTTPHandlerBatchEncoding(t *testing.T) {
+	tests := []struct {
+		encoding    handler.BatchEncoding
+		contentType string
+		expected    string
+	}{
+		{
+			encoding:    handler.BatchEncodingNDJSON,
+			contentType: "application/x-ndjson",
+			expected:    `{"@msg":"first"}` + "\n" + `{"@msg":"second"}` + "\n",
+		},
+		{
+			encoding:    handler.BatchEncodingArray,
+			contentType: "application/json",
+			expected:    `[{"@msg":"first"},{"@msg":"second"}]`,
+		},
+		{
+			encoding:    handler.BatchEncodingElasticsearchBulk,
+			contentType: "application/x-ndjson",
+			expected: `{"index":{}}` + "\n" + `{"@msg":"first"}` + "\n" + `{"index":{}}` + "\n" +
+				`{"@msg":"second"}` + "\n",
+		},
+	}
+	for _, test := range tests {
+		var lock sync.Mutex
+		var contentTypes, bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			lock.Lock()
+			defer lock.Unlock()
+			contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+			bodies = append(bodies, string(body))
+		}))
+
+		h, err := handler.NewHTTPHandler(handler.HTTPHandlerOptions{
+			BatchEncoding:      test.encoding,
+			BatchFlushInterval: time.Hour,
+			BatchSize:          2,
+			RecordFormatter:    messageOnlyFormatter{},
+			URL:                server.URL,
+		})
+		if err != nil {
+			t.Errorf("failed to create HTTP Handler: %s", err.Error())
+			server.Close()
+			return
+		}
+		logger := slogx.Wrap(slog.New(h))
+		logger.Info("first")
+		logger.Info("second")
+		logger.Info("third")
+		if err := logger.Shutdown(false); err != nil {
+			t.Errorf("failed to shut down handler: %s", err.Error())
+		}
+		server.Close()
+
+		lock.Lock()
+		if len(bodies) != 2 {
+			t.Errorf("expected 2 requests, got %d", len(bodies))
+		} else {
+			if contentTypes[0] != test.contentType {
+				t.Errorf("expected content type %s, got %s", test.contentType, contentTypes[0])
+			}
+			if bodies[0] != test.expected {
+				t.Errorf("expected body %q, got %q", test.expected, bodies[0])
+			}
+		}
+		lock.Unlock()
+	}
+}
+
+func TestHTTPHandlerMissingURL(t *testing.T) {
+	if _, err := handler.NewHTTPHandler(handler.HTTPHandlerOptions{}); !errors.Is(err, handler.ErrMissingURL) {
+		t.Errorf("expected ErrMissingURL, got: %v", err)
+	}
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"io"
 	"os"
+	"slices"
 	"sync"
 
 	"log/slog"
 
-	"go.innotegrity.dev/generic"
 	"go.innotegrity.dev/slogx"
 	"go.innotegrity.dev/slogx/formatter"
 )
@@ -23,6 +23,18 @@ type JSONHandlerOptions struct {
 	// If this is nil, it defaults to slogx.LevelInfo.
 	Level *slogx.LevelVar
 
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
 	// RecordFormatter specifies the formatter to use to format the record before writing it to the writer.
 	//
 	// If no formatter is supplied, formatter.DefaultJSONFormatter is used to format the output.
@@ -64,11 +76,11 @@ func (o *JSONHandlerOptions) AddToContext(ctx context.Context) context.Context {
 
 // jsonHandler is a log handler that writes records to an io.Writer using standard JSON formatting.
 type jsonHandler struct {
-	activeGroup string
-	attrs       []slog.Attr
-	groups      []string
-	options     JSONHandlerOptions
-	writeLock   *sync.Mutex
+	attrs     []slog.Attr
+	closed    *bool
+	groups    []string
+	options   JSONHandlerOptions
+	writeLock *sync.Mutex
 }
 
 // NewJSONHandler creates a new handler object.
@@ -83,7 +95,8 @@ func NewJSONHandler(opts JSONHandlerOptions) *jsonHandler {
 
 	// create the handler
 	return &jsonHandler{
-		attrs:     []slog.Attr{},
+		attrs:     nameAttrs(opts.Name, opts.NameAttrKey),
+		closed:    new(bool),
 		groups:    []string{},
 		options:   opts,
 		writeLock: &sync.Mutex{},
@@ -100,17 +113,19 @@ func (h jsonHandler) Enabled(ctx context.Context, level slog.Level) bool {
 // Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
 // If a duplicate is encountered, the last value found will be used for the attribute's value.
 func (h *jsonHandler) Handle(ctx context.Context, r slog.Record) error {
-	handlerCtx := h.options.AddToContext(ctx)
-	attrs := slogx.ConsolidateAttrs(h.attrs, h.activeGroup, r)
+	handlerCtx := slogx.ContextWithGroupStack(h.options.AddToContext(ctx), h.groups)
 
-	// format the output into a buffer
+	// format the output into a buffer, letting the formatter combine the attributes itself if it can
+	var f formatter.BufferFormatter = h.options.RecordFormatter
+	if f == nil {
+		f = formatter.DefaultJSONFormatter()
+	}
 	var buf *slogx.Buffer
 	var err error
-	if h.options.RecordFormatter != nil {
-		buf, err = h.options.RecordFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
-			attrs)
+	if rf, ok := f.(formatter.RecordAttrsFormatter); ok {
+		buf, err = rf.FormatRecordAttrs(handlerCtx, r, h.attrs, h.groups)
 	} else {
-		f := formatter.DefaultJSONFormatter()
+		attrs := consolidateAttrs(h.attrs, h.groups, r, nil, f)
 		buf, err = f.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
 	}
 	if err != nil {
@@ -120,6 +135,9 @@ func (h *jsonHandler) Handle(ctx context.Context, r slog.Record) error {
 	// write the buffer to the output
 	h.writeLock.Lock()
 	defer h.writeLock.Unlock()
+	if *h.closed {
+		return ErrHandlerClosed
+	}
 	_, err = h.options.Writer.Write(buf.Bytes())
 	return err
 }
@@ -130,7 +148,15 @@ func (h jsonHandler) Level() *slogx.LevelVar {
 }
 
 // Shutdown is responsible for cleaning up resources used by the handler.
+//
+// Once the handler has been shut down, any further records are rejected with ErrHandlerClosed.
 func (h jsonHandler) Shutdown(continueOnError bool) error {
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	if *h.closed {
+		return nil
+	}
+	*h.closed = true
 	if w, ok := h.options.Writer.(io.WriteCloser); ok {
 		if err := w.Close(); err != nil {
 			return err
@@ -143,16 +169,12 @@ func (h jsonHandler) Shutdown(continueOnError bool) error {
 func (h jsonHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	newHandler := &jsonHandler{
 		attrs:     h.attrs,
+		closed:    h.closed,
 		groups:    h.groups,
 		options:   h.options,
 		writeLock: h.writeLock,
 	}
-	if h.activeGroup == "" {
-		newHandler.attrs = append(newHandler.attrs, attrs...)
-	} else {
-		newHandler.attrs = append(newHandler.attrs, slog.Group(h.activeGroup, generic.AnySlice(attrs)...))
-		newHandler.activeGroup = h.activeGroup
-	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
 	return newHandler
 }
 
@@ -160,13 +182,21 @@ func (h jsonHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 func (h *jsonHandler) WithGroup(name string) slog.Handler {
 	newHandler := &jsonHandler{
 		attrs:     h.attrs,
+		closed:    h.closed,
 		groups:    h.groups,
 		options:   h.options,
 		writeLock: h.writeLock,
 	}
 	if name != "" {
-		newHandler.groups = append(newHandler.groups, name)
-		newHandler.activeGroup = name
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
 	}
 	return newHandler
 }
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h jsonHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*jsonHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
@@ -0,0 +1,90 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+// plainFormatter hides the RecordAttrsFormatter interface implemented by the formatter it wraps.
+type plainFormatter struct {
+	formatter.BufferFormatter
+}
+
+func (f plainFormatter) KeepsDuplicateKeys() bool {
+	return f.BufferFormatter.(formatter.DuplicateKeyFormatter).KeepsDuplicateKeys()
+}
+
+func TestJSONHandlerFusedAttrs(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := map[string]func(*formatter.JSONFormatterOptions){
+		"default":          func(o *formatter.JSONFormatterOptions) {},
+		"unsorted":         func(o *formatter.JSONFormatterOptions) { o.SortAttrs = false },
+		"case insensitive": func(o *formatter.JSONFormatterOptions) { o.SortAttrsCaseInsensitive = true },
+		"array":            func(o *formatter.JSONFormatterOptions) { o.DuplicateKeyMode = formatter.DuplicateKeyArray },
+	}
+	for name, setOpts := range tests {
+		opts := formatter.DefaultJSONFormatterOptions()
+		setOpts(&opts)
+		f := formatter.NewJSONFormatter(opts)
+
+		output := make([]string, 2)
+		for i, rf := range []formatter.BufferFormatter{f, plainFormatter{f}} {
+			var buf strings.Builder
+			h := handler.NewJSONHandler(handler.JSONHandlerOptions{
+				RecordFormatter: rf,
+				Writer:          &buf,
+			}).WithAttrs([]slog.Attr{slog.String("Zone", "us"), slog.Int("b", 1)}).WithGroup("req").
+				WithAttrs([]slog.Attr{slog.String("id", "abc"), slog.Group("user", slog.String("name", "x"))})
+			r := slog.NewRecord(timestamp, slog.LevelInfo, "message", 0)
+			r.AddAttrs(slog.String("id", "def"), slog.Group("user", slog.Int("age", 3)), slog.String("a", "y"))
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Errorf("%s: unexpected error: %s", name, err)
+				return
+			}
+			output[i] = buf.String()
+		}
+		if output[0] != output[1] {
+			t.Errorf("%s: expected output %s, got %s", name, output[1], output[0])
+		}
+	}
+}
+
+// benchmarkJSONHandler formats a record with several handler and record attributes using the given formatter.
+func benchmarkJSONHandler(b *testing.B, f formatter.BufferFormatter) {
+	h := handler.NewJSONHandler(handler.JSONHandlerOptions{
+		RecordFormatter: f,
+		Writer:          io.Discard,
+	}).WithAttrs([]slog.Attr{slog.String("service", "api"), slog.Int("pid", 1234)}).WithGroup("request").
+		WithAttrs([]slog.Attr{slog.String("id", "abc123")})
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "request completed", 0)
+	r.AddAttrs(
+		slog.String("method", "GET"),
+		slog.String("path", "/api/v1/users"),
+		slog.Int("status", 200),
+		slog.Duration("elapsed", time.Millisecond),
+		slog.String("client", "127.0.0.1"),
+		slog.String("agent", "curl/8.0"),
+	)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = h.Handle(ctx, r)
+	}
+}
+
+func BenchmarkJSONHandlerHandle(b *testing.B) {
+	benchmarkJSONHandler(b, formatter.DefaultJSONFormatter())
+}
+
+func BenchmarkJSONHandlerHandleConsolidated(b *testing.B) {
+	benchmarkJSONHandler(b, plainFormatter{formatter.DefaultJSONFormatter()})
+}
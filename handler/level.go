@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// probeLevels are the levels used to find the lowest level a handler logs when it does not report it directly.
+var probeLevels = []slogx.Level{
+	slogx.LevelMin,
+	slogx.LevelTrace,
+	slogx.LevelDebug,
+	slogx.LevelInfo,
+	slogx.LevelNotice,
+	slogx.LevelWarn,
+	slogx.LevelError,
+	slogx.LevelFatal,
+	slogx.LevelPanic,
+	slogx.LevelMax,
+}
+
+// handlerMinLevel returns the lowest level at which the given handler logs records.
+//
+// Handlers implementing slogx.MinLevelHandler or slogx.LevelVarHandler report their level directly. Any other handler
+// is asked whether it is enabled for each of the levels defined by slogx in turn, so a handler using a level between
+// them is reported at the next defined level below it. If the handler is not enabled for any of them,
+// slogx.LevelDisabled is returned.
+func handlerMinLevel(h slog.Handler) slog.Level {
+	switch lh := h.(type) {
+	case slogx.MinLevelHandler:
+		return lh.MinLevel()
+	case slogx.LevelVarHandler:
+		if lv := lh.Level(); lv != nil {
+			return slog.Level(lv.Level())
+		}
+	}
+	if h == nil {
+		return slog.Level(slogx.LevelDisabled)
+	}
+	for _, l := range probeLevels {
+		if h.Enabled(context.Background(), slog.Level(l)) {
+			return slog.Level(l)
+		}
+	}
+	return slog.Level(slogx.LevelDisabled)
+}
+
+// minLevel returns the lowest level at which any of the given handlers logs records.
+//
+// If there are no handlers, slogx.LevelDisabled is returned.
+func minLevel(handlers []slog.Handler) slog.Level {
+	level := slog.Level(slogx.LevelDisabled)
+	for _, h := range handlers {
+		level = min(level, handlerMinLevel(h))
+	}
+	return level
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// LevelHookFn is the function called by the level hook handler for each record at or above its threshold.
+type LevelHookFn func(context.Context, slog.Record)
+
+// levelHookHandler is a handler which calls a function for each record at or above a level before passing the record
+// onto the next handler.
+//
+// This is typically used to trigger side-effects for important records, such as paging someone or incrementing a
+// counter, regardless of where the records are written.
+type levelHookHandler struct {
+	// unexported variables
+	fn        LevelHookFn
+	next      slog.Handler
+	threshold slog.Leveler
+}
+
+// NewLevelHookHandler creates a new handler object.
+//
+// The function is called synchronously before the record is passed onto the next handler, so it should return
+// quickly. To do any slow work, start a goroutine from the function and use r.Clone() if the record is used by it.
+// Any panic raised by the function is recovered so that the record is still logged. The record does not include any
+// attributes added to the handler using WithAttrs(). If threshold is nil, it defaults to slogx.LevelError.
+func NewLevelHookHandler(next slog.Handler, threshold slog.Leveler, fn LevelHookFn) *levelHookHandler {
+	if threshold == nil {
+		threshold = slogx.LevelError
+	}
+	return &levelHookHandler{
+		fn:        fn,
+		next:      next,
+		threshold: threshold,
+	}
+}
+
+// Enabled returns whether or not the next handler would log this message or the function should be called for it.
+func (h levelHookHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.fn != nil && l >= h.threshold.Level() {
+		return true
+	}
+	if h.next == nil {
+		return false
+	}
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle calls the function if the record is at or above the threshold and then sends the record onto the next
+// handler if it is enabled for the record's level.
+func (h *levelHookHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.fn != nil && r.Level >= h.threshold.Level() {
+		h.callHook(ctx, r)
+	}
+	if h.next == nil || !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+func (h levelHookHandler) Shutdown(continueOnError bool) error {
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h levelHookHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next != nil {
+		return &levelHookHandler{
+			fn:        h.fn,
+			next:      h.next.WithAttrs(attrs),
+			threshold: h.threshold,
+		}
+	}
+	return &h
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h levelHookHandler) WithGroup(name string) slog.Handler {
+	if h.next != nil {
+		return &levelHookHandler{
+			fn:        h.fn,
+			next:      h.next.WithGroup(name),
+			threshold: h.threshold,
+		}
+	}
+	return &h
+}
+
+// callHook calls the function for the record, recovering from any panic raised by it.
+func (h *levelHookHandler) callHook(ctx context.Context, r slog.Record) {
+	defer func() {
+		_ = recover()
+	}()
+	h.fn(ctx, r)
+}
@@ -0,0 +1,37 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestLevelHookHandler(t *testing.T) {
+	var output bytes.Buffer
+	messages := []string{}
+	h := handler.NewLevelHookHandler(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Level:  slogx.NewLevelVar(slogx.LevelFatal),
+		Writer: &output,
+	}), slogx.LevelError, func(ctx context.Context, r slog.Record) {
+		messages = append(messages, r.Message)
+		if r.Message == "panic" {
+			panic("hook failed")
+		}
+	})
+	logger := slogx.Wrap(slog.New(h))
+	logger.Info("info")
+	logger.Error("error")
+	logger.Fatal("panic")
+
+	if len(messages) != 2 || messages[0] != "error" || messages[1] != "panic" {
+		t.Errorf("expected hook to be called for error and fatal records, got %v", messages)
+	}
+	if strings.Contains(output.String(), "error") || !strings.Contains(output.String(), "panic") {
+		t.Errorf("expected only the fatal record to be logged, got %s", output.String())
+	}
+}
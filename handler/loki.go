@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"log/slog"
+
+	"github.com/go-resty/resty/v2"
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+const (
+	// lokiPushPath is the path of the Loki push API relative to the base URL.
+	lokiPushPath = "/loki/api/v1/push"
+
+	// lokiStreamOrderWindow is how far behind the newest entry pushed the last entry pushed to a stream may be before
+	// the handler stops keeping entries for that stream in order.
+	//
+	// This matches the window within which Loki accepts out-of-order entries by default.
+	lokiStreamOrderWindow = time.Hour
+)
+
+// lokiLabelInvalidCharsRegex matches the characters which are not allowed in a Loki label name.
+var lokiLabelInvalidCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// lokiHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type lokiHandlerOptionsContext struct{}
+
+// LokiHandlerOptions holds the options for the Grafana Loki handler.
+type LokiHandlerOptions struct {
+	// BatchFlushInterval is the maximum amount of time to hold records in a batch before pushing them.
+	//
+	// By default, this is set to 5 seconds.
+	BatchFlushInterval time.Duration
+
+	// BatchSize is the maximum number of records to push to Loki in a single request.
+	//
+	// By default, this is set to 100.
+	BatchSize int
+
+	// BearerToken is the token to use to authenticate with Loki using bearer authentication.
+	//
+	// If set, this takes precedence over Username and Password.
+	BearerToken string
+
+	// HTTPClient allows for the use of a custom HTTP client for pushing the records to Loki.
+	//
+	// If nil, a default resty client is used.
+	HTTPClient *resty.Client
+
+	// IgnoreAttrs is a list of regular expressions to use for matching attributes which should not be included in the
+	// log line sent to Loki.
+	//
+	// Attributes nested within groups are matched using a single period (.) to separate the group and attribute names
+	// (eg: GROUP.ATTRIBUTE). If a group matches, the entire group is removed. Attributes are removed after the labels
+	// have been extracted from the record but before the record is formatted. If any regular expression does not
+	// compile, it is simply ignored.
+	IgnoreAttrs []string
+
+	// LabelAttrs is a list of attribute keys whose values are used as labels for the stream the record is pushed to.
+	//
+	// Use a single period (.) to separate group name from attribute name if the attribute is nested within a group
+	// (eg: GROUP.ATTRIBUTE). Any character which is not allowed in a Loki label name is replaced with an underscore
+	// (eg: http.method becomes http_method). Attributes missing from a record are simply not used as labels.
+	LabelAttrs []string
+
+	// Labels is a set of static labels added to every stream.
+	//
+	// Labels extracted from attributes take precedence over static labels with the same name.
+	Labels map[string]string
+
+	// Level is the minimum log level to write to the handler.
+	//
+	// If this is nil, it defaults to slogx.LevelInfo.
+	Level *slogx.LevelVar
+
+	// LevelLabel is the name of the label to store the level of the record in.
+	//
+	// If empty, the level is not used as a label. DefaultLokiHandlerOptions() sets this to "level".
+	LevelLabel string
+
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
+	// Password is the password to use to authenticate with Loki using basic authentication.
+	Password string
+
+	// RecordFormatter specifies the formatter to use to format the log line for each record.
+	//
+	// Any trailing newline is removed from the line. If no formatter is supplied, formatter.DefaultJSONFormatter is
+	// used to format the output.
+	RecordFormatter formatter.BufferFormatter
+
+	// TenantID is the ID of the tenant to push records as when Loki is running in multi-tenant mode.
+	//
+	// If set, it is passed to Loki in the X-Scope-OrgID header.
+	TenantID string
+
+	// URL is the base URL of the Loki server (eg: http://localhost:3100).
+	//
+	// Records are pushed to the /loki/api/v1/push endpoint of the server. This is a required option.
+	URL string
+
+	// Username is the username to use to authenticate with Loki using basic authentication.
+	//
+	// If empty, basic authentication is not used.
+	Username string
+}
+
+// ContextWithLokiHandlerOptions adds the options to the given context and returns the new context.
+func ContextWithLokiHandlerOptions(ctx context.Context, opts LokiHandlerOptions) context.Context {
+	return context.WithValue(ctx, lokiHandlerOptionsContext{}, &opts)
+}
+
+// DefaultLokiHandlerOptions returns a default set of options for the handler.
+func DefaultLokiHandlerOptions() LokiHandlerOptions {
+	return LokiHandlerOptions{
+		BatchFlushInterval: 5 * time.Second,
+		BatchSize:          100,
+		HTTPClient:         resty.New(),
+		IgnoreAttrs:        []string{},
+		LabelAttrs:         []string{},
+		Labels:             map[string]string{},
+		Level:              slogx.NewLevelVar(slogx.LevelInfo),
+		LevelLabel:         "level",
+		RecordFormatter:    formatter.DefaultJSONFormatter(),
+	}
+}
+
+// LokiHandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func LokiHandlerOptionsFromContext(ctx context.Context) *LokiHandlerOptions {
+	o := ctx.Value(lokiHandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*LokiHandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultLokiHandlerOptions()
+	return &opts
+}
+
+// lokiEntry holds a single formatted record waiting to be pushed to Loki.
+type lokiEntry struct {
+	labels    map[string]string
+	line      string
+	stream    string
+	timestamp time.Time
+}
+
+// lokiPushRequest is the body of a request to the Loki push API.
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+// lokiStream holds the entries for a single stream within a request to the Loki push API.
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// lokiState holds the batch of records shared between a Loki handler and any handlers created from it.
+type lokiState struct {
+	done     chan struct{}
+	entries  []lokiEntry
+	lastPush map[string]time.Time
+	lock     sync.Mutex
+	pushLock sync.Mutex
+	shutdown sync.Once
+	wg       sync.WaitGroup
+}
+
+// lokiHandler is a log handler that pushes batches of records to Grafana Loki.
+type lokiHandler struct {
+	attrs               []slog.Attr
+	groups              []string
+	ignoredAttrPatterns []*regexp.Regexp
+	options             LokiHandlerOptions
+	state               *lokiState
+}
+
+// NewLokiHandler creates a new handler object.
+//
+// Batches are pushed in a separate goroutine every BatchFlushInterval. You should be sure to call the Shutdown()
+// function or use the slogx.Shutdown() function to ensure any pending records have been written.
+func NewLokiHandler(opts LokiHandlerOptions) (*lokiHandler, error) {
+	// validate required options
+	if opts.URL == "" {
+		return nil, ErrMissingURL
+	}
+
+	// set default options
+	if opts.BatchFlushInterval <= 0 {
+		opts.BatchFlushInterval = 5 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = resty.New()
+	}
+	if opts.Level == nil {
+		opts.Level = slogx.NewLevelVar(slogx.LevelInfo)
+	}
+
+	// create the handler
+	h := &lokiHandler{
+		attrs:               nameAttrs(opts.Name, opts.NameAttrKey),
+		groups:              []string{},
+		ignoredAttrPatterns: compileAttrPatterns(opts.IgnoreAttrs),
+		options:             opts,
+		state: &lokiState{
+			done:     make(chan struct{}),
+			entries:  make([]lokiEntry, 0, opts.BatchSize),
+			lastPush: map[string]time.Time{},
+		},
+	}
+	h.state.wg.Add(1)
+	go h.flushPeriodically()
+	return h, nil
+}
+
+// Enabled determines whether or not the given level is enabled in this handler.
+func (h lokiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slogx.Level(level) >= h.options.Level.Level()
+}
+
+// Flush pushes any records in the current batch to Loki.
+//
+// Loki requires the entries within a stream to be pushed in ascending order, so the entries for each stream are
+// sorted by time and any entry older than the newest entry already pushed to its stream is given that entry's time.
+// Streams whose newest entry is more than an hour older than the newest entry pushed are forgotten so that the
+// handler does not keep track of every stream it has ever pushed to.
+func (h lokiHandler) Flush(ctx context.Context) error {
+	h.state.pushLock.Lock()
+	defer h.state.pushLock.Unlock()
+
+	h.state.lock.Lock()
+	entries := h.state.entries
+	h.state.entries = make([]lokiEntry, 0, h.options.BatchSize)
+	h.state.lock.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// group the entries by stream in ascending order
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].timestamp.Before(entries[j].timestamp)
+	})
+	streams := map[string]*lokiStream{}
+	keys := []string{}
+	var newest time.Time
+	for _, e := range entries {
+		s, ok := streams[e.stream]
+		if !ok {
+			s = &lokiStream{Stream: e.labels, Values: [][2]string{}}
+			streams[e.stream] = s
+			keys = append(keys, e.stream)
+		}
+		if last, ok := h.state.lastPush[e.stream]; ok && e.timestamp.Before(last) {
+			e.timestamp = last
+		}
+		h.state.lastPush[e.stream] = e.timestamp
+		if e.timestamp.After(newest) {
+			newest = e.timestamp
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.timestamp.UnixNano(), 10), e.line})
+	}
+	for stream, last := range h.state.lastPush {
+		if last.Before(newest.Add(-lokiStreamOrderWindow)) {
+			delete(h.state.lastPush, stream)
+		}
+	}
+	body := lokiPushRequest{Streams: make([]lokiStream, 0, len(keys))}
+	for _, k := range keys {
+		body.Streams = append(body.Streams, *streams[k])
+	}
+	return h.push(ctx, body)
+}
+
+// Handle actually handles adding the record to the batch, pushing the batch to Loki if it is full.
+//
+// Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
+// If a duplicate is encountered, the last value found will be used for the attribute's value.
+func (h *lokiHandler) Handle(ctx context.Context, r slog.Record) error {
+	handlerCtx := slogx.ContextWithGroupStack(ContextWithLokiHandlerOptions(ctx, h.options), h.groups)
+	attrs := consolidateAttrs(h.attrs, h.groups, r, nil, h.options.RecordFormatter)
+	labels := h.labels(slogx.Level(r.Level), attrs)
+	attrs = removeAttrs(attrs, "", h.ignoredAttrPatterns)
+
+	// format the output into a buffer
+	var buf *slogx.Buffer
+	var err error
+	if h.options.RecordFormatter != nil {
+		buf, err = h.options.RecordFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
+			attrs)
+	} else {
+		f := formatter.DefaultJSONFormatter()
+		buf, err = f.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
+	}
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	// add the record to the batch
+	timestamp := r.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	h.state.lock.Lock()
+	h.state.entries = append(h.state.entries, lokiEntry{
+		labels:    labels,
+		line:      string(bytes.TrimRight(buf.Bytes(), "\r\n")),
+		stream:    lokiStreamKey(labels),
+		timestamp: timestamp,
+	})
+	full := len(h.state.entries) >= h.options.BatchSize
+	h.state.lock.Unlock()
+	if full {
+		// the batch holds records from other callers too, so don't let this caller cancel pushing it
+		return h.Flush(context.WithoutCancel(ctx))
+	}
+	return nil
+}
+
+// Level returns a pointer to the handler's level for updating.
+func (h lokiHandler) Level() *slogx.LevelVar {
+	return h.options.Level
+}
+
+// Shutdown is responsible for cleaning up resources used by the handler.
+//
+// Any records remaining in the batch are pushed to Loki.
+func (h lokiHandler) Shutdown(continueOnError bool) error {
+	h.state.shutdown.Do(func() {
+		close(h.state.done)
+	})
+	h.state.wg.Wait()
+	return h.Flush(context.Background())
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+func (h lokiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := &lokiHandler{
+		attrs:               h.attrs,
+		groups:              h.groups,
+		ignoredAttrPatterns: h.ignoredAttrPatterns,
+		options:             h.options,
+		state:               h.state,
+	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
+	return newHandler
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+func (h lokiHandler) WithGroup(name string) slog.Handler {
+	newHandler := &lokiHandler{
+		attrs:               h.attrs,
+		groups:              h.groups,
+		ignoredAttrPatterns: h.ignoredAttrPatterns,
+		options:             h.options,
+		state:               h.state,
+	}
+	if name != "" {
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
+	}
+	return newHandler
+}
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h lokiHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*lokiHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
+
+// flushPeriodically pushes the batch to Loki every BatchFlushInterval until the handler is shut down.
+func (h lokiHandler) flushPeriodically() {
+	defer h.state.wg.Done()
+	ticker := time.NewTicker(h.options.BatchFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = h.Flush(context.Background())
+		case <-h.state.done:
+			return
+		}
+	}
+}
+
+// labels returns the labels for the stream a record with the given level and attributes should be pushed to.
+func (h lokiHandler) labels(level slogx.Level, attrs []slog.Attr) map[string]string {
+	labels := make(map[string]string, len(h.options.Labels)+len(h.options.LabelAttrs)+1)
+	for k, v := range h.options.Labels {
+		labels[lokiLabelName(k)] = v
+	}
+	if h.options.LevelLabel != "" {
+		labels[lokiLabelName(h.options.LevelLabel)] = strings.ToLower(level.String())
+	}
+	if len(h.options.LabelAttrs) > 0 {
+		attrMap := slogx.ToAttrMap(slogx.FlattenAttrs(attrs))
+		for _, key := range h.options.LabelAttrs {
+			if v, ok := attrMap[key]; ok {
+				labels[lokiLabelName(key)] = v.Resolve().String()
+			}
+		}
+	}
+	return labels
+}
+
+// push pushes the given request to the Loki push API.
+func (h lokiHandler) push(ctx context.Context, body lokiPushRequest) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req := h.options.HTTPClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(data)
+	if h.options.BearerToken != "" {
+		req.SetAuthToken(h.options.BearerToken)
+	} else if h.options.Username != "" {
+		req.SetBasicAuth(h.options.Username, h.options.Password)
+	}
+	if h.options.TenantID != "" {
+		req.SetHeader("X-Scope-OrgID", h.options.TenantID)
+	}
+	resp, err := req.Post(strings.TrimRight(h.options.URL, "/") + lokiPushPath)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 400 {
+		return fmt.Errorf("failed to write records - HTTP status code %d", resp.StatusCode())
+	}
+	return nil
+}
+
+// lokiLabelName converts the given name into a valid Loki label name.
+func lokiLabelName(name string) string {
+	name = lokiLabelInvalidCharsRegex.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// lokiStreamKey returns a key which uniquely identifies the stream with the given labels.
+func lokiStreamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var key strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&key, "%s=%q,", k, labels[k])
+	}
+	return key.String()
+}
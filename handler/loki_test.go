@@ -0,0 +1,222 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestLokiHandler(t *testing.T) {
+	type push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	var lock sync.Mutex
+	var pushes []push
+	var paths, tenants, auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p push
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		lock.Lock()
+		pushes = append(pushes, p)
+		paths = append(paths, r.URL.Path)
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+		auths = append(auths, r.Header.Get("Authorization"))
+		lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	opts := handler.DefaultLokiHandlerOptions()
+	opts.BatchFlushInterval = time.Hour
+	opts.BatchSize = 3
+	opts.BearerToken = "secret"
+	opts.LabelAttrs = []string{"http.method"}
+	opts.Labels = map[string]string{"service": "api"}
+	opts.TenantID = "tenant"
+	opts.URL = server.URL + "/"
+	h, err := handler.NewLokiHandler(opts)
+	if err != nil {
+		t.Errorf("failed to create Loki handler: %s", err.Error())
+		return
+	}
+
+	// the records are added out of order to make sure they are sorted within each stream
+	now := time.Now()
+	ctx := context.Background()
+	get := slog.Group("http", slog.String("method", "GET"))
+	records := []slog.Record{
+		slog.NewRecord(now.Add(time.Second), slog.LevelInfo, "second", 0),
+		slog.NewRecord(now, slog.LevelInfo, "first", 0),
+		slog.NewRecord(now, slog.LevelWarn, "warning", 0),
+	}
+	records[0].AddAttrs(get)
+	records[1].AddAttrs(get)
+	for _, r := range records {
+		if err := h.Handle(ctx, r); err != nil {
+			t.Errorf("failed to handle record: %s", err.Error())
+			return
+		}
+	}
+
+	// a record older than the last one pushed to its stream must not go backwards in time
+	late := slog.NewRecord(now.Add(-time.Second), slog.LevelInfo, "late", 0)
+	late.AddAttrs(get)
+	if err := h.Handle(ctx, late); err != nil {
+		t.Errorf("failed to handle record: %s", err.Error())
+		return
+	}
+	if err := slogx.Wrap(slog.New(h)).Shutdown(false); err != nil {
+		t.Errorf("failed to shut down handler: %s", err.Error())
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(pushes) != 2 {
+		t.Errorf("expected 2 pushes, got %d", len(pushes))
+		return
+	}
+	if paths[0] != "/loki/api/v1/push" || tenants[0] != "tenant" || auths[0] != "Bearer secret" {
+		t.Errorf("unexpected request: path=%s tenant=%s auth=%s", paths[0], tenants[0], auths[0])
+		return
+	}
+	if len(pushes[0].Streams) != 2 {
+		t.Errorf("expected 2 streams, got %d", len(pushes[0].Streams))
+		return
+	}
+	infoStream := pushes[0].Streams[0]
+	if infoStream.Stream["level"] != "info" || infoStream.Stream["service"] != "api" ||
+		infoStream.Stream["http_method"] != "GET" {
+		t.Errorf("unexpected labels: %v", infoStream.Stream)
+		return
+	}
+	if len(infoStream.Values) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(infoStream.Values))
+		return
+	}
+	expected := strconv.FormatInt(now.UnixNano(), 10)
+	if infoStream.Values[0][0] != expected {
+		t.Errorf("expected first timestamp %s, got %s", expected, infoStream.Values[0][0])
+		return
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(infoStream.Values[1][1]), &line); err != nil || line["@msg"] != "second" {
+		t.Errorf("unexpected log line: %s", infoStream.Values[1][1])
+		return
+	}
+	if warnStream := pushes[0].Streams[1]; warnStream.Stream["level"] != "warn" {
+		t.Errorf("unexpected labels: %v", warnStream.Stream)
+		return
+	}
+
+	lateStream := pushes[1].Streams[0]
+	expected = strconv.FormatInt(now.Add(time.Second).UnixNano(), 10)
+	if len(lateStream.Values) != 1 || lateStream.Values[0][0] != expected {
+		t.Errorf("expected late entry at %s, got %v", expected, lateStream.Values)
+	}
+}
+
+func TestLokiHandlerForgetsIdleStreams(t *testing.T) {
+	var lock sync.Mutex
+	var timestamps []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p struct {
+			Streams []struct {
+				Values [][2]string `json:"values"`
+			} `json:"streams"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		lock.Lock()
+		timestamps = append(timestamps, p.Streams[0].Values[0][0])
+		lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	opts := handler.DefaultLokiHandlerOptions()
+	opts.BatchFlushInterval = time.Hour
+	opts.BatchSize = 1
+	opts.URL = server.URL
+	h, err := handler.NewLokiHandler(opts)
+	if err != nil {
+		t.Errorf("failed to create Loki handler: %s", err.Error())
+		return
+	}
+	defer h.Shutdown(false)
+
+	// once another stream has moved more than an hour ahead, the info stream is no longer kept in order
+	now := time.Now()
+	records := []slog.Record{
+		slog.NewRecord(now, slog.LevelInfo, "first", 0),
+		slog.NewRecord(now.Add(2*time.Hour), slog.LevelWarn, "warning", 0),
+		slog.NewRecord(now.Add(-time.Minute), slog.LevelInfo, "late", 0),
+	}
+	for _, r := range records {
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Errorf("failed to handle record: %s", err.Error())
+			return
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(timestamps) != 3 {
+		t.Errorf("expected 3 pushes, got %d", len(timestamps))
+		return
+	}
+	if expected := strconv.FormatInt(now.Add(-time.Minute).UnixNano(), 10); timestamps[2] != expected {
+		t.Errorf("expected late entry at %s, got %s", expected, timestamps[2])
+	}
+}
+
+func TestLokiHandlerCancelledContext(t *testing.T) {
+	var lock sync.Mutex
+	pushes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		pushes++
+		lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	opts := handler.DefaultLokiHandlerOptions()
+	opts.BatchFlushInterval = time.Hour
+	opts.BatchSize = 2
+	opts.URL = server.URL
+	h, err := handler.NewLokiHandler(opts)
+	if err != nil {
+		t.Errorf("failed to create Loki handler: %s", err.Error())
+		return
+	}
+	defer h.Shutdown(false)
+
+	// the record filling the batch is logged with a cancelled context, but the whole batch must still be pushed
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "first", 0)); err != nil {
+		t.Errorf("failed to handle record: %s", err.Error())
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "second", 0)); err != nil {
+		t.Errorf("expected batch to be pushed despite the cancelled context, got: %s", err.Error())
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if pushes != 1 {
+		t.Errorf("expected 1 push, got %d", pushes)
+	}
+}
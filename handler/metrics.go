@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// MetricsExtractorFn is called for each record handled by a metrics handler to update any metrics derived from it.
+//
+// The function must not modify the record and should return quickly as it is called synchronously before the record
+// is passed onto the next handler.
+type MetricsExtractorFn func(ctx context.Context, r slog.Record)
+
+// CountByLevel returns a metrics extractor which calls inc with the level of each record.
+//
+// This is typically used to increment a counter labeled by level (eg: a Prometheus CounterVec).
+func CountByLevel(inc func(level slogx.Level)) MetricsExtractorFn {
+	return func(ctx context.Context, r slog.Record) {
+		inc(slogx.Level(r.Level))
+	}
+}
+
+// ObserveDurationAttr returns a metrics extractor which calls observe with the value of the duration attribute with
+// the given key whenever a record contains one.
+//
+// Only attributes added to the record itself are checked, not those added to the handler using WithAttrs(). Use a
+// single period (.) to separate group name from attribute name if the attribute is nested within a group (eg:
+// GROUP.ATTRIBUTE). This is typically used to observe a histogram (eg: a Prometheus HistogramVec).
+func ObserveDurationAttr(key string, observe func(level slogx.Level, d time.Duration)) MetricsExtractorFn {
+	return func(ctx context.Context, r slog.Record) {
+		attrs := make([]slog.Attr, 0, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			attrs = append(attrs, a)
+			return true
+		})
+		for _, attr := range slogx.FlattenAttrs(attrs) {
+			if attr.Key == key && attr.Value.Kind() == slog.KindDuration {
+				observe(slogx.Level(r.Level), attr.Value.Duration())
+			}
+		}
+	}
+}
+
+// metricsHandler is a handler which calls one or more metrics extractors for each record before passing it onto the
+// next handler.
+type metricsHandler struct {
+	// unexported variables
+	extractors []MetricsExtractorFn
+	next       slog.Handler
+}
+
+// NewMetricsHandler creates a new handler object.
+//
+// Extractors are only called for records at levels enabled in the next handler.
+func NewMetricsHandler(next slog.Handler, extractor ...MetricsExtractorFn) *metricsHandler {
+	return &metricsHandler{
+		extractors: extractor,
+		next:       next,
+	}
+}
+
+// Enabled returns whether or not the next handler would log this message.
+func (h metricsHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next == nil {
+		return false
+	}
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle calls each metrics extractor with the record and then sends the record onto the next handler.
+func (h *metricsHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.next == nil {
+		return nil
+	}
+	for _, extractor := range h.extractors {
+		if extractor != nil {
+			extractor(ctx, r)
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+func (h metricsHandler) Shutdown(continueOnError bool) error {
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h metricsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next != nil {
+		return &metricsHandler{
+			extractors: h.extractors,
+			next:       h.next.WithAttrs(attrs),
+		}
+	}
+	return &h
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h metricsHandler) WithGroup(name string) slog.Handler {
+	if h.next != nil {
+		return &metricsHandler{
+			extractors: h.extractors,
+			next:       h.next.WithGroup(name),
+		}
+	}
+	return &h
+}
@@ -0,0 +1,43 @@
+package handler_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	var output bytes.Buffer
+	counts := map[slogx.Level]int{}
+	durations := []time.Duration{}
+	h := handler.NewMetricsHandler(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Writer: &output,
+	}),
+		handler.CountByLevel(func(level slogx.Level) {
+			counts[level]++
+		}),
+		handler.ObserveDurationAttr("http.took", func(level slogx.Level, d time.Duration) {
+			durations = append(durations, d)
+		}),
+	)
+	logger := slogx.Wrap(slog.New(h))
+
+	logger.Debug("not enabled")
+	logger.Info("request", slog.Group("http", slog.Duration("took", 2*time.Second)))
+	logger.Error("failed", slog.Duration("took", time.Second))
+	logger.Error("failed again")
+
+	if counts[slogx.LevelDebug] != 0 || counts[slogx.LevelInfo] != 1 || counts[slogx.LevelError] != 2 {
+		t.Errorf("unexpected level counts: %v", counts)
+	}
+	if len(durations) != 1 || durations[0] != 2*time.Second {
+		t.Errorf("unexpected observed durations: %v", durations)
+	}
+	if output.Len() == 0 {
+		t.Errorf("expected records to be passed onto the next handler")
+	}
+}
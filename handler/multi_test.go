@@ -103,3 +103,26 @@ func TestMultiHandler(t *testing.T) {
 		),
 	)
 }
+
+func TestMultiHandlerMinLevel(t *testing.T) {
+	warn := handler.NewWriterHandler(handler.WriterHandlerOptions{Level: slogx.NewLevelVar(slogx.LevelWarn)})
+	debug := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slogx.LevelDebug})
+	tests := []struct {
+		handler  slogx.MinLevelHandler
+		expected slog.Level
+	}{
+		{handler.NewMultiHandler(handler.MultiHandlerOptions{}), slog.Level(slogx.LevelDisabled)},
+		{handler.NewMultiHandler(handler.MultiHandlerOptions{}, warn), slog.LevelWarn},
+		{handler.NewMultiHandler(handler.MultiHandlerOptions{}, warn, debug), slog.LevelDebug},
+		{handler.NewMultiHandler(handler.MultiHandlerOptions{}, warn,
+			handler.NewRoundRobinHandler(handler.RoundRobinHandlerOptions{}, debug)), slog.LevelDebug},
+		{handler.NewConditionalHandler(handler.ConditionalHandlerOptions{},
+			handler.NewCondition(debug).WithMinLevel(slogx.LevelNotice),
+			handler.NewCondition(warn)), slog.Level(slogx.LevelNotice)},
+	}
+	for i, tt := range tests {
+		if level := tt.handler.MinLevel(); level != tt.expected {
+			t.Errorf("test %d: expected %s, got %s", i, tt.expected, level)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+// netHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type netHandlerOptionsContext struct{}
+
+// NetHandlerOptions holds the options for the network handler.
+type NetHandlerOptions struct {
+	// Address is the address of the remote host to connect to (eg: localhost:514).
+	//
+	// This is a required option.
+	Address string
+
+	// DialTimeout is the maximum amount of time to wait for a connection to be established.
+	//
+	// By default, this is set to 5 seconds.
+	DialTimeout time.Duration
+
+	// Level is the minimum log level to write to the handler.
+	//
+	// If this is nil, it defaults to slogx.LevelInfo.
+	Level *slogx.LevelVar
+
+	// MaxReconnectAttempts is the maximum number of times to try reconnecting and rewriting a record after a write
+	// fails before giving up on the record.
+	//
+	// By default, this is set to 3. If this value is negative, no attempt is made to reconnect until the next record
+	// is written.
+	MaxReconnectAttempts int
+
+	// MaxReconnectBackoff is the maximum amount of time to wait between reconnection attempts.
+	//
+	// By default, this is set to 5 seconds.
+	MaxReconnectBackoff time.Duration
+
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
+	// Network is the type of network to connect to (eg: tcp, tcp4, udp, unix, etc.).
+	//
+	// By default, this is set to tcp.
+	Network string
+
+	// ReconnectBackoff is the amount of time to wait before the first reconnection attempt. The time is doubled for each
+	// subsequent attempt up to MaxReconnectBackoff.
+	//
+	// By default, this is set to 100 milliseconds.
+	ReconnectBackoff time.Duration
+
+	// RecordFormatter specifies the formatter to use to format the record before writing it to the connection.
+	//
+	// If no formatter is supplied, formatter.DefaultJSONFormatter is used to format the output.
+	RecordFormatter formatter.BufferFormatter
+
+	// TLSConfig is the TLS configuration to use when connecting to the remote host.
+	//
+	// If nil, TLS is not used. TLS is only supported for stream-oriented networks such as tcp.
+	TLSConfig *tls.Config
+}
+
+// ContextWithNetHandlerOptions adds the options to the given context and returns the new context.
+func ContextWithNetHandlerOptions(ctx context.Context, opts NetHandlerOptions) context.Context {
+	return context.WithValue(ctx, netHandlerOptionsContext{}, &opts)
+}
+
+// DefaultNetHandlerOptions returns a default set of options for the handler.
+func DefaultNetHandlerOptions() NetHandlerOptions {
+	return NetHandlerOptions{
+		DialTimeout:          5 * time.Second,
+		Level:                slogx.NewLevelVar(slogx.LevelInfo),
+		MaxReconnectAttempts: 3,
+		MaxReconnectBackoff:  5 * time.Second,
+		Network:              "tcp",
+		ReconnectBackoff:     100 * time.Millisecond,
+		RecordFormatter:      formatter.DefaultJSONFormatter(),
+	}
+}
+
+// NetHandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func NetHandlerOptionsFromContext(ctx context.Context) *NetHandlerOptions {
+	o := ctx.Value(netHandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*NetHandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultNetHandlerOptions()
+	return &opts
+}
+
+// netConn holds the connection shared between a network handler and any handlers created from it.
+type netConn struct {
+	closed bool
+	conn   net.Conn
+	lock   sync.Mutex
+}
+
+// netHandler is a log handler that writes records to a raw TCP, UDP or other network socket.
+type netHandler struct {
+	attrs   []slog.Attr
+	conn    *netConn
+	groups  []string
+	options NetHandlerOptions
+}
+
+// NewNetHandler creates a new handler object.
+//
+// The connection to the remote host is not established until the first record is written.
+func NewNetHandler(opts NetHandlerOptions) (*netHandler, error) {
+	// validate required options
+	if opts.Address == "" {
+		return nil, ErrMissingAddress
+	}
+
+	// set default options
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	if opts.Level == nil {
+		opts.Level = slogx.NewLevelVar(slogx.LevelInfo)
+	}
+	if opts.MaxReconnectAttempts == 0 {
+		opts.MaxReconnectAttempts = 3
+	}
+	if opts.MaxReconnectBackoff == 0 {
+		opts.MaxReconnectBackoff = 5 * time.Second
+	}
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	if opts.ReconnectBackoff == 0 {
+		opts.ReconnectBackoff = 100 * time.Millisecond
+	}
+
+	// create the handler
+	return &netHandler{
+		attrs:   nameAttrs(opts.Name, opts.NameAttrKey),
+		conn:    &netConn{},
+		groups:  []string{},
+		options: opts,
+	}, nil
+}
+
+// Enabled determines whether or not the given level is enabled in this handler.
+func (h netHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slogx.Level(level) >= h.options.Level.Level()
+}
+
+// Handle actually handles writing the record to the connection.
+//
+// Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
+// If a duplicate is encountered, the last value found will be used for the attribute's value.
+func (h *netHandler) Handle(ctx context.Context, r slog.Record) error {
+	handlerCtx := slogx.ContextWithGroupStack(ContextWithNetHandlerOptions(ctx, h.options), h.groups)
+	attrs := consolidateAttrs(h.attrs, h.groups, r, nil, h.options.RecordFormatter)
+
+	// format the output into a buffer
+	var buf *slogx.Buffer
+	var err error
+	if h.options.RecordFormatter != nil {
+		buf, err = h.options.RecordFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
+			attrs)
+	} else {
+		f := formatter.DefaultJSONFormatter()
+		buf, err = f.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
+	}
+	if err != nil {
+		return err
+	}
+
+	// write the buffer to the connection
+	return h.write(buf)
+}
+
+// Level returns a pointer to the handler's level for updating.
+func (h netHandler) Level() *slogx.LevelVar {
+	return h.options.Level
+}
+
+// Shutdown is responsible for cleaning up resources used by the handler.
+//
+// Once the handler has been shut down, any further records, including any waiting to reconnect, are rejected with
+// ErrHandlerClosed.
+func (h netHandler) Shutdown(continueOnError bool) error {
+	h.conn.lock.Lock()
+	defer h.conn.lock.Unlock()
+	h.conn.closed = true
+	if h.conn.conn != nil {
+		err := h.conn.conn.Close()
+		h.conn.conn = nil
+		return err
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+func (h netHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := &netHandler{
+		attrs:   h.attrs,
+		conn:    h.conn,
+		groups:  h.groups,
+		options: h.options,
+	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
+	return newHandler
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+func (h netHandler) WithGroup(name string) slog.Handler {
+	newHandler := &netHandler{
+		attrs:   h.attrs,
+		conn:    h.conn,
+		groups:  h.groups,
+		options: h.options,
+	}
+	if name != "" {
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
+	}
+	return newHandler
+}
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h netHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*netHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
+
+// dial establishes a new connection to the remote host.
+//
+// The connection lock must be held by the caller.
+func (h *netHandler) dial() error {
+	dialer := &net.Dialer{Timeout: h.options.DialTimeout}
+	var conn net.Conn
+	var err error
+	if h.options.TLSConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, h.options.Network, h.options.Address, h.options.TLSConfig)
+	} else {
+		conn, err = dialer.Dial(h.options.Network, h.options.Address)
+	}
+	if err != nil {
+		return err
+	}
+	h.conn.conn = conn
+	return nil
+}
+
+// write handles writing the buffer contents to the connection, reconnecting with backoff if the write fails.
+//
+// The connection lock is released while waiting to reconnect so that other records and Shutdown() are not blocked
+// for the duration of the backoff.
+func (h *netHandler) write(buf *slogx.Buffer) error {
+	h.conn.lock.Lock()
+	defer h.conn.lock.Unlock()
+
+	var err error
+	backoff := h.options.ReconnectBackoff
+	for attempt := 0; attempt <= max(h.options.MaxReconnectAttempts, 0); attempt++ {
+		// wait before trying to reconnect
+		if attempt > 0 {
+			h.conn.lock.Unlock()
+			time.Sleep(backoff)
+			h.conn.lock.Lock()
+			backoff *= 2
+			if backoff > h.options.MaxReconnectBackoff {
+				backoff = h.options.MaxReconnectBackoff
+			}
+		}
+
+		// the handler may have been shut down or another record may have reconnected while we were waiting
+		if h.conn.closed {
+			return ErrHandlerClosed
+		}
+
+		// connect if we're not already connected
+		if h.conn.conn == nil {
+			if err = h.dial(); err != nil {
+				continue
+			}
+		}
+
+		// write the message and drop the connection if it fails
+		if _, err = h.conn.conn.Write(buf.Bytes()); err == nil {
+			return nil
+		}
+		h.conn.conn.Close()
+		h.conn.conn = nil
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestNetHandler(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Errorf("failed to create listener: %s", err.Error())
+		return
+	}
+	defer listener.Close()
+
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	netHandler, err := handler.NewNetHandler(handler.NetHandlerOptions{
+		Address: listener.Addr().String(),
+		Level:   slogx.NewLevelVar(slogx.LevelTrace),
+	})
+	if err != nil {
+		t.Errorf("failed to create Net Handler: %s", err.Error())
+		return
+	}
+	logger := slogx.Wrap(slog.New(netHandler))
+	logger.Info("first message")
+	logger.Warn("second message", slog.String("key", "value"))
+	logger.Shutdown(true)
+
+	received := []string{}
+	for line := range lines {
+		received = append(received, line)
+	}
+	if len(received) != 2 {
+		t.Errorf("expected 2 records, got %d", len(received))
+		return
+	}
+	if !strings.Contains(received[0], "first message") || !strings.Contains(received[1], `"key":"value"`) {
+		t.Errorf("unexpected records received: %v", received)
+	}
+}
+
+func TestNetHandlerShutdownDuringBackoff(t *testing.T) {
+	// reserve an address nothing is listening on so every connection attempt fails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Errorf("failed to create listener: %s", err.Error())
+		return
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	netHandler, err := handler.NewNetHandler(handler.NetHandlerOptions{
+		Address:              address,
+		MaxReconnectAttempts: 3,
+		MaxReconnectBackoff:  10 * time.Second,
+		ReconnectBackoff:     500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Errorf("failed to create Net Handler: %s", err.Error())
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- netHandler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0))
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// shutting down must not wait for the backoff and the waiting record must be rejected once it wakes up
+	start := time.Now()
+	_ = netHandler.Shutdown(true)
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("expected shutdown not to wait for the reconnect backoff, took %s", elapsed)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, handler.ErrHandlerClosed) {
+			t.Errorf("expected ErrHandlerClosed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected record to be rejected after the first backoff")
+	}
+}
@@ -2,16 +2,284 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
 
 	"log/slog"
+
+	"go.innotegrity.dev/slogx"
 )
 
 // pipeHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
 type pipeHandlerOptionsContext struct{}
 
+// pipeScopeContext is used to pass the scope of the pipe handler running a pipe function to the function.
+type pipeScopeContext struct{}
+
+// pipeScope identifies a single pipe handler so that pipe functions which keep state between records can tell apart
+// records passed to handlers derived from each other using WithAttrs() or WithGroup().
+type pipeScope struct {
+	// flushes holds the functions to call when the pipe handler is shut down and is shared by every derived handler.
+	flushes *pipeFlushes
+
+	// next is the next handler of the pipe handler, which can be used to pass additional records onto it.
+	next slog.Handler
+}
+
+// pipeFlushes holds the functions registered by pipe functions to flush any pending records when the pipe handler is
+// shut down.
+type pipeFlushes struct {
+	fns  map[any]func() error
+	lock sync.Mutex
+}
+
+// flush calls each of the registered functions, returning the first error encountered.
+//
+// The functions are called without holding the lock so that they can register functions themselves.
+func (f *pipeFlushes) flush(continueOnError bool) error {
+	f.lock.Lock()
+	fns := make([]func() error, 0, len(f.fns))
+	for _, fn := range f.fns {
+		fns = append(fns, fn)
+	}
+	f.lock.Unlock()
+
+	var firstErr error
+	for _, fn := range fns {
+		if err := fn(); err != nil {
+			if !continueOnError {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// register adds the function to call when the pipe handler is shut down, replacing any function registered with the
+// same key.
+func (f *pipeFlushes) register(key any, fn func() error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.fns[key] = fn
+}
+
 // PipeHandlerFn should take the clone of the given record, modify it as needed and return the modified version.
+//
+// To drop the record entirely, return ErrDropRecord. The record is then not passed onto any remaining pipe functions
+// or the next handler and no error is returned from the handler.
 type PipeHandlerFn func(context.Context, slog.Record) (slog.Record, error)
 
+// DedupeOccurrencesKey is the key of the attribute added by DedupePipe() to hold the number of identical records
+// collapsed into a single record.
+const DedupeOccurrencesKey = "occurrences"
+
+// DedupePipe returns a pipe function which drops records that are identical to the immediately preceding record
+// within the given window.
+//
+// This is the same as calling DedupePipeWithOptions() with only the Window option set.
+func DedupePipe(window time.Duration) PipeHandlerFn {
+	return DedupePipeWithOptions(DedupePipeOptions{Window: window})
+}
+
+// DedupePipeOptions holds the options for the pipe function returned by DedupePipeWithOptions().
+type DedupePipeOptions struct {
+	// OmitOccurrences indicates whether or not to leave out the number of records collapsed into a single record.
+	//
+	// When true, duplicate records are simply dropped: no DedupeOccurrencesKey attribute is added and no summary
+	// record is passed on when a streak of identical records ends.
+	OmitOccurrences bool
+
+	// Window is how long identical records are dropped for after a record is passed on.
+	Window time.Duration
+}
+
+// DedupePipeWithOptions returns a pipe function which drops records that are identical to the immediately preceding
+// record within the window set in the options.
+//
+// Records are considered identical if their level, message and attributes all match and they are passed to the same
+// pipe handler. Records passed to handlers derived from each other using WithAttrs() or WithGroup() are never
+// identical, even if their attributes match. The window starts when a record is passed on, so a burst of identical
+// records results in at most one record per window. Unless OmitOccurrences is
+// set, the number of dropped records is reported using an attribute named DedupeOccurrencesKey:
+//   - when an identical record is passed on after the window expired, the attribute is added to it holding the number
+//     of records it represents, including itself
+//   - when a different record ends the streak, a copy of the last dropped record holding the number of identical
+//     records since the last one passed on, including that one, is passed to the next handler first (eg: a burst of 5
+//     identical records results in the first record followed by a summary with occurrences=5)
+//
+// The summary record is passed directly to the next handler of the pipe handler which received the identical records,
+// skipping any remaining pipe functions, and is only written when the function is used by a pipe handler. Any pending
+// summary is also written when the pipe handler is shut down. Only the most recent record is tracked, so the function
+// uses a fixed amount of memory and is safe to use concurrently.
+func DedupePipeWithOptions(opts DedupePipeOptions) PipeHandlerFn {
+	state := &dedupeState{options: opts}
+	return func(ctx context.Context, r slog.Record) (slog.Record, error) {
+		scope, _ := ctx.Value(pipeScopeContext{}).(*pipeScope)
+		hash := recordHash(r)
+		now := time.Now()
+
+		state.lock.Lock()
+		defer state.lock.Unlock()
+		if scope == state.lastScope && hash == state.lastHash && !state.lastTime.IsZero() &&
+			now.Sub(state.lastTime) < opts.Window {
+
+			state.suppressed++
+			if !opts.OmitOccurrences {
+				state.lastRecord = r.Clone()
+				if scope != nil {
+					scope.flushes.register(state, state.shutdownFlush)
+				}
+			}
+			return r, ErrDropRecord
+		}
+		if scope == state.lastScope && hash == state.lastHash {
+			if state.suppressed > 0 && !opts.OmitOccurrences {
+				r.AddAttrs(slog.Int64(DedupeOccurrencesKey, state.suppressed+1))
+			}
+		} else if err := state.flush(ctx); err != nil {
+			return r, err
+		}
+		state.lastHash = hash
+		state.lastRecord = slog.Record{}
+		state.lastScope = scope
+		state.lastTime = now
+		state.suppressed = 0
+		return r, nil
+	}
+}
+
+// dedupeState holds the most recent record seen by a pipe function returned by DedupePipeWithOptions().
+type dedupeState struct {
+	lastHash   uint64
+	lastRecord slog.Record
+	lastScope  *pipeScope
+	lastTime   time.Time
+	lock       sync.Mutex
+	options    DedupePipeOptions
+	suppressed int64
+}
+
+// shutdownFlush passes the summary of any identical records dropped since the last record passed on to the next
+// handler when the pipe handler is shut down.
+func (s *dedupeState) shutdownFlush() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.flush(context.Background())
+}
+
+// flush passes the summary of any identical records dropped since the last record passed on to the next handler of
+// the pipe handler which received them.
+//
+// The lock must be held by the caller.
+func (s *dedupeState) flush(ctx context.Context) error {
+	if s.suppressed == 0 || s.options.OmitOccurrences || s.lastScope == nil {
+		return nil
+	}
+	r := s.lastRecord
+	r.AddAttrs(slog.Int64(DedupeOccurrencesKey, s.suppressed+1))
+	s.lastRecord = slog.Record{}
+	s.suppressed = 0
+	return s.lastScope.next.Handle(ctx, r)
+}
+
+// LoggerNameKey is the default key of the attribute added by LoggerNamePipe() to hold the name of the logger.
+const LoggerNameKey = "logger"
+
+// LoggerNamePipe returns a pipe function which adds the name of the logger which logged the record as an attribute
+// with the given key.
+//
+// The name is retrieved from the context using slogx.LoggerNameFromContext(), so it is set when logging using a
+// slogx.Logger whose Name field is set. If the key is empty, LoggerNameKey is used. Like any other attribute of the
+// record, the attribute is nested within any groups added to the next handler. If no name is stored in the context,
+// the record is returned unchanged.
+func LoggerNamePipe(key string) PipeHandlerFn {
+	if key == "" {
+		key = LoggerNameKey
+	}
+	return func(ctx context.Context, r slog.Record) (slog.Record, error) {
+		if name := slogx.LoggerNameFromContext(ctx); name != "" {
+			r.AddAttrs(slog.String(key, name))
+		}
+		return r, nil
+	}
+}
+
+// RedactedGroupValue is the value used by RedactGroupPipe() in place of the contents of a redacted group.
+const RedactedGroupValue = "<redacted>"
+
+// RedactGroupPipe returns a pipe function which replaces the contents of the group at the given path with
+// RedactedGroupValue.
+//
+// Nested groups are referenced using a single period (.) to separate the group names (eg: request.headers). Only the
+// attributes of the record itself are redacted; attributes added to the next handler using WithAttrs() are not
+// available to pipe functions. If the group is not found in the record, the record is returned unchanged.
+func RedactGroupPipe(groupPath string) PipeHandlerFn {
+	path := strings.Split(groupPath, ".")
+	return func(ctx context.Context, r slog.Record) (slog.Record, error) {
+		redacted := false
+		record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		r.Attrs(func(attr slog.Attr) bool {
+			attr, ok := redactGroup(attr, path)
+			redacted = redacted || ok
+			record.AddAttrs(attr)
+			return true
+		})
+		if !redacted {
+			return r, nil
+		}
+		return record, nil
+	}
+}
+
+// redactGroup replaces the contents of the group at the given path within the attribute with RedactedGroupValue.
+//
+// The second return value indicates whether or not the group was found.
+func redactGroup(attr slog.Attr, path []string) (slog.Attr, bool) {
+	if attr.Key != path[0] {
+		return attr, false
+	}
+	v := attr.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return attr, false
+	}
+	if len(path) == 1 {
+		return slog.String(attr.Key, RedactedGroupValue), true
+	}
+	found := false
+	groupAttrs := make([]any, 0, len(v.Group()))
+	for _, ga := range v.Group() {
+		ga, ok := redactGroup(ga, path[1:])
+		found = found || ok
+		groupAttrs = append(groupAttrs, ga)
+	}
+	if !found {
+		return attr, false
+	}
+	return slog.Group(attr.Key, groupAttrs...), true
+}
+
+// recordHash returns a hash of the level, message and attributes of the given record.
+func recordHash(r slog.Record) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(slog.Level(r.Level).String()))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Message))
+	r.Attrs(func(attr slog.Attr) bool {
+		h.Write([]byte{0})
+		h.Write([]byte(attr.Key))
+		h.Write([]byte{'='})
+		h.Write([]byte(attr.Value.Resolve().String()))
+		return true
+	})
+	return h.Sum64()
+}
+
 // PipeHandlerOptions holds the options for the pipe handler.
 type PipeHandlerOptions struct {
 	// ContinueOnError determines whether or not to continue logging to handlers to if an error occurs while running any
@@ -53,6 +321,7 @@ type pipeHandler struct {
 	// unexported variables
 	next    slog.Handler
 	options PipeHandlerOptions
+	scope   *pipeScope
 }
 
 // NewPipeHandler creates a new object.
@@ -60,6 +329,10 @@ func NewPipeHandler(opts PipeHandlerOptions, next slog.Handler) *pipeHandler {
 	return &pipeHandler{
 		options: opts,
 		next:    next,
+		scope: &pipeScope{
+			flushes: &pipeFlushes{fns: map[any]func() error{}},
+			next:    next,
+		},
 	}
 }
 
@@ -73,6 +346,9 @@ func (h pipeHandler) Enabled(ctx context.Context, l slog.Level) bool {
 }
 
 // Handle runs the record through all of the pipe functions and then sends it on to the next handler.
+//
+// If a pipe function returns an error and ContinueOnError is true, the record returned by that function is ignored
+// and the record is passed onto the next pipe function unchanged.
 func (h *pipeHandler) Handle(ctx context.Context, r slog.Record) error {
 	handlerCtx := ContextWithPipeHandlerOptions(ctx, h.options)
 	if h.next == nil {
@@ -80,17 +356,38 @@ func (h *pipeHandler) Handle(ctx context.Context, r slog.Record) error {
 	}
 
 	// run the pipe functions
-	var err error
+	pipeCtx := context.WithValue(handlerCtx, pipeScopeContext{}, h.scope)
 	record := r.Clone()
 	for _, fn := range h.options.PipeFns {
-		record, err = fn(handlerCtx, record)
-		if err != nil && !h.options.ContinueOnError {
-			return err
+		piped, err := fn(pipeCtx, record)
+		if errors.Is(err, ErrDropRecord) {
+			return nil
+		}
+		if err != nil {
+			if !h.options.ContinueOnError {
+				return err
+			}
+			continue
 		}
+		record = piped
 	}
 
-	// send the record to the next handler
-	return h.next.Handle(handlerCtx, r)
+	// send the modified record to the next handler
+	return h.next.Handle(handlerCtx, record)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+//
+// Any records held back by the pipe functions (eg: the summary of identical records dropped by DedupePipe()) are
+// passed onto the next handler first.
+func (h pipeHandler) Shutdown(continueOnError bool) error {
+	if err := h.scope.flushes.flush(continueOnError); err != nil && !continueOnError {
+		return err
+	}
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
 }
 
 // WithAttrs creates a new handler from the existing one adding the given attributes to it.
@@ -98,7 +395,7 @@ func (h *pipeHandler) Handle(ctx context.Context, r slog.Record) error {
 // If there is no next handler, the existing object is returned instead.
 func (h pipeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if h.next != nil {
-		return NewPipeHandler(h.options, h.next.WithAttrs(attrs))
+		return h.derive(h.next.WithAttrs(attrs))
 	}
 	return &h
 }
@@ -108,7 +405,21 @@ func (h pipeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 // If there is no next handler, the existing object is returned instead.
 func (h pipeHandler) WithGroup(name string) slog.Handler {
 	if h.next != nil {
-		return NewPipeHandler(h.options, h.next.WithGroup(name))
+		return h.derive(h.next.WithGroup(name))
 	}
 	return &h
 }
+
+// derive creates a new handler from the existing one which passes records onto the given next handler.
+//
+// The new handler has its own scope but shares the functions to call when shutting down with the existing one.
+func (h pipeHandler) derive(next slog.Handler) *pipeHandler {
+	return &pipeHandler{
+		options: h.options,
+		next:    next,
+		scope: &pipeScope{
+			flushes: h.scope.flushes,
+			next:    next,
+		},
+	}
+}
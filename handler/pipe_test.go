@@ -0,0 +1,212 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestPipeHandlerDropRecord(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewPipeHandler(handler.PipeHandlerOptions{
+		PipeFns: []handler.PipeHandlerFn{
+			func(ctx context.Context, r slog.Record) (slog.Record, error) {
+				if r.Message == "health check" {
+					return r, handler.ErrDropRecord
+				}
+				return r, nil
+			},
+			func(ctx context.Context, r slog.Record) (slog.Record, error) {
+				r.AddAttrs(slog.String("piped", "yes"))
+				return r, nil
+			},
+		},
+	}, handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Writer: &output,
+	}))
+	logger := slogx.Wrap(slog.New(h))
+
+	logger.Info("health check")
+	logger.Info("real request")
+
+	if strings.Contains(output.String(), "health check") {
+		t.Errorf("expected health check record to be dropped, got: %s", output.String())
+	}
+	if !strings.Contains(output.String(), "real request") || !strings.Contains(output.String(), "piped=yes") {
+		t.Errorf("expected modified record to be passed onto the next handler, got: %s", output.String())
+	}
+}
+
+func TestDedupePipe(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewPipeHandler(handler.PipeHandlerOptions{
+		PipeFns: []handler.PipeHandlerFn{handler.DedupePipe(50 * time.Millisecond)},
+	}, handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Writer: &output,
+	}))
+	logger := slogx.Wrap(slog.New(h))
+
+	for i := 0; i < 5; i++ {
+		logger.Info("connection refused", "host", "db1")
+	}
+	logger.Info("connection refused", "host", "db2")
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 3 {
+		t.Errorf("expected 3 records after deduplication, got %d: %s", len(lines), output.String())
+		return
+	}
+	if !strings.Contains(lines[1], "host=db1") || !strings.Contains(lines[1], "occurrences=5") ||
+		strings.Contains(lines[2], "occurrences") {
+		t.Errorf("expected a summary with occurrences=5 when the streak ended, got: %s", output.String())
+		return
+	}
+
+	output.Reset()
+	for i := 0; i < 3; i++ {
+		logger.Info("connection refused", "host", "db2")
+	}
+	time.Sleep(60 * time.Millisecond)
+	logger.Info("connection refused", "host", "db2")
+	if !strings.Contains(output.String(), "occurrences=4") {
+		t.Errorf("expected occurrences attribute once the window expired, got: %s", output.String())
+	}
+}
+
+func TestDedupePipeDerivedHandlers(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewPipeHandler(handler.PipeHandlerOptions{
+		PipeFns: []handler.PipeHandlerFn{handler.DedupePipe(time.Minute)},
+	}, handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Writer: &output,
+	}))
+	first := slog.New(h).With("req", 1)
+	second := slog.New(h).WithGroup("grp").With("req", 2)
+
+	// identical records from different derived handlers are not collapsed
+	first.Info("connection refused")
+	second.Info("connection refused")
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "req=1") || !strings.Contains(lines[1], "grp.req=2") {
+		t.Errorf("expected a record from each derived handler, got: %s", output.String())
+		return
+	}
+
+	// the summary is written by the handler which dropped the records
+	output.Reset()
+	for i := 0; i < 3; i++ {
+		first.Info("connection refused")
+	}
+	second.Info("connection refused")
+	lines = strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 3 {
+		t.Errorf("expected 3 records, got %d: %s", len(lines), output.String())
+		return
+	}
+	if !strings.Contains(lines[1], "req=1") || strings.Contains(lines[1], "grp.") ||
+		!strings.Contains(lines[1], "occurrences=3") {
+		t.Errorf("expected a summary from the first handler, got: %s", lines[1])
+	}
+	if !strings.Contains(lines[2], "grp.req=2") || strings.Contains(lines[2], "occurrences") {
+		t.Errorf("expected a plain record from the second handler, got: %s", lines[2])
+	}
+}
+
+func TestDedupePipeShutdown(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewPipeHandler(handler.PipeHandlerOptions{
+		PipeFns: []handler.PipeHandlerFn{handler.DedupePipe(time.Minute)},
+	}, handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Writer: &output,
+	}))
+	logger := slogx.Wrap(slog.New(h).With("req", 1))
+
+	for i := 0; i < 4; i++ {
+		logger.Info("connection refused")
+	}
+	if err := slogx.Wrap(slog.New(h)).Shutdown(false); err != nil {
+		t.Errorf("failed to shut down handler: %s", err.Error())
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "req=1") || !strings.Contains(lines[1], "occurrences=4") {
+		t.Errorf("expected the pending summary to be written on shutdown, got: %s", output.String())
+	}
+}
+
+func TestDedupePipeOmitOccurrences(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewPipeHandler(handler.PipeHandlerOptions{
+		PipeFns: []handler.PipeHandlerFn{handler.DedupePipeWithOptions(handler.DedupePipeOptions{
+			OmitOccurrences: true,
+			Window:          time.Minute,
+		})},
+	}, handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Writer: &output,
+	}))
+	logger := slogx.Wrap(slog.New(h))
+
+	for i := 0; i < 5; i++ {
+		logger.Info("connection refused", "host", "db1")
+	}
+	logger.Info("connection refused", "host", "db2")
+	if count := strings.Count(output.String(), "connection refused"); count != 2 {
+		t.Errorf("expected 2 records after deduplication, got %d: %s", count, output.String())
+	}
+	if strings.Contains(output.String(), "occurrences") {
+		t.Errorf("expected no occurrences attribute, got: %s", output.String())
+	}
+}
+
+func TestLoggerNamePipe(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewPipeHandler(handler.PipeHandlerOptions{
+		PipeFns: []handler.PipeHandlerFn{handler.LoggerNamePipe("")},
+	}, handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Writer: &output,
+	}))
+	logger := slogx.Wrap(slog.New(h))
+
+	logger.Info("unnamed")
+	logger.WithName("api").Info("named")
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Errorf("expected 2 lines, got: %s", output.String())
+		return
+	}
+	if strings.Contains(lines[0], handler.LoggerNameKey+"=") {
+		t.Errorf("expected no logger name for unnamed logger, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], handler.LoggerNameKey+"=api") {
+		t.Errorf("expected logger name to be added, got: %s", lines[1])
+	}
+}
+
+func TestRedactGroupPipe(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewPipeHandler(handler.PipeHandlerOptions{
+		PipeFns: []handler.PipeHandlerFn{handler.RedactGroupPipe("request.http.headers")},
+	}, handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Writer: &output,
+	}))
+	logger := slogx.Wrap(slog.New(h))
+
+	logger.Info("request received", slog.Group("request",
+		slog.Group("http",
+			slog.String("method", "GET"),
+			slog.Group("headers", slog.String("Authorization", "Bearer secret")),
+		),
+	))
+	if strings.Contains(output.String(), "secret") {
+		t.Errorf("expected headers to be redacted, got: %s", output.String())
+	}
+	if !strings.Contains(output.String(), "request.http.headers=<redacted>") ||
+		!strings.Contains(output.String(), "request.http.method=GET") {
+		t.Errorf("expected only the headers group to be redacted, got: %s", output.String())
+	}
+}
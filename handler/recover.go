@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// recoverHandler is a handler which recovers from any panic raised by the next handler while handling a record.
+//
+// Rather than propagating the panic up through the logger and crashing the application, the panic and its stack trace
+// are written to a fallback writer.
+type recoverHandler struct {
+	// unexported variables
+	fallback  io.Writer
+	next      slog.Handler
+	writeLock *sync.Mutex
+}
+
+// NewRecoverHandler creates a new handler object.
+//
+// If fallback is nil, recovered panics are written to os.Stderr.
+func NewRecoverHandler(next slog.Handler, fallback io.Writer) *recoverHandler {
+	if fallback == nil {
+		fallback = os.Stderr
+	}
+	return &recoverHandler{
+		fallback:  fallback,
+		next:      next,
+		writeLock: &sync.Mutex{},
+	}
+}
+
+// Enabled returns whether or not the next handler would log this message.
+func (h recoverHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next == nil {
+		return false
+	}
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle sends the record on to the next handler, recovering from any panic that occurs.
+//
+// If a panic is recovered, it is written to the fallback writer and an error is returned.
+func (h *recoverHandler) Handle(ctx context.Context, r slog.Record) (err error) {
+	if h.next == nil {
+		return nil
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("recovered from panic while handling record: %v", rec)
+			h.writeLock.Lock()
+			defer h.writeLock.Unlock()
+			fmt.Fprintf(h.fallback, "%s: %q\n%s\n", err.Error(), r.Message, debug.Stack())
+		}
+	}()
+	return h.next.Handle(ctx, r)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+func (h recoverHandler) Shutdown(continueOnError bool) error {
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h recoverHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next != nil {
+		return &recoverHandler{
+			fallback:  h.fallback,
+			next:      h.next.WithAttrs(attrs),
+			writeLock: h.writeLock,
+		}
+	}
+	return &h
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h recoverHandler) WithGroup(name string) slog.Handler {
+	if h.next != nil {
+		return &recoverHandler{
+			fallback:  h.fallback,
+			next:      h.next.WithGroup(name),
+			writeLock: h.writeLock,
+		}
+	}
+	return &h
+}
@@ -0,0 +1,35 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestRecoverHandler(t *testing.T) {
+	var output, fallback bytes.Buffer
+	opts := formatter.DefaultConsoleFormatterOptions()
+	opts.AttrFormatter = func(ctx context.Context, level slog.Leveler, group, key string,
+		value slog.Value) (string, slog.Value, error) {
+		panic("attribute formatter panicked")
+	}
+	h := handler.NewRecoverHandler(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: formatter.NewConsoleFormatter(opts),
+		Writer:          &output,
+	}), &fallback)
+	logger := slogx.Wrap(slog.New(h))
+
+	logger.Info("this message should not panic", slog.String("key", "value"))
+	if !strings.Contains(fallback.String(), "attribute formatter panicked") {
+		t.Errorf("expected panic to be written to fallback writer, got: %s", fallback.String())
+	}
+	if output.Len() != 0 {
+		t.Errorf("expected no output to be written, got: %s", output.String())
+	}
+}
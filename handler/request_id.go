@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// DefaultRequestIDKey is the default name of the attribute holding the request ID.
+const DefaultRequestIDKey = "request_id"
+
+// requestIDContextKey is used to store the request ID in a context when no other key is configured.
+type requestIDContextKey struct{}
+
+// requestIDHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type requestIDHandlerOptionsContext struct{}
+
+// RequestIDHandlerOptions holds the options for the request ID handler.
+type RequestIDHandlerOptions struct {
+	// AttrKey is the name of the attribute to add the request ID to the record as.
+	//
+	// By default, DefaultRequestIDKey is used.
+	AttrKey string
+
+	// ContextKey is the key used to look up the request ID in the context.
+	//
+	// This allows the handler to use request IDs stored in the context by other middleware. The value stored under
+	// the key should be a string or a fmt.Stringer. If nil, the request ID is looked up using a key private to this
+	// package, which is used by ContextWithRequestID().
+	ContextKey any
+
+	// GenerateMissing determines whether or not to generate a request ID for records whose context does not hold one.
+	//
+	// A handler cannot change the context of the code logging the record, so an ID generated here only applies to the
+	// single record. Use ContextWithRequestID() early on, such as in HTTP middleware, so that every record for a
+	// request shares the same ID.
+	GenerateMissing bool
+
+	// Generator is the function used to generate new request IDs.
+	//
+	// If nil, NewRequestID() is used, which generates a random UUID.
+	Generator func() string
+}
+
+// ContextWithRequestIDHandlerOptions adds the options to the given context and returns the new context.
+func ContextWithRequestIDHandlerOptions(ctx context.Context, opts RequestIDHandlerOptions) context.Context {
+	return context.WithValue(ctx, requestIDHandlerOptionsContext{}, &opts)
+}
+
+// DefaultRequestIDHandlerOptions returns a default set of options for the handler.
+func DefaultRequestIDHandlerOptions() RequestIDHandlerOptions {
+	return RequestIDHandlerOptions{
+		AttrKey:   DefaultRequestIDKey,
+		Generator: NewRequestID,
+	}
+}
+
+// RequestIDHandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func RequestIDHandlerOptionsFromContext(ctx context.Context) *RequestIDHandlerOptions {
+	o := ctx.Value(requestIDHandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*RequestIDHandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultRequestIDHandlerOptions()
+	return &opts
+}
+
+// NewRequestID generates a new random (version 4) UUID to use as a request ID.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// requestIDHandler is a handler which adds the request ID stored in the context to every record passed onto the next
+// handler.
+type requestIDHandler struct {
+	// unexported variables
+	hasAttr bool
+	next    slog.Handler
+	options RequestIDHandlerOptions
+}
+
+// NewRequestIDHandler creates a new handler object.
+func NewRequestIDHandler(opts RequestIDHandlerOptions, next slog.Handler) *requestIDHandler {
+	// set default options
+	if opts.AttrKey == "" {
+		opts.AttrKey = DefaultRequestIDKey
+	}
+	if opts.Generator == nil {
+		opts.Generator = NewRequestID
+	}
+
+	// create the handler
+	return &requestIDHandler{
+		next:    next,
+		options: opts,
+	}
+}
+
+// ContextWithRequestID returns the request ID stored in the context, generating a new one and storing it in the
+// returned context if there is none.
+//
+// The ID is stored using ContextKey, so every record logged using the returned context, or any context derived from
+// it, is given the same ID by the handler. The ID can also be used to correlate the request with other systems, such as
+// by returning it in a response header.
+func (h requestIDHandler) ContextWithRequestID(ctx context.Context) (context.Context, string) {
+	if id := h.requestID(ctx); id != "" {
+		return ctx, id
+	}
+	id := h.options.Generator()
+	return context.WithValue(ctx, h.contextKey(), id), id
+}
+
+// Enabled returns whether or not the next handler would log this message.
+func (h requestIDHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next == nil {
+		return false
+	}
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle adds the request ID from the context to the record and sends it onto the next handler.
+//
+// The ID is not added if the record or handler already has an attribute with the same key, even if the handler's
+// attribute is outside of a group added later using WithGroup(). Otherwise the attribute is added to the record
+// itself, so it is nested within any groups added to the handler using WithGroup().
+func (h *requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.next == nil {
+		return nil
+	}
+	if !h.hasAttr && !recordHasAttr(r, h.options.AttrKey) {
+		id := h.requestID(ctx)
+		if id == "" && h.options.GenerateMissing {
+			id = h.options.Generator()
+		}
+		if id != "" {
+			r = r.Clone()
+			r.AddAttrs(slog.String(h.options.AttrKey, id))
+		}
+	}
+	return h.next.Handle(ContextWithRequestIDHandlerOptions(ctx, h.options), r)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+func (h requestIDHandler) Shutdown(continueOnError bool) error {
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next != nil {
+		hasAttr := h.hasAttr
+		for _, attr := range attrs {
+			hasAttr = hasAttr || attr.Key == h.options.AttrKey
+		}
+		return &requestIDHandler{
+			hasAttr: hasAttr,
+			next:    h.next.WithAttrs(attrs),
+			options: h.options,
+		}
+	}
+	return &h
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+//
+// If there is no next handler, the existing object is returned instead.
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	if h.next != nil {
+		return &requestIDHandler{
+			hasAttr: h.hasAttr,
+			next:    h.next.WithGroup(name),
+			options: h.options,
+		}
+	}
+	return &h
+}
+
+// contextKey returns the key used to store the request ID in a context.
+func (h requestIDHandler) contextKey() any {
+	if h.options.ContextKey != nil {
+		return h.options.ContextKey
+	}
+	return requestIDContextKey{}
+}
+
+// requestID returns the request ID stored in the context or an empty string if there is none.
+func (h requestIDHandler) requestID(ctx context.Context) string {
+	switch id := ctx.Value(h.contextKey()).(type) {
+	case string:
+		return id
+	case fmt.Stringer:
+		return id.String()
+	}
+	return ""
+}
+
+// recordHasAttr returns whether or not the record has a top-level attribute with the given key.
+func recordHasAttr(r slog.Record, key string) bool {
+	found := false
+	r.Attrs(func(attr slog.Attr) bool {
+		found = attr.Key == key
+		return !found
+	})
+	return found
+}
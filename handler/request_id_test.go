@@ -0,0 +1,92 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	var output bytes.Buffer
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.NestAttributes = false
+	h := handler.NewRequestIDHandler(handler.RequestIDHandlerOptions{}, handler.NewWriterHandler(
+		handler.WriterHandlerOptions{
+			RecordFormatter: formatter.NewJSONFormatter(opts),
+			Writer:          &output,
+		}))
+	logger := slog.New(h)
+
+	ctx, id := h.ContextWithRequestID(context.Background())
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("expected a random UUID, got %s", id)
+		return
+	}
+	if _, existing := h.ContextWithRequestID(ctx); existing != id {
+		t.Errorf("expected existing request ID %s to be kept, got %s", id, existing)
+		return
+	}
+	logger.InfoContext(ctx, "first")
+	logger.InfoContext(ctx, "second", slog.String(handler.DefaultRequestIDKey, "explicit"))
+	logger.InfoContext(context.Background(), "third")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 3 {
+		t.Errorf("expected 3 records, got %d: %s", len(lines), output.String())
+		return
+	}
+	if !strings.Contains(lines[0], `"request_id":"`+id+`"`) {
+		t.Errorf("expected request ID in record, got: %s", lines[0])
+	}
+	if strings.Count(lines[1], "request_id") != 1 || !strings.Contains(lines[1], `"request_id":"explicit"`) {
+		t.Errorf("expected existing request ID attribute to be kept, got: %s", lines[1])
+	}
+	if strings.Contains(lines[2], "request_id") {
+		t.Errorf("expected no request ID without GenerateMissing, got: %s", lines[2])
+	}
+}
+
+func TestRequestIDHandlerWithGroup(t *testing.T) {
+	var output bytes.Buffer
+	h := handler.NewRequestIDHandler(handler.RequestIDHandlerOptions{}, handler.NewWriterHandler(
+		handler.WriterHandlerOptions{
+			RecordFormatter: formatter.DefaultJSONFormatter(),
+			Writer:          &output,
+		}))
+	ctx, _ := h.ContextWithRequestID(context.Background())
+
+	slog.New(h).With(slog.String(handler.DefaultRequestIDKey, "explicit")).WithGroup("g").InfoContext(ctx, "message",
+		slog.Int("k", 1))
+	if strings.Count(output.String(), "request_id") != 1 || !strings.Contains(output.String(), `"request_id":"explicit"`) {
+		t.Errorf("expected only the handler's request ID attribute, got: %s", output.String())
+	}
+}
+
+func TestRequestIDHandlerContextKey(t *testing.T) {
+	type traceKey struct{}
+	var output bytes.Buffer
+	h := handler.NewRequestIDHandler(handler.RequestIDHandlerOptions{
+		AttrKey:         "trace",
+		ContextKey:      traceKey{},
+		GenerateMissing: true,
+		Generator:       func() string { return "generated" },
+	}, handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: formatter.DefaultJSONFormatter(),
+		Writer:          &output,
+	}))
+	logger := slog.New(h)
+	logger.InfoContext(context.WithValue(context.Background(), traceKey{}, "upstream"), "first")
+	logger.Info("second")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"trace":"upstream"`) ||
+		!strings.Contains(lines[1], `"trace":"generated"`) {
+		t.Errorf("unexpected output: %s", output.String())
+	}
+}
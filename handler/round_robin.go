@@ -84,6 +84,14 @@ func (h *roundRobinHandler) Handle(ctx context.Context, r slog.Record) error {
 	return err
 }
 
+// MinLevel returns the lowest level at which any of the handlers logs records.
+//
+// Handlers which do not report their level directly are checked using Enabled(), so this is a best-effort value for
+// handlers whose level is dynamic or unknown. If there are no handlers, slogx.LevelDisabled is returned.
+func (h roundRobinHandler) MinLevel() slog.Level {
+	return minLevel(h.handlers)
+}
+
 // Shutdown is responsible for cleaning up resources used by the handler.
 func (h roundRobinHandler) Shutdown(continueOnError bool) error {
 	for _, handler := range h.handlers {
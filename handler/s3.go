@@ -0,0 +1,455 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"log/slog"
+
+	"github.com/go-resty/resty/v2"
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+// s3HandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type s3HandlerOptionsContext struct{}
+
+// S3HandlerOptions holds the options for the S3 handler.
+type S3HandlerOptions struct {
+	// AccessKeyID is the access key ID to use when signing requests.
+	//
+	// If empty, the AWS_ACCESS_KEY_ID environment variable is used. If that is also empty, requests are not signed.
+	AccessKeyID string
+
+	// Bucket is the name of the bucket to write objects to.
+	//
+	// This is a required option.
+	Bucket string
+
+	// Endpoint is the base URL of the S3-compatible service (eg: https://minio.example.com:9000).
+	//
+	// By default, this is set to the AWS S3 endpoint for the region.
+	Endpoint string
+
+	// FlushInterval is how often to write any buffered records to a new object.
+	//
+	// By default, this is set to 1 minute.
+	FlushInterval time.Duration
+
+	// FlushSize is the number of uncompressed bytes to buffer before writing the buffered records to a new object
+	// without waiting for FlushInterval.
+	//
+	// By default, this is set to 5MB (5000000 bytes).
+	FlushSize int
+
+	// HTTPClient allows for the use of a custom HTTP client for writing objects.
+	//
+	// If nil, a default resty client is used.
+	HTTPClient *resty.Client
+
+	// KeyPrefix is prepended to the name of each object written to the bucket (eg: logs/app/).
+	KeyPrefix string
+
+	// KeyTimeLayout is the layout used to format the time the object is written in UTC for the name of the object.
+	//
+	// The object name is made up of KeyPrefix, the formatted time and a .log.gz extension. By default, this is set to
+	// 2006/01/02/150405.000000000.
+	KeyTimeLayout string
+
+	// Level is the minimum log level to write to the handler.
+	//
+	// If this is nil, it defaults to slogx.LevelInfo.
+	Level *slogx.LevelVar
+
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
+	// RecordFormatter specifies the formatter to use to format the record before buffering it.
+	//
+	// If no formatter is supplied, formatter.DefaultJSONFormatter is used to format the output.
+	RecordFormatter formatter.BufferFormatter
+
+	// Region is the region of the bucket used when signing requests.
+	//
+	// By default, this is set to us-east-1.
+	Region string
+
+	// SecretAccessKey is the secret access key to use when signing requests.
+	//
+	// If empty, the AWS_SECRET_ACCESS_KEY environment variable is used.
+	SecretAccessKey string
+
+	// SessionToken is the session token to use for temporary credentials.
+	//
+	// If empty, the AWS_SESSION_TOKEN environment variable is used.
+	SessionToken string
+
+	// UsePathStyle indicates whether or not to include the bucket in the path of the URL rather than the host name.
+	//
+	// This is typically required for S3-compatible services such as MinIO.
+	UsePathStyle bool
+}
+
+// ContextWithS3HandlerOptions adds the options to the given context and returns the new context.
+func ContextWithS3HandlerOptions(ctx context.Context, opts S3HandlerOptions) context.Context {
+	return context.WithValue(ctx, s3HandlerOptionsContext{}, &opts)
+}
+
+// DefaultS3HandlerOptions returns a default set of options for the handler.
+func DefaultS3HandlerOptions() S3HandlerOptions {
+	return S3HandlerOptions{
+		FlushInterval:   time.Minute,
+		FlushSize:       5000000,
+		HTTPClient:      resty.New(),
+		KeyTimeLayout:   "2006/01/02/150405.000000000",
+		Level:           slogx.NewLevelVar(slogx.LevelInfo),
+		RecordFormatter: formatter.DefaultJSONFormatter(),
+		Region:          "us-east-1",
+	}
+}
+
+// S3HandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func S3HandlerOptionsFromContext(ctx context.Context) *S3HandlerOptions {
+	o := ctx.Value(s3HandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*S3HandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultS3HandlerOptions()
+	return &opts
+}
+
+// s3State holds the buffered records shared between an S3 handler and any handlers created from it.
+type s3State struct {
+	buf      bytes.Buffer
+	bufLock  sync.Mutex
+	done     chan struct{}
+	shutdown sync.Once
+	wg       sync.WaitGroup
+}
+
+// s3Handler is a log handler that buffers records and periodically writes them as a gzipped object to an
+// S3-compatible object store.
+type s3Handler struct {
+	attrs   []slog.Attr
+	groups  []string
+	options S3HandlerOptions
+	state   *s3State
+}
+
+// NewS3Handler creates a new handler object.
+//
+// Buffered records are written in a separate goroutine every FlushInterval. You should be sure to call the
+// Shutdown() function or use the slogx.Shutdown() function to ensure any buffered records are written.
+func NewS3Handler(opts S3HandlerOptions) (*s3Handler, error) {
+	// validate required options
+	if opts.Bucket == "" {
+		return nil, ErrMissingBucket
+	}
+
+	// set default options
+	if opts.AccessKeyID == "" {
+		opts.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Minute
+	}
+	if opts.FlushSize <= 0 {
+		opts.FlushSize = 5000000
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = resty.New()
+	}
+	if opts.KeyTimeLayout == "" {
+		opts.KeyTimeLayout = "2006/01/02/150405.000000000"
+	}
+	if opts.Level == nil {
+		opts.Level = slogx.NewLevelVar(slogx.LevelInfo)
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.Region)
+	}
+	if opts.SecretAccessKey == "" {
+		opts.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if opts.SessionToken == "" {
+		opts.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if _, err := url.Parse(opts.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %s", err.Error())
+	}
+
+	// create the handler
+	h := &s3Handler{
+		attrs:   nameAttrs(opts.Name, opts.NameAttrKey),
+		groups:  []string{},
+		options: opts,
+		state: &s3State{
+			done: make(chan struct{}),
+		},
+	}
+	h.state.wg.Add(1)
+	go h.flushPeriodically()
+	return h, nil
+}
+
+// Enabled determines whether or not the given level is enabled in this handler.
+func (h s3Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slogx.Level(level) >= h.options.Level.Level()
+}
+
+// Flush writes any buffered records to a new object in the bucket.
+//
+// If no records are buffered, nothing is written.
+func (h s3Handler) Flush() error {
+	h.state.bufLock.Lock()
+	if h.state.buf.Len() == 0 {
+		h.state.bufLock.Unlock()
+		return nil
+	}
+	data := bytes.Clone(h.state.buf.Bytes())
+	h.state.buf.Reset()
+	h.state.bufLock.Unlock()
+	return h.putObject(data)
+}
+
+// Handle actually handles buffering the record for writing to the bucket.
+//
+// If the buffer reaches FlushSize, the buffered records are written to the bucket immediately.
+//
+// Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
+// If a duplicate is encountered, the last value found will be used for the attribute's value.
+func (h *s3Handler) Handle(ctx context.Context, r slog.Record) error {
+	handlerCtx := slogx.ContextWithGroupStack(ContextWithS3HandlerOptions(ctx, h.options), h.groups)
+	attrs := consolidateAttrs(h.attrs, h.groups, r, nil, h.options.RecordFormatter)
+
+	// format the output into a buffer
+	var buf *slogx.Buffer
+	var err error
+	if h.options.RecordFormatter != nil {
+		buf, err = h.options.RecordFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
+			attrs)
+	} else {
+		f := formatter.DefaultJSONFormatter()
+		buf, err = f.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
+	}
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	// add the record to the buffered records
+	h.state.bufLock.Lock()
+	h.state.buf.Write(buf.Bytes())
+	full := h.state.buf.Len() >= h.options.FlushSize
+	h.state.bufLock.Unlock()
+	if full {
+		return h.Flush()
+	}
+	return nil
+}
+
+// Level returns a pointer to the handler's level for updating.
+func (h s3Handler) Level() *slogx.LevelVar {
+	return h.options.Level
+}
+
+// Shutdown is responsible for cleaning up resources used by the handler.
+//
+// Any buffered records are written to a final object in the bucket.
+func (h s3Handler) Shutdown(continueOnError bool) error {
+	h.state.shutdown.Do(func() {
+		close(h.state.done)
+	})
+	h.state.wg.Wait()
+	return h.Flush()
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+func (h s3Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := &s3Handler{
+		attrs:   h.attrs,
+		groups:  h.groups,
+		options: h.options,
+		state:   h.state,
+	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
+	return newHandler
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+func (h s3Handler) WithGroup(name string) slog.Handler {
+	newHandler := &s3Handler{
+		attrs:   h.attrs,
+		groups:  h.groups,
+		options: h.options,
+		state:   h.state,
+	}
+	if name != "" {
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
+	}
+	return newHandler
+}
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h s3Handler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*s3Handler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
+
+// flushPeriodically writes the buffered records to the bucket every FlushInterval until the handler is shut down.
+func (h s3Handler) flushPeriodically() {
+	defer h.state.wg.Done()
+	ticker := time.NewTicker(h.options.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = h.Flush()
+		case <-h.state.done:
+			return
+		}
+	}
+}
+
+// putObject compresses the data and writes it to a new object in the bucket.
+func (h s3Handler) putObject(data []byte) error {
+	// compress the data
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	// build the URL for the object
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s%s.log.gz", h.options.KeyPrefix, now.Format(h.options.KeyTimeLayout))
+	endpoint, _ := url.Parse(h.options.Endpoint)
+	host := endpoint.Host
+	path := "/" + s3EscapePath(key)
+	if h.options.UsePathStyle {
+		path = "/" + s3EscapePath(h.options.Bucket) + path
+	} else {
+		host = h.options.Bucket + "." + host
+	}
+
+	// sign and send the request
+	headers := map[string]string{
+		"Content-Type":         "application/gzip",
+		"X-Amz-Content-Sha256": s3Hash(body.Bytes()),
+		"X-Amz-Date":           now.Format("20060102T150405Z"),
+	}
+	if h.options.SessionToken != "" {
+		headers["X-Amz-Security-Token"] = h.options.SessionToken
+	}
+	if h.options.AccessKeyID != "" {
+		headers["Authorization"] = h.signature(now, host, path, headers)
+	}
+	req := h.options.HTTPClient.R().
+		SetHeaders(headers).
+		SetBody(body.Bytes())
+	req.Header.Set("Host", host)
+	resp, err := req.Put(fmt.Sprintf("%s://%s%s", endpoint.Scheme, host, path))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 400 {
+		return fmt.Errorf("failed to write object - HTTP status code %d", resp.StatusCode())
+	}
+	return nil
+}
+
+// signature returns the AWS Signature Version 4 authorization header for a PUT request with the given headers.
+func (h s3Handler) signature(t time.Time, host, path string, headers map[string]string) string {
+	// build the canonical request
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", host,
+		headers["X-Amz-Content-Sha256"], headers["X-Amz-Date"])
+	if token, ok := headers["X-Amz-Security-Token"]; ok {
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += fmt.Sprintf("x-amz-security-token:%s\n", token)
+	}
+	canonicalRequest := strings.Join([]string{
+		"PUT",
+		path,
+		"",
+		canonicalHeaders,
+		signedHeaders,
+		headers["X-Amz-Content-Sha256"],
+	}, "\n")
+
+	// sign the request
+	date := t.Format("20060102")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, h.options.Region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		headers["X-Amz-Date"],
+		scope,
+		s3Hash([]byte(canonicalRequest)),
+	}, "\n")
+	key := s3HMAC([]byte("AWS4"+h.options.SecretAccessKey), date)
+	key = s3HMAC(key, h.options.Region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", h.options.AccessKeyID,
+		scope, signedHeaders, hex.EncodeToString(s3HMAC(key, stringToSign)))
+}
+
+// s3EscapePath escapes the given object path as required by AWS Signature Version 4, leaving slashes unescaped.
+func s3EscapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3Hash returns the hex-encoded SHA256 hash of the data.
+func s3Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// s3HMAC returns the HMAC-SHA256 of the data using the given key.
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
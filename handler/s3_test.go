@@ -0,0 +1,77 @@
+package handler_test
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestS3HandlerFlushOnShutdown(t *testing.T) {
+	var lock sync.Mutex
+	var paths, auths, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(zr)
+		paths = append(paths, r.URL.Path)
+		auths = append(auths, r.Header.Get("Authorization"))
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	h, err := handler.NewS3Handler(handler.S3HandlerOptions{
+		AccessKeyID:     "AKID",
+		Bucket:          "logs",
+		Endpoint:        server.URL,
+		FlushInterval:   time.Hour,
+		KeyPrefix:       "app/",
+		Level:           slogx.NewLevelVar(slogx.LevelInfo),
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+	})
+	if err != nil {
+		t.Errorf("failed to create S3 handler: %s", err.Error())
+		return
+	}
+	logger := slogx.Wrap(slog.New(h))
+	logger.Info("first message")
+	logger.Info("second message")
+	if err := logger.Shutdown(false); err != nil {
+		t.Errorf("failed to shut down handler: %s", err.Error())
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(paths) != 1 {
+		t.Errorf("expected 1 object to be written, got %d", len(paths))
+		return
+	}
+	if !strings.HasPrefix(paths[0], "/logs/app/") || !strings.HasSuffix(paths[0], ".log.gz") {
+		t.Errorf("unexpected object path: %s", paths[0])
+	}
+	if !strings.HasPrefix(auths[0], "AWS4-HMAC-SHA256 Credential=AKID/") {
+		t.Errorf("unexpected authorization header: %s", auths[0])
+	}
+	if !strings.Contains(bodies[0], "first message") || !strings.Contains(bodies[0], "second message") {
+		t.Errorf("object is missing records: %s", bodies[0])
+	}
+}
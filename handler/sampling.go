@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+const (
+	// SamplingSummaryDroppedKey is the name of the attribute holding the number of records dropped in a summary record.
+	SamplingSummaryDroppedKey = "dropped"
+
+	// SamplingSummaryMessageKey is the name of the attribute holding the message of the sampled records in a summary
+	// record.
+	SamplingSummaryMessageKey = "sampled_message"
+
+	// SamplingSummaryTotalKey is the name of the attribute holding the total number of records sampled in a summary
+	// record.
+	SamplingSummaryTotalKey = "total"
+)
+
+// SampleConfig defines how records are sampled.
+type SampleConfig struct {
+	// Rate is the fraction of records to keep, between 0 and 1 (eg: 0.01 keeps 1 out of every 100 records).
+	//
+	// Records are kept at evenly spaced intervals rather than randomly so that sampling is deterministic. A rate of
+	// 0 or less drops every record while a rate of 1 or more keeps every record.
+	Rate float64
+}
+
+// samplingHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type samplingHandlerOptionsContext struct{}
+
+// SamplingHandlerOptions holds the options for the sampling handler.
+type SamplingHandlerOptions struct {
+	// Default is the sampling configuration to use for levels which do not have an entry in PerLevel.
+	//
+	// If nil, records at levels without an entry in PerLevel are always kept.
+	Default *SampleConfig
+
+	// MinLevelAlwaysLog is the level at or above which records are always kept regardless of any sampling
+	// configuration.
+	//
+	// If nil, this defaults to slogx.LevelError.
+	MinLevelAlwaysLog slog.Leveler
+
+	// PerLevel holds the sampling configuration for specific levels.
+	//
+	// Levels are matched exactly, so a configuration for slogx.LevelDebug does not apply to records at
+	// slogx.LevelDebug+1.
+	PerLevel map[slogx.Level]SampleConfig
+
+	// SummaryInterval is how often to emit a summary record for each message which had records dropped.
+	//
+	// Summary records are logged at the level of the dropped records with a message such as "dropped 9900 of 10000
+	// debug records" along with the SamplingSummaryMessageKey, SamplingSummaryDroppedKey and SamplingSummaryTotalKey
+	// attributes, so no record goes uncounted even when it is dropped. A final summary is emitted when the handler is
+	// shut down, so be sure to call the Shutdown() function or use the slogx.Shutdown() function. If this is 0 or
+	// less, no summary records are emitted.
+	SummaryInterval time.Duration
+}
+
+// ContextWithSamplingHandlerOptions adds the options to the given context and returns the new context.
+func ContextWithSamplingHandlerOptions(ctx context.Context, opts SamplingHandlerOptions) context.Context {
+	return context.WithValue(ctx, samplingHandlerOptionsContext{}, &opts)
+}
+
+// DefaultSamplingHandlerOptions returns a default set of options for the handler.
+func DefaultSamplingHandlerOptions() SamplingHandlerOptions {
+	return SamplingHandlerOptions{
+		MinLevelAlwaysLog: slogx.LevelError,
+		PerLevel:          map[slogx.Level]SampleConfig{},
+	}
+}
+
+// SamplingHandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func SamplingHandlerOptionsFromContext(ctx context.Context) *SamplingHandlerOptions {
+	o := ctx.Value(samplingHandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*SamplingHandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultSamplingHandlerOptions()
+	return &opts
+}
+
+// samplingState holds the counters shared between a sampling handler and any handlers created from it.
+type samplingState struct {
+	counts    map[slogx.Level]uint64
+	done      chan struct{}
+	lock      sync.Mutex
+	shutdown  sync.Once
+	summaries map[samplingSummaryKey]*samplingSummary
+	wg        sync.WaitGroup
+}
+
+// samplingSummary holds the number of records sampled for a single message since the last summary was emitted.
+type samplingSummary struct {
+	dropped uint64
+	total   uint64
+}
+
+// samplingSummaryKey identifies the records counted by a single summary.
+type samplingSummaryKey struct {
+	level slogx.Level
+	msg   string
+}
+
+// samplingHandler is a handler which only passes a sample of records onto the next handler.
+type samplingHandler struct {
+	// unexported variables
+	next    slog.Handler
+	options SamplingHandlerOptions
+	state   *samplingState
+}
+
+// NewSamplingHandler creates a new handler object.
+func NewSamplingHandler(opts SamplingHandlerOptions, next slog.Handler) *samplingHandler {
+	// set default options
+	if opts.MinLevelAlwaysLog == nil {
+		opts.MinLevelAlwaysLog = slogx.LevelError
+	}
+
+	// create the handler
+	h := &samplingHandler{
+		next:    next,
+		options: opts,
+		state: &samplingState{
+			counts:    map[slogx.Level]uint64{},
+			done:      make(chan struct{}),
+			summaries: map[samplingSummaryKey]*samplingSummary{},
+		},
+	}
+	if opts.SummaryInterval > 0 && next != nil {
+		h.state.wg.Add(1)
+		go h.summarizePeriodically()
+	}
+	return h
+}
+
+// Enabled returns whether or not the next handler would log this message.
+func (h samplingHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next == nil {
+		return false
+	}
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle sends the record onto the next handler if it is selected by the sampling configuration for its level.
+//
+// Records at or above MinLevelAlwaysLog are always sent onto the next handler.
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.next == nil || !h.keep(slogx.Level(r.Level), r.Message) {
+		return nil
+	}
+	return h.next.Handle(ContextWithSamplingHandlerOptions(ctx, h.options), r)
+}
+
+// Shutdown is responsible for cleaning up resources used by the next handler.
+//
+// If SummaryInterval is set, a final summary is emitted for any records dropped since the last summary before the
+// next handler is shut down.
+func (h samplingHandler) Shutdown(continueOnError bool) error {
+	if h.options.SummaryInterval > 0 && h.next != nil {
+		h.state.shutdown.Do(func() {
+			close(h.state.done)
+		})
+		h.state.wg.Wait()
+		if err := h.summarize(); err != nil && !continueOnError {
+			return err
+		}
+	}
+	if sh, ok := h.next.(slogx.ShutdownableHandler); ok {
+		return sh.Shutdown(continueOnError)
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+//
+// The new handler shares its sampling counters with the existing handler. If there is no next handler, the existing
+// object is returned instead.
+func (h samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.next != nil {
+		return &samplingHandler{
+			next:    h.next.WithAttrs(attrs),
+			options: h.options,
+			state:   h.state,
+		}
+	}
+	return &h
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+//
+// The new handler shares its sampling counters with the existing handler. If there is no next handler, the existing
+// object is returned instead.
+func (h samplingHandler) WithGroup(name string) slog.Handler {
+	if h.next != nil {
+		return &samplingHandler{
+			next:    h.next.WithGroup(name),
+			options: h.options,
+			state:   h.state,
+		}
+	}
+	return &h
+}
+
+// keep determines whether or not a record at the given level with the given message should be kept.
+func (h samplingHandler) keep(level slogx.Level, msg string) bool {
+	if level >= slogx.Level(h.options.MinLevelAlwaysLog.Level()) {
+		return true
+	}
+	config, ok := h.options.PerLevel[level]
+	if !ok {
+		if h.options.Default == nil {
+			return true
+		}
+		config = *h.options.Default
+	}
+	if config.Rate >= 1 {
+		return true
+	}
+
+	// keep the record whenever the running total of kept records crosses the next whole number
+	h.state.lock.Lock()
+	defer h.state.lock.Unlock()
+	keep := false
+	if config.Rate > 0 {
+		h.state.counts[level]++
+		n := float64(h.state.counts[level])
+		keep = uint64(n*config.Rate) > uint64((n-1)*config.Rate)
+	}
+	if h.options.SummaryInterval > 0 {
+		key := samplingSummaryKey{level: level, msg: msg}
+		summary, ok := h.state.summaries[key]
+		if !ok {
+			summary = &samplingSummary{}
+			h.state.summaries[key] = summary
+		}
+		summary.total++
+		if !keep {
+			summary.dropped++
+		}
+	}
+	return keep
+}
+
+// summarize emits a summary record to the next handler for each message which had records dropped since the last
+// summary and resets the counts.
+func (h samplingHandler) summarize() error {
+	h.state.lock.Lock()
+	summaries := h.state.summaries
+	h.state.summaries = map[samplingSummaryKey]*samplingSummary{}
+	h.state.lock.Unlock()
+
+	// emit the summaries in a consistent order
+	keys := make([]samplingSummaryKey, 0, len(summaries))
+	for key := range summaries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].msg < keys[j].msg
+	})
+
+	ctx := ContextWithSamplingHandlerOptions(context.Background(), h.options)
+	var errs []error
+	for _, key := range keys {
+		summary := summaries[key]
+		if summary.dropped == 0 || !h.next.Enabled(ctx, key.level.Level()) {
+			continue
+		}
+		r := slog.NewRecord(time.Now(), key.level.Level(), fmt.Sprintf("dropped %d of %d %s records",
+			summary.dropped, summary.total, strings.ToLower(key.level.String())), 0)
+		r.AddAttrs(
+			slog.String(SamplingSummaryMessageKey, key.msg),
+			slog.Uint64(SamplingSummaryDroppedKey, summary.dropped),
+			slog.Uint64(SamplingSummaryTotalKey, summary.total),
+		)
+		if err := h.next.Handle(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// summarizePeriodically emits summary records every SummaryInterval until the handler is shut down.
+func (h samplingHandler) summarizePeriodically() {
+	defer h.state.wg.Done()
+	ticker := time.NewTicker(h.options.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = h.summarize()
+		case <-h.state.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package handler_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+func TestSamplingHandlerPerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	next := handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Level:           slogx.NewLevelVar(slogx.LevelTrace),
+		RecordFormatter: formatter.DefaultJSONFormatter(),
+		Writer:          &buf,
+	})
+	logger := slogx.Wrap(slog.New(handler.NewSamplingHandler(handler.SamplingHandlerOptions{
+		Default: &handler.SampleConfig{Rate: 0},
+		PerLevel: map[slogx.Level]handler.SampleConfig{
+			slogx.LevelDebug: {Rate: 0.1},
+			slogx.LevelInfo:  {Rate: 1},
+		},
+	}, next)))
+
+	for i := 0; i < 100; i++ {
+		logger.Trace("trace")
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Error("error")
+	}
+
+	counts := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		for _, level := range []string{"trace", "debug", "info", "error"} {
+			if strings.Contains(line, `"@level":"`+level+`"`) {
+				counts[level]++
+			}
+		}
+	}
+	expected := map[string]int{"trace": 0, "debug": 10, "info": 100, "error": 100}
+	for level, count := range expected {
+		if counts[level] != count {
+			t.Errorf("expected %d %s records, got %d", count, level, counts[level])
+		}
+	}
+}
+
+func TestSamplingHandlerSummary(t *testing.T) {
+	var buf bytes.Buffer
+	next := handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Level:           slogx.NewLevelVar(slogx.LevelTrace),
+		RecordFormatter: formatter.DefaultJSONFormatter(),
+		Writer:          &buf,
+	})
+	logger := slogx.Wrap(slog.New(handler.NewSamplingHandler(handler.SamplingHandlerOptions{
+		PerLevel: map[slogx.Level]handler.SampleConfig{
+			slogx.LevelDebug: {Rate: 0.01},
+		},
+		SummaryInterval: time.Hour,
+	}, next)))
+
+	for i := 0; i < 1000; i++ {
+		logger.Debug("cache miss")
+	}
+	logger.Info("info")
+	if err := logger.Shutdown(false); err != nil {
+		t.Errorf("failed to shut down handler: %s", err.Error())
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 12 {
+		t.Errorf("expected 12 records, got %d", len(lines))
+		return
+	}
+	summary := lines[len(lines)-1]
+	for _, expected := range []string{
+		`"@level":"debug"`,
+		`"@msg":"dropped 990 of 1000 debug records"`,
+		`"sampled_message":"cache miss"`,
+		`"dropped":990`,
+		`"total":1000`,
+	} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("expected summary to contain %s, got %s", expected, summary)
+		}
+	}
+}
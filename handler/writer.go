@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+)
+
+// writerHandlerOptionsContext can be used to retrieve the options used by the handler from the context.
+type writerHandlerOptionsContext struct{}
+
+// WriterHandlerOptions holds the options for the writer handler.
+type WriterHandlerOptions struct {
+	// Level is the minimum log level to write to the handler.
+	//
+	// If this is nil, it defaults to slogx.LevelInfo.
+	Level *slogx.LevelVar
+
+	// Name is the name of the handler to add to every record under NameAttrKey.
+	//
+	// This is useful for telling which destination a record was written to when records are sent to several handlers
+	// (eg: using a multi handler).
+	Name string
+
+	// NameAttrKey is the key of the attribute holding Name which is added to every record (eg: "handler").
+	//
+	// The attribute is added at the top level of the record rather than within any groups added to the handler. If
+	// empty, which is the default, or if Name is empty, no attribute is added.
+	NameAttrKey string
+
+	// RecordFormatter specifies the formatter to use to format the record before writing it to the writer.
+	//
+	// Unlike the console handler, any formatter may be used here as no colorization support is required. If no
+	// formatter is supplied, an uncolorized formatter.DefaultConsoleFormatter is used to format the output.
+	RecordFormatter formatter.BufferFormatter
+
+	// Writer is where to write the output to.
+	//
+	// By default, messages are written to os.Stdout if not supplied.
+	Writer io.Writer
+}
+
+// ContextWithWriterHandlerOptions adds the options to the given context and returns the new context.
+func ContextWithWriterHandlerOptions(ctx context.Context, opts WriterHandlerOptions) context.Context {
+	return context.WithValue(ctx, writerHandlerOptionsContext{}, &opts)
+}
+
+// DefaultWriterHandlerOptions returns a default set of options for the handler.
+func DefaultWriterHandlerOptions() WriterHandlerOptions {
+	return WriterHandlerOptions{
+		Level:           slogx.NewLevelVar(slogx.LevelInfo),
+		RecordFormatter: formatter.DefaultConsoleFormatter(false),
+		Writer:          os.Stdout,
+	}
+}
+
+// WriterHandlerOptionsFromContext retrieves the options from the context.
+//
+// If the options are not set in the context, a set of default options is returned instead.
+func WriterHandlerOptionsFromContext(ctx context.Context) *WriterHandlerOptions {
+	o := ctx.Value(writerHandlerOptionsContext{})
+	if o != nil {
+		if opts, ok := o.(*WriterHandlerOptions); ok {
+			return opts
+		}
+	}
+	opts := DefaultWriterHandlerOptions()
+	return &opts
+}
+
+// writerHandler is a log handler that writes records to any io.Writer using any formatter.
+type writerHandler struct {
+	attrs     []slog.Attr
+	closed    *bool
+	groups    []string
+	options   WriterHandlerOptions
+	writeLock *sync.Mutex
+}
+
+// NewWriterHandler creates a new handler object.
+func NewWriterHandler(opts WriterHandlerOptions) *writerHandler {
+	// set default options
+	if opts.Level == nil {
+		opts.Level = slogx.NewLevelVar(slogx.LevelInfo)
+	}
+	if opts.Writer == nil {
+		opts.Writer = os.Stdout
+	}
+
+	// create the handler
+	return &writerHandler{
+		attrs:     nameAttrs(opts.Name, opts.NameAttrKey),
+		closed:    new(bool),
+		groups:    []string{},
+		options:   opts,
+		writeLock: &sync.Mutex{},
+	}
+}
+
+// Enabled determines whether or not the given level is enabled in this handler.
+func (h writerHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slogx.Level(level) >= h.options.Level.Level()
+}
+
+// Handle actually handles writing the record to the output writer.
+//
+// Any attributes duplicated between the handler and record, including within groups, are automaticlaly removed.
+// If a duplicate is encountered, the last value found will be used for the attribute's value.
+func (h *writerHandler) Handle(ctx context.Context, r slog.Record) error {
+	handlerCtx := slogx.ContextWithGroupStack(ContextWithWriterHandlerOptions(ctx, h.options), h.groups)
+	attrs := consolidateAttrs(h.attrs, h.groups, r, nil, h.options.RecordFormatter)
+
+	// format the output into a buffer
+	var buf *slogx.Buffer
+	var err error
+	if h.options.RecordFormatter != nil {
+		buf, err = h.options.RecordFormatter.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message,
+			attrs)
+	} else {
+		f := formatter.DefaultConsoleFormatter(false)
+		buf, err = f.FormatRecord(handlerCtx, r.Time, slogx.Level(r.Level), r.PC, r.Message, attrs)
+	}
+	if err != nil {
+		return err
+	}
+
+	// write the buffer to the output
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	if *h.closed {
+		return ErrHandlerClosed
+	}
+	_, err = h.options.Writer.Write(buf.Bytes())
+	return err
+}
+
+// Level returns a pointer to the handler's level for updating.
+func (h writerHandler) Level() *slogx.LevelVar {
+	return h.options.Level
+}
+
+// Shutdown is responsible for cleaning up resources used by the handler.
+//
+// Once the handler has been shut down, any further records are rejected with ErrHandlerClosed.
+func (h writerHandler) Shutdown(continueOnError bool) error {
+	h.writeLock.Lock()
+	defer h.writeLock.Unlock()
+	if *h.closed {
+		return nil
+	}
+	*h.closed = true
+	if w, ok := h.options.Writer.(io.WriteCloser); ok {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithAttrs creates a new handler from the existing one adding the given attributes to it.
+func (h writerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := &writerHandler{
+		attrs:     h.attrs,
+		closed:    h.closed,
+		groups:    h.groups,
+		options:   h.options,
+		writeLock: h.writeLock,
+	}
+	newHandler.attrs = append(slices.Clip(newHandler.attrs), slogx.NestAttrs(h.groups, attrs)...)
+	return newHandler
+}
+
+// WithGroup creates a new handler from the existing one adding the given group to it.
+func (h writerHandler) WithGroup(name string) slog.Handler {
+	newHandler := &writerHandler{
+		attrs:     h.attrs,
+		closed:    h.closed,
+		groups:    h.groups,
+		options:   h.options,
+		writeLock: h.writeLock,
+	}
+	if name != "" {
+		newHandler.groups = append(slices.Clip(newHandler.groups), name)
+	}
+	return newHandler
+}
+
+// WithReplacedAttrs creates a new handler from the existing one adding the given attributes to it, replacing any
+// existing attributes with the same key rather than appending duplicates.
+func (h writerHandler) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	newHandler := h.WithAttrs(nil).(*writerHandler)
+	newHandler.attrs = replaceAttrs(h.attrs, h.groups, attrs)
+	return newHandler
+}
@@ -0,0 +1,193 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+// groupStackFormatter records the group stack found in the context passed to FormatRecord.
+type groupStackFormatter struct {
+	groups []string
+}
+
+func (f *groupStackFormatter) FormatRecord(ctx context.Context, timestamp time.Time, level slogx.Level, pc uintptr,
+	msg string, attrs []slog.Attr) (*slogx.Buffer, error) {
+
+	f.groups = slogx.GroupStackFromContext(ctx)
+	return slogx.NewBuffer(), nil
+}
+
+func TestWriterHandlerGroupStack(t *testing.T) {
+	f := &groupStackFormatter{}
+	logger := slog.New(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: f,
+		Writer:          io.Discard,
+	}))
+
+	logger.WithGroup("request").WithGroup("user").Info("this is a message")
+	if !slices.Equal(f.groups, []string{"request", "user"}) {
+		t.Errorf("expected group stack [request user], got %v", f.groups)
+	}
+
+	// changing the stack seen by the formatter must not change the handler's groups
+	grouped := logger.WithGroup("request")
+	grouped.Info("this is a message")
+	f.groups[0] = "changed"
+	grouped.Info("this is a message")
+	if !slices.Equal(f.groups, []string{"request"}) {
+		t.Errorf("expected group stack [request], got %v", f.groups)
+	}
+}
+
+func TestWriterHandlerNestedGroups(t *testing.T) {
+	var buf strings.Builder
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.NestAttributes = false
+	opts.SortAttrs = false
+	logger := slog.New(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: formatter.NewJSONFormatter(opts),
+		Writer:          &buf,
+	}))
+
+	tests := []struct {
+		logger   *slog.Logger
+		expected string
+	}{
+		{
+			logger:   logger.WithGroup("a").WithGroup("b"),
+			expected: `"a":{"b":{"k":2}}}`,
+		},
+		{
+			logger:   logger.WithGroup("a").With("x", 1),
+			expected: `"a":{"x":1,"k":2}}`,
+		},
+		{
+			logger:   logger.With("r", 0).WithGroup("a").With("x", 1).WithGroup("b").With("y", 3),
+			expected: `"r":0,"a":{"x":1,"b":{"y":3,"k":2}}}`,
+		},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		tt.logger.Info("message", "k", 2)
+		if !strings.HasSuffix(strings.TrimSpace(buf.String()), tt.expected) {
+			t.Errorf("expected output to end with %s, got %s", tt.expected, buf.String())
+		}
+	}
+
+	// handlers created from the same handler must not share attributes
+	base := logger.With("a", 1).With("b", 2).With("c", 3)
+	first, second := base.With("d", 4), base.With("e", 5)
+	buf.Reset()
+	first.Info("message")
+	second.Info("message")
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[0], `"d":4`) || strings.Contains(lines[0], `"e":5`) {
+		t.Errorf("expected sibling handlers to keep separate attributes, got %s", buf.String())
+	}
+}
+
+func TestWriterHandlerWithReplacedAttrs(t *testing.T) {
+	var buf strings.Builder
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.NestAttributes = false
+	opts.SortAttrs = false
+	logger := slogx.Wrap(slog.New(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: formatter.NewJSONFormatter(opts),
+		Writer:          &buf,
+	})))
+	resolved := 0
+	logger = slogx.Wrap(logger.WithReplace("a", 1, "b", 1).WithReplace("a", 2).WithGroup("g"))
+	logger = logger.WithReplace("c", 1).WithReplace(slogx.Lazy("c", func() any { resolved++; return 2 }))
+	if resolved != 0 {
+		t.Errorf("expected attribute values not to be resolved when added")
+	}
+	logger.Info("message", "k", 3)
+	expected := `"a":2,"b":1,"g":{"c":2,"k":3}}`
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), expected) {
+		t.Errorf("expected output to end with %s, got %s", expected, buf.String())
+	}
+}
+
+func TestWriterHandlerDuplicateKeys(t *testing.T) {
+	var buf strings.Builder
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.DuplicateKeyMode = formatter.DuplicateKeyArray
+	opts.NestAttributes = false
+	opts.SortAttrs = false
+	logger := slog.New(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: formatter.NewJSONFormatter(opts),
+		Writer:          &buf,
+	})).With("tag", "a").WithGroup("g").With("x", 1)
+	logger.Info("message", "x", 2, "y", 3)
+	expected := `"tag":"a","g":{"x":[1,2],"y":3}}`
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), expected) {
+		t.Errorf("expected output to end with %s, got %s", expected, buf.String())
+	}
+}
+
+func BenchmarkWriterHandlerHandle(b *testing.B) {
+	h := handler.NewWriterHandler(handler.WriterHandlerOptions{
+		RecordFormatter: &groupStackFormatter{},
+		Writer:          io.Discard,
+	}).WithAttrs([]slog.Attr{slog.String("service", "api"), slog.Int("pid", 1234)})
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "request completed", 0)
+	r.AddAttrs(
+		slog.String("method", "GET"),
+		slog.String("path", "/api/v1/users"),
+		slog.Int("status", 200),
+		slog.Duration("elapsed", time.Millisecond),
+		slog.String("client", "127.0.0.1"),
+		slog.String("agent", "curl/8.0"),
+	)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = h.Handle(ctx, r)
+	}
+}
+
+func TestWriterHandlerName(t *testing.T) {
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.NestAttributes = false
+	f := formatter.NewJSONFormatter(opts)
+
+	outputs := map[string]*strings.Builder{"audit": {}, "debug": {}}
+	handlers := []slog.Handler{}
+	for name, buf := range outputs {
+		handlers = append(handlers, handler.NewWriterHandler(handler.WriterHandlerOptions{
+			Name:            name,
+			NameAttrKey:     "handler",
+			RecordFormatter: f,
+			Writer:          buf,
+		}))
+	}
+	logger := slog.New(handler.NewMultiHandler(handler.MultiHandlerOptions{}, handlers...))
+	logger.WithGroup("request").Info("message", "id", 1)
+
+	for name, buf := range outputs {
+		expected := `"handler":"` + name + `","request":{"id":1}}`
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %s in output: %s", expected, buf.String())
+		}
+	}
+
+	var buf strings.Builder
+	slog.New(handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Name:            "audit",
+		RecordFormatter: f,
+		Writer:          &buf,
+	})).Info("message")
+	if strings.Contains(buf.String(), "audit") {
+		t.Errorf("expected no name attribute without a key: %s", buf.String())
+	}
+}
@@ -0,0 +1,77 @@
+package slogx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// levelHTTPPayload is the JSON representation of the level used by LevelHTTPHandler.
+type levelHTTPPayload struct {
+	Level Level `json:"level"`
+}
+
+// LevelHTTPHandler returns an HTTP handler which can be used to retrieve or change the given level at runtime.
+//
+// A GET request returns the current level. PUT and POST requests parse the level from the request body using
+// ParseLevel and set it, returning the new level. If the request's Content-Type is application/json, the body is
+// expected to be a JSON object such as {"level":"DEBUG"}; otherwise the body is expected to contain just the name of
+// the level. Responses are returned in the same JSON format if the request's Accept header includes application/json
+// and as plain text otherwise.
+//
+// A 400 status is returned if the level is missing, empty or cannot be parsed and a 405 status is returned for any
+// other request method. The handler performs no authentication, so it should only be exposed on a trusted debug
+// endpoint, similar to net/http/pprof.
+func LevelHTTPHandler(lv *LevelVar) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeLevelHTTPResponse(w, r, lv.Level())
+		case http.MethodPut, http.MethodPost:
+			body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to read request body: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+			var level Level
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+				var payload struct {
+					Level *Level `json:"level"`
+				}
+				err = json.Unmarshal(body, &payload)
+				if err == nil && payload.Level == nil {
+					err = errors.New("missing level")
+				}
+				if err == nil {
+					level = *payload.Level
+				}
+			} else {
+				level, err = ParseLevel(strings.TrimSpace(string(body)))
+			}
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to parse level: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+			lv.Set(level)
+			writeLevelHTTPResponse(w, r, level)
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// writeLevelHTTPResponse writes the level to the response in the format requested by the client.
+func writeLevelHTTPResponse(w http.ResponseWriter, r *http.Request, level Level) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levelHTTPPayload{Level: level})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, level.String())
+}
@@ -0,0 +1,62 @@
+package slogx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.innotegrity.dev/slogx"
+)
+
+func TestLevelHTTPHandler(t *testing.T) {
+	lv := slogx.NewLevelVar(slogx.LevelInfo)
+	h := slogx.LevelHTTPHandler(lv)
+
+	tests := []struct {
+		method      string
+		body        string
+		contentType string
+		accept      string
+		status      int
+		response    string
+		level       slogx.Level
+	}{
+		{method: http.MethodGet, status: http.StatusOK, response: "INFO\n", level: slogx.LevelInfo},
+		{method: http.MethodPut, body: "debug", status: http.StatusOK, response: "DEBUG\n", level: slogx.LevelDebug},
+		{method: http.MethodPost, body: `{"level":"notice"}`, contentType: "application/json",
+			accept: "application/json", status: http.StatusOK, response: "{\"level\":\"NOTICE\"}\n",
+			level: slogx.LevelNotice},
+		{method: http.MethodPut, body: "loud", status: http.StatusBadRequest, level: slogx.LevelNotice},
+		{method: http.MethodPut, body: "", status: http.StatusBadRequest, level: slogx.LevelNotice},
+		{method: http.MethodPut, body: `{}`, contentType: "application/json", status: http.StatusBadRequest,
+			level: slogx.LevelNotice},
+		{method: http.MethodPost, body: `{"other":"debug"}`, contentType: "application/json",
+			status: http.StatusBadRequest, level: slogx.LevelNotice},
+		{method: http.MethodPost, body: `{"level":""}`, contentType: "application/json",
+			status: http.StatusBadRequest, level: slogx.LevelNotice},
+		{method: http.MethodPost, body: `{"level":null}`, contentType: "application/json",
+			status: http.StatusBadRequest, level: slogx.LevelNotice},
+		{method: http.MethodDelete, status: http.StatusMethodNotAllowed, level: slogx.LevelNotice},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/debug/level", strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("%s %q: expected status %d, got %d", test.method, test.body, test.status, rec.Code)
+		}
+		if test.response != "" && rec.Body.String() != test.response {
+			t.Errorf("%s %q: expected response %q, got %q", test.method, test.body, test.response, rec.Body.String())
+		}
+		if lv.Level() != test.level {
+			t.Errorf("%s %q: expected level %s, got %s", test.method, test.body, test.level, lv.Level())
+		}
+	}
+}
@@ -38,11 +38,49 @@ func ParseLevel(l string) (Level, error) {
 	return level, nil
 }
 
+// ParseLevelSet parses a comma-separated list of level strings (eg: "notice,error") into a set of levels.
+//
+// Whitespace around each level is ignored, as are empty entries. If any entry is not a valid level, an error naming
+// the entry is returned.
+func ParseLevelSet(s string) (map[Level]bool, error) {
+	set := map[Level]bool{}
+	for _, token := range strings.Split(s, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		level, err := ParseLevel(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level %q in set: %s", token, err.Error())
+		}
+		set[level] = true
+	}
+	return set, nil
+}
+
 // Level returns the level itself in order to implement the `Leveler` interface.
 func (l Level) Level() slog.Level {
 	return slog.Level(l)
 }
 
+// LevelReplaceAttr renders slogx levels using their proper names in standard library handlers.
+//
+// Pass this function as the ReplaceAttr field of slog.HandlerOptions when using slog.NewJSONHandler() or
+// slog.NewTextHandler() so that extended levels such as LevelNotice are written as "NOTICE" rather than "INFO+2".
+// All other attributes are returned unchanged.
+func LevelReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) != 0 || a.Key != slog.LevelKey {
+		return a
+	}
+	switch v := a.Value.Any().(type) {
+	case slog.Level:
+		a.Value = slog.StringValue(Level(v).String())
+	case Level:
+		a.Value = slog.StringValue(v.String())
+	}
+	return a
+}
+
 // MarshalJSON marshals the level into a JSON string.
 func (l Level) MarshalJSON() ([]byte, error) {
 	return strconv.AppendQuote(nil, l.String()), nil
@@ -195,7 +233,8 @@ func (l *Level) parse(s string) error {
 // It implements Leveler as well as a Set method, and it is safe for use by multiple goroutines. The zero LevelVar
 // corresponds to LevelInfo.
 type LevelVar struct {
-	val atomic.Int64
+	onChange atomic.Pointer[func(old, new Level)]
+	val      atomic.Int64
 }
 
 // NewLevelVar returns a new object with the given level set.
@@ -211,8 +250,26 @@ func (v *LevelVar) Level() Level {
 }
 
 // Set sets v's level to l.
+//
+// If a callback has been registered with SetOnChange and the level actually changes, the callback is invoked with the
+// old and new levels.
 func (v *LevelVar) Set(l Level) {
-	v.val.Store(int64(l))
+	old := Level(int(v.val.Swap(int64(l))))
+	if fn := v.onChange.Load(); fn != nil && old != l {
+		(*fn)(old, l)
+	}
+}
+
+// SetOnChange registers a callback to invoke whenever v's level is changed by Set.
+//
+// The callback is called synchronously from Set, so it should return quickly. Only one callback may be registered at
+// a time. Passing nil removes any registered callback.
+func (v *LevelVar) SetOnChange(fn func(old, new Level)) {
+	if fn == nil {
+		v.onChange.Store(nil)
+		return
+	}
+	v.onChange.Store(&fn)
 }
 
 func (v *LevelVar) String() string {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 
 	"log/slog"
@@ -33,3 +34,69 @@ func TestLevel(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.Level(-99)}))
 	logger.Log(context.TODO(), l.Level(), "this is a message")
 }
+
+func TestLevelReplaceAttr(t *testing.T) {
+	var output strings.Builder
+	logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{
+		Level:       slogx.LevelTrace,
+		ReplaceAttr: slogx.LevelReplaceAttr,
+	}))
+	levels := map[slogx.Level]string{
+		slogx.LevelTrace:  "level=TRACE",
+		slogx.LevelNotice: "level=NOTICE",
+		slogx.LevelFatal:  "level=FATAL",
+		slogx.LevelPanic:  "level=PANIC",
+	}
+	for level, expected := range levels {
+		output.Reset()
+		logger.Log(context.Background(), level.Level(), "message")
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected %q in output: %s", expected, output.String())
+		}
+	}
+}
+
+func TestLevelVarSetOnChange(t *testing.T) {
+	lv := slogx.NewLevelVar(slogx.LevelInfo)
+	changes := [][2]slogx.Level{}
+	lv.SetOnChange(func(old, new slogx.Level) {
+		changes = append(changes, [2]slogx.Level{old, new})
+	})
+	lv.Set(slogx.LevelDebug)
+	lv.Set(slogx.LevelDebug)
+	lv.Set(slogx.LevelTrace)
+	lv.SetOnChange(nil)
+	lv.Set(slogx.LevelError)
+
+	expected := [][2]slogx.Level{{slogx.LevelInfo, slogx.LevelDebug}, {slogx.LevelDebug, slogx.LevelTrace}}
+	if len(changes) != len(expected) {
+		t.Errorf("expected %d changes, got %d: %v", len(expected), len(changes), changes)
+		return
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("expected change %v, got %v", expected[i], changes[i])
+		}
+	}
+}
+
+func TestParseLevelSet(t *testing.T) {
+	set, err := slogx.ParseLevelSet("notice, ERROR,,fatal")
+	if err != nil {
+		t.Errorf("failed to parse level set: %s", err.Error())
+		return
+	}
+	expected := map[slogx.Level]bool{slogx.LevelNotice: true, slogx.LevelError: true, slogx.LevelFatal: true}
+	if len(set) != len(expected) {
+		t.Errorf("expected %d levels, got %d", len(expected), len(set))
+	}
+	for level := range expected {
+		if !set[level] {
+			t.Errorf("expected %s in set", level)
+		}
+	}
+
+	if _, err := slogx.ParseLevelSet("warn,bogus"); err == nil || !strings.Contains(err.Error(), `"bogus"`) {
+		t.Errorf("expected error naming the invalid level, got: %v", err)
+	}
+}
@@ -2,12 +2,115 @@ package slogx
 
 import (
 	"context"
+	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"log/slog"
+
+	"go.innotegrity.dev/runtimex"
+)
+
+const (
+	// RecoveredPanicKey is the name of the attribute holding the recovered value in records logged by RecoverAndLog.
+	RecoveredPanicKey = "panic"
+
+	// RecoveredStackKey is the name of the attribute holding the stack trace in records logged by RecoverAndLog.
+	RecoveredStackKey = "stack"
 )
 
+// ensure Logger implements the LoggingService interface
+var _ LoggingService = (*Logger)(nil)
+
+// exitFunc is the function called to exit the application after a FATAL message is logged.
+var exitFunc = os.Exit
+
+// SetExitFunc replaces the function called to exit the application after a FATAL message is logged when a logger's
+// FatalExitCode is set.
+//
+// This is primarily useful in tests to avoid actually exiting the application. If nil is supplied, os.Exit is used.
+func SetExitFunc(fn func(int)) {
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitFunc = fn
+}
+
+// loggerNameContextKey is used to store the name of the logger which created a record in a standard Go context object.
+type loggerNameContextKey struct{}
+
+// ContextWithLoggerName returns a new context with the given logger name stored in it.
+//
+// Named loggers add their name to the context passed to the handler so that handlers and formatters can retrieve it
+// using LoggerNameFromContext().
+func ContextWithLoggerName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, loggerNameContextKey{}, name)
+}
+
+// LoggerNameFromContext retrieves the name of the logger stored in the context, if it exists.
+//
+// If no name is stored in the context, an empty string is returned.
+func LoggerNameFromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(loggerNameContextKey{}).(string); ok {
+		return name
+	}
+	return ""
+}
+
+// NewRecord creates a new record just like slog.NewRecord() except that the program counter is captured from the call
+// stack rather than being supplied.
+//
+// skip is the number of additional stack frames to skip, so a skip of 0 uses the caller of NewRecord as the source of
+// the record. Helper functions which create records on behalf of their callers should pass 1.
+func NewRecord(t time.Time, level Level, msg string, skip int) slog.Record {
+	// skip this function
+	return slog.NewRecord(t, slog.Level(level), msg, callerPC(skip+1))
+}
+
+// RecoverAndLog recovers from a panic and logs it as a PANIC-level record using the given logger.
+//
+// It is meant to be deferred at the start of a goroutine:
+//
+//	go func() {
+//		defer slogx.RecoverAndLog(ctx, logger)
+//		...
+//	}()
+//
+// The recovered value and the stack trace of the goroutine are added to the record under the RecoveredPanicKey and
+// RecoveredStackKey attributes. If the logger's PanicOnPanicLevel field is true, the recovered value is re-panicked
+// once the record has been logged. The logger's handlers are left open so that they can still be used if the panic is
+// recovered further up the stack. If the logger is nil, the default logger is used.
+//
+// If the logger's IncludeFileLine field is true, the source of the record is the line which panicked rather than the
+// deferred call to RecoverAndLog. The runtime's own panic frames are skipped and AdjustFrameCount is ignored.
+func RecoverAndLog(ctx context.Context, l *Logger) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	if l == nil {
+		l = Default()
+	}
+
+	value := slog.Any(RecoveredPanicKey, rec)
+	if err, ok := rec.(error); ok {
+		value = Err(RecoveredPanicKey, err)
+	}
+	if l.Enabled(ctx, slog.Level(LevelPanic)) {
+		var pc uintptr
+		if l.IncludeFileLine {
+			pc = panicPC()
+		}
+		r := slog.NewRecord(l.now(), slog.Level(LevelPanic), "recovered from panic", pc)
+		r.AddAttrs(value, Stack(RecoveredStackKey))
+		_ = l.Handler().Handle(l.namedContext(ctx), r)
+	}
+	if l.PanicOnPanicLevel {
+		panic(rec)
+	}
+}
+
 // SetDefault replaces the default logger with the one supplied.
 func SetDefault(l *Logger) {
 	slog.SetDefault(l.Logger)
@@ -30,13 +133,49 @@ func Shutdown(l *Logger, continueOnError bool) error {
 type Logger struct {
 	*slog.Logger
 
-	// AdjustFrameCount indicates a number of frames to adjust the skip by when calling runtime.Callers. By default,
-	// 3 frames are skipped when creating the record.
+	// AdjustFrameCount indicates a number of frames to adjust the skip by when capturing the caller. By default, the
+	// caller of the logging method (eg: Info, LogAttrs, etc.) is used as the source of the record. Increase this when
+	// wrapping the logger in your own helper functions so that the caller of the helper is reported instead.
 	AdjustFrameCount int
 
+	// FatalExitCode is the exit code to use when exiting the application after a message is logged with Fatal() or
+	// FatalContext().
+	//
+	// By default, this is 0, which means the application does not exit and Fatal() behaves like any other logging
+	// function. If this is set to any other value, the application exits with that code once the message has been
+	// handled, even if the FATAL level is not enabled in the handler. The logger's handlers are shut down before
+	// exiting so that any pending messages are delivered.
+	FatalExitCode int
+
 	// IncludeFileLine indicates whether or not to invoke runtime.Callers to get the program counter in order to retrieve
 	// source file information.
 	IncludeFileLine bool
+
+	// Name is the name of the logger.
+	//
+	// If set, the name is added to the context passed to the handler when logging a message so that it can be
+	// retrieved using LoggerNameFromContext(). Use handler.LoggerNamePipe() to add it to each record as an attribute.
+	// It is also used as the default name when storing the logger in a context using ContextWithLoggingService() or
+	// ContextWithActiveLoggingService().
+	Name string
+
+	// PanicOnPanicLevel indicates whether or not to panic with the message after a message is logged with Panic() or
+	// PanicContext().
+	//
+	// By default, this is false, which means Panic() behaves like any other logging function. If this is true, the
+	// logger panics with the message once it has been handled, even if the PANIC level is not enabled in the handler.
+	// The logger's handlers are left open so that the logger, and any other logger sharing its handlers, can still be
+	// used if the panic is recovered.
+	PanicOnPanicLevel bool
+
+	// TimeFunc is the function to call to get the time of each record created by the logger.
+	//
+	// This allows tests to use a fixed clock or applications to force timestamps into a specific form at the source
+	// (eg: func() time.Time { return time.Now().UTC() }). If nil, time.Now() is used.
+	TimeFunc func() time.Time
+
+	// unexported variables
+	ctx context.Context
 }
 
 // Default returns the default logger object.
@@ -49,7 +188,7 @@ func Default() *Logger {
 // Nil returns a new "nil" logger which does not log anything, ever.
 func Nil() *Logger {
 	return &Logger{
-		Logger: slog.New(newNilHandler()),
+		Logger: slog.New(NewNilHandler()),
 	}
 }
 
@@ -62,7 +201,18 @@ func Wrap(l *slog.Logger) *Logger {
 
 // Debug logs a message using DEBUG level.
 func (l *Logger) Debug(msg string, args ...any) {
-	l.log(context.Background(), LevelDebug, msg, args...)
+	l.log(l.boundContext(), LevelDebug, msg, args...)
+}
+
+// DebugAttrs logs a message using DEBUG level with the attributes returned by fn.
+//
+// The function is only called if DEBUG level is enabled, so it can be used to build attributes which are expensive to
+// compute without paying that cost when debug logging is disabled.
+func (l *Logger) DebugAttrs(ctx context.Context, msg string, fn func() []slog.Attr) {
+	if !l.Enabled(ctx, slog.Level(LevelDebug)) {
+		return
+	}
+	l.logAttrs(ctx, LevelDebug, msg, fn()...)
 }
 
 // DebugContext logs a message using DEBUG level with context.
@@ -72,27 +222,36 @@ func (l *Logger) DebugContext(ctx context.Context, msg string, args ...any) {
 
 // Error logs a message using ERROR level.
 func (l *Logger) Error(msg string, args ...any) {
-	l.log(context.Background(), LevelError, msg, args...)
+	l.log(l.boundContext(), LevelError, msg, args...)
 }
 
 // ErrorContext logs a message using ERROR level with context.
+func (l *Logger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, LevelError, msg, args...)
+}
+
+// ErrorlContext logs a message using ERROR level with context.
+//
+// Deprecated: Use ErrorContext instead.
 func (l *Logger) ErrorlContext(ctx context.Context, msg string, args ...any) {
 	l.log(ctx, LevelError, msg, args...)
 }
 
 // Fatal logs a message using FATAL level.
 func (l *Logger) Fatal(msg string, args ...any) {
-	l.log(context.Background(), LevelFatal, msg, args...)
+	l.log(l.boundContext(), LevelFatal, msg, args...)
+	l.exitOnFatal()
 }
 
 // FatalContext logs a message using FATAL level with context.
 func (l *Logger) FatalContext(ctx context.Context, msg string, args ...any) {
 	l.log(ctx, LevelFatal, msg, args...)
+	l.exitOnFatal()
 }
 
 // Info logs a message using INFO level.
 func (l *Logger) Info(msg string, args ...any) {
-	l.log(context.Background(), LevelInfo, msg, args...)
+	l.log(l.boundContext(), LevelInfo, msg, args...)
 }
 
 // InfoContext logs a message using INFO level with context.
@@ -111,13 +270,19 @@ func (l *Logger) LogAttrs(ctx context.Context, level Level, msg string, attrs ..
 }
 
 // LogRecord simply logs the given pre-created record.
+//
+// If the record does not have a program counter set and IncludeFileLine is true, the caller of LogRecord is used as
+// the source of the record.
 func (l *Logger) LogRecord(ctx context.Context, r slog.Record) {
-	_ = l.Handler().Handle(ctx, r)
+	if r.PC == 0 {
+		r.PC = l.callerPC(1)
+	}
+	_ = l.Handler().Handle(l.namedContext(ctx), r)
 }
 
 // Notice logs a message using NOTICE level.
 func (l *Logger) Notice(msg string, args ...any) {
-	l.log(context.Background(), LevelNotice, msg, args...)
+	l.log(l.boundContext(), LevelNotice, msg, args...)
 }
 
 // NoticeContext logs a message using NOTICE level with context.
@@ -127,12 +292,14 @@ func (l *Logger) NoticeContext(ctx context.Context, msg string, args ...any) {
 
 // Panic logs a message using PANIC level.
 func (l *Logger) Panic(msg string, args ...any) {
-	l.log(context.Background(), LevelPanic, msg, args...)
+	l.log(l.boundContext(), LevelPanic, msg, args...)
+	l.panicOnPanic(msg)
 }
 
 // PanicContext logs a message using PANIC level with context.
 func (l *Logger) PanicContext(ctx context.Context, msg string, args ...any) {
 	l.log(ctx, LevelPanic, msg, args...)
+	l.panicOnPanic(msg)
 }
 
 // Shutdown will cleanup any open resources or pending goroutines being run in the handler(s) attached to the logger.
@@ -147,7 +314,18 @@ func (l *Logger) Shutdown(continueOnError bool) error {
 
 // Trace logs a message using TRACE level.
 func (l *Logger) Trace(msg string, args ...any) {
-	l.log(context.Background(), LevelTrace, msg, args...)
+	l.log(l.boundContext(), LevelTrace, msg, args...)
+}
+
+// TraceAttrs logs a message using TRACE level with the attributes returned by fn.
+//
+// The function is only called if TRACE level is enabled, so it can be used to build attributes which are expensive to
+// compute without paying that cost when trace logging is disabled.
+func (l *Logger) TraceAttrs(ctx context.Context, msg string, fn func() []slog.Attr) {
+	if !l.Enabled(ctx, slog.Level(LevelTrace)) {
+		return
+	}
+	l.logAttrs(ctx, LevelTrace, msg, fn()...)
 }
 
 // TraceContext logs a message using TRACE level with context.
@@ -157,7 +335,7 @@ func (l *Logger) TraceContext(ctx context.Context, msg string, args ...any) {
 
 // Warn logs a message using WARN level.
 func (l *Logger) Warn(msg string, args ...any) {
-	l.log(context.Background(), LevelWarn, msg, args...)
+	l.log(l.boundContext(), LevelWarn, msg, args...)
 }
 
 // WarnContext logs a message using WARN level with context.
@@ -168,30 +346,143 @@ func (l *Logger) WarnContext(ctx context.Context, msg string, args ...any) {
 // With returns a new logger with the given attributes.
 func (l *Logger) With(args ...any) *Logger {
 	return &Logger{
-		Logger:           l.Logger.With(args...),
-		AdjustFrameCount: l.AdjustFrameCount,
-		IncludeFileLine:  l.IncludeFileLine,
+		Logger:            l.Logger.With(args...),
+		AdjustFrameCount:  l.AdjustFrameCount,
+		FatalExitCode:     l.FatalExitCode,
+		IncludeFileLine:   l.IncludeFileLine,
+		Name:              l.Name,
+		PanicOnPanicLevel: l.PanicOnPanicLevel,
+		TimeFunc:          l.TimeFunc,
+		ctx:               l.ctx,
 	}
 }
 
-// log is the low-level logging method for methods that take ...any.
-func (l *Logger) log(ctx context.Context, level Level, msg string, args ...any) {
-	if !l.Enabled(ctx, slog.Level(level)) {
-		return
+// WithContext returns a new logger which uses the given context when logging messages.
+//
+// The bound context is passed to the handler by the functions which do not take a context, such as Info() or Error(),
+// instead of context.Background(). This allows context-based handlers to work without using the ...Context variants.
+// Calling any of the ...Context variants still uses the context passed to them instead of the bound context.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	return &Logger{
+		Logger:            l.Logger,
+		AdjustFrameCount:  l.AdjustFrameCount,
+		FatalExitCode:     l.FatalExitCode,
+		IncludeFileLine:   l.IncludeFileLine,
+		Name:              l.Name,
+		PanicOnPanicLevel: l.PanicOnPanicLevel,
+		TimeFunc:          l.TimeFunc,
+		ctx:               ctx,
 	}
-	var pc uintptr
-	if l.IncludeFileLine {
-		var pcs [1]uintptr
-		// skip runtime.Callers, this function, calling function
-		runtime.Callers(3+l.AdjustFrameCount, pcs[:])
-		pc = pcs[0]
+}
+
+// WithName returns a new logger with the given name.
+func (l *Logger) WithName(name string) *Logger {
+	return &Logger{
+		Logger:            l.Logger,
+		AdjustFrameCount:  l.AdjustFrameCount,
+		FatalExitCode:     l.FatalExitCode,
+		IncludeFileLine:   l.IncludeFileLine,
+		Name:              name,
+		PanicOnPanicLevel: l.PanicOnPanicLevel,
+		TimeFunc:          l.TimeFunc,
+		ctx:               l.ctx,
 	}
-	r := slog.NewRecord(time.Now(), slog.Level(level), msg, pc)
+}
+
+// WithReplace returns a new logger with the given attributes, replacing any attributes with the same key which were
+// previously added to the logger.
+//
+// With() simply appends the attributes to the handler, so adding the same key repeatedly grows the handler's list of
+// attributes until the duplicates are removed each time a record is handled. WithReplace() removes the duplicates
+// immediately instead so the list stays as small as possible. In both cases, records are handled the same way: the
+// last value added for a key is used at the position where the key was first added. Attribute values are not
+// resolved until a record is handled.
+//
+// If the logger's handler does not implement AttrReplacingHandler, this behaves exactly like With().
+func (l *Logger) WithReplace(args ...any) *Logger {
+	h, ok := l.Handler().(AttrReplacingHandler)
+	if !ok || len(args) == 0 {
+		return l.With(args...)
+	}
+	var r slog.Record
 	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	newLogger := l.WithContext(l.ctx)
+	newLogger.Logger = slog.New(h.WithReplacedAttrs(attrs))
+	return newLogger
+}
+
+// boundContext returns the context bound to the logger or context.Background() if there is none.
+func (l *Logger) boundContext() context.Context {
+	if l.ctx == nil {
+		return context.Background()
+	}
+	return l.ctx
+}
+
+// callerPC returns the program counter of the function skip frames above the function calling callerPC, adjusted by
+// AdjustFrameCount.
+//
+// A skip of 0 identifies the function calling callerPC itself. If IncludeFileLine is false, 0 is returned.
+func (l *Logger) callerPC(skip int) uintptr {
+	if !l.IncludeFileLine {
+		return 0
+	}
+	// skip this function
+	return callerPC(skip + 1 + l.AdjustFrameCount)
+}
+
+// exitOnFatal shuts down the logger's handlers and exits the application with FatalExitCode if it is set.
+func (l *Logger) exitOnFatal() {
+	if l.FatalExitCode != 0 {
+		_ = l.Shutdown(true)
+		exitFunc(l.FatalExitCode)
+	}
+}
+
+// namedContext returns the given context with the logger's name added to it if the logger has a name.
+//
+// If the context is nil, context.Background() is used instead.
+func (l *Logger) namedContext(ctx context.Context) context.Context {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	_ = l.Handler().Handle(ctx, r)
+	if l.Name != "" {
+		ctx = ContextWithLoggerName(ctx, l.Name)
+	}
+	return ctx
+}
+
+// now returns the time to use for a new record.
+func (l *Logger) now() time.Time {
+	if l.TimeFunc != nil {
+		return l.TimeFunc()
+	}
+	return time.Now()
+}
+
+// panicOnPanic panics with the given message if PanicOnPanicLevel is set.
+//
+// Unlike exitOnFatal, the logger's handlers are not shut down since the panic may be recovered.
+func (l *Logger) panicOnPanic(msg string) {
+	if l.PanicOnPanicLevel {
+		panic(msg)
+	}
+}
+
+// log is the low-level logging method for methods that take ...any.
+func (l *Logger) log(ctx context.Context, level Level, msg string, args ...any) {
+	if !l.Enabled(ctx, slog.Level(level)) {
+		return
+	}
+	// skip this function and the exported logging method which called it
+	r := slog.NewRecord(l.now(), slog.Level(level), msg, l.callerPC(2))
+	r.Add(args...)
+	_ = l.Handler().Handle(l.namedContext(ctx), r)
 }
 
 // logAttrs is like [Logger.log], but for methods that take ...Attr.
@@ -199,17 +490,35 @@ func (l *Logger) logAttrs(ctx context.Context, level Level, msg string, attrs ..
 	if !l.Enabled(ctx, slog.Level(level)) {
 		return
 	}
-	var pc uintptr
-	if l.IncludeFileLine {
-		var pcs [1]uintptr
-		// skip runtime.Callers, this function, calling function
-		runtime.Callers(3+l.AdjustFrameCount, pcs[:])
-		pc = pcs[0]
-	}
-	r := slog.NewRecord(time.Now(), slog.Level(level), msg, pc)
+	// skip this function and the exported logging method which called it
+	r := slog.NewRecord(l.now(), slog.Level(level), msg, l.callerPC(2))
 	r.AddAttrs(attrs...)
-	if ctx == nil {
-		ctx = context.Background()
+	_ = l.Handler().Handle(l.namedContext(ctx), r)
+}
+
+// panicPC returns the program counter of the line which panicked when called from a function deferred by it.
+//
+// The frames of the runtime's panic machinery sitting between the deferred function and the panicking function are
+// skipped. 0 is returned if no such frame can be found.
+func panicPC() uintptr {
+	var pcs [32]uintptr
+	// skip runtime.Callers, this function and the deferred function calling it
+	n := runtime.Callers(3, pcs[:])
+	for _, pc := range pcs[:n] {
+		frame := runtimex.FrameFromPC(pc)
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			return pc
+		}
 	}
-	_ = l.Handler().Handle(ctx, r)
+	return 0
+}
+
+// callerPC returns the program counter of the function skip frames above the function calling callerPC.
+//
+// A skip of 0 identifies the function calling callerPC itself.
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	// skip runtime.Callers and this function
+	runtime.Callers(skip+2, pcs[:])
+	return pcs[0]
 }
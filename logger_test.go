@@ -1,5 +1,407 @@
 package slogx_test
 
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+// shutdownRecorder is a handler which records when it has been shut down.
+type shutdownRecorder struct {
+	slog.Handler
+	shutdown bool
+}
+
+func (h *shutdownRecorder) Shutdown(continueOnError bool) error {
+	h.shutdown = true
+	return nil
+}
+
+// replaceRecorder is a handler which records the attributes passed to WithReplacedAttrs().
+type replaceRecorder struct {
+	slog.Handler
+	replaced []slog.Attr
+}
+
+func (h *replaceRecorder) WithReplacedAttrs(attrs []slog.Attr) slog.Handler {
+	h.replaced = attrs
+	return h
+}
+
+// contextKey is used for storing test values in a context.
+type contextKey struct{}
+
+// contextRecorder is a handler which records the value stored in the context for each record.
+type contextRecorder struct {
+	slog.Handler
+	values []any
+}
+
+func (h *contextRecorder) Handle(ctx context.Context, r slog.Record) error {
+	h.values = append(h.values, ctx.Value(contextKey{}))
+	return nil
+}
+
+func TestLoggerWithContext(t *testing.T) {
+	h := &contextRecorder{Handler: slog.NewTextHandler(&strings.Builder{}, nil)}
+	logger := slogx.Wrap(slog.New(h))
+	bound := logger.WithContext(context.WithValue(context.Background(), contextKey{}, "bound"))
+
+	logger.Info("no context")
+	bound.Info("bound context")
+	bound.Warn("bound context again")
+	bound.InfoContext(context.WithValue(context.Background(), contextKey{}, "explicit"), "explicit context")
+
+	expected := []any{nil, "bound", "bound", "explicit"}
+	if len(h.values) != len(expected) {
+		t.Errorf("expected %d records, got %d", len(expected), len(h.values))
+		return
+	}
+	for i := range expected {
+		if h.values[i] != expected[i] {
+			t.Errorf("expected context value %v, got %v", expected[i], h.values[i])
+		}
+	}
+}
+
+// nameRecorder is a handler which records the logger name stored in the context for each record.
+type nameRecorder struct {
+	slog.Handler
+	names []string
+}
+
+func (h *nameRecorder) Handle(ctx context.Context, r slog.Record) error {
+	h.names = append(h.names, slogx.LoggerNameFromContext(ctx))
+	return nil
+}
+
+func TestLoggerWithName(t *testing.T) {
+	h := &nameRecorder{Handler: slog.NewTextHandler(&strings.Builder{}, nil)}
+	logger := slogx.Wrap(slog.New(h))
+	named := logger.WithName("audit")
+
+	logger.Info("unnamed")
+	named.Info("named")
+	named.WithContext(context.Background()).Info("named with bound context")
+
+	expected := []string{"", "audit", "audit"}
+	if len(h.names) != len(expected) {
+		t.Errorf("expected %d records, got %d", len(expected), len(h.names))
+		return
+	}
+	for i := range expected {
+		if h.names[i] != expected[i] {
+			t.Errorf("expected logger name %q, got %q", expected[i], h.names[i])
+		}
+	}
+
+	ctx := slogx.ContextWithActiveLoggingService(context.Background(), named, "")
+	if name := slogx.ActiveLoggingServiceNameFromContext(ctx); name != "audit" {
+		t.Errorf("expected active logging service name audit, got %s", name)
+	}
+	if s := slogx.LoggingServiceFromContext(ctx, "audit"); s != named {
+		t.Errorf("expected named logger to be stored under its name")
+	}
+}
+
+// pcRecorder is a handler which records the program counter of the last record.
+type pcRecorder struct {
+	slog.Handler
+	pc uintptr
+}
+
+func (h *pcRecorder) Handle(ctx context.Context, r slog.Record) error {
+	h.pc = r.PC
+	return nil
+}
+
+// currentLine returns the line number of the caller.
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestLoggerCallerSource(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	noAttrs := func() []slog.Attr { return nil }
+	recorder := &pcRecorder{Handler: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slogx.LevelTrace,
+	})}
+	logger := slogx.Wrap(slog.New(recorder))
+	logger.IncludeFileLine = true
+
+	tests := map[string]func() int{
+		"Debug":         func() int { logger.Debug("msg"); return currentLine() },
+		"DebugAttrs":    func() int { logger.DebugAttrs(ctx, "msg", noAttrs); return currentLine() },
+		"DebugContext":  func() int { logger.DebugContext(ctx, "msg"); return currentLine() },
+		"Error":         func() int { logger.Error("msg"); return currentLine() },
+		"ErrorContext":  func() int { logger.ErrorContext(ctx, "msg"); return currentLine() },
+		"Fatal":         func() int { logger.Fatal("msg"); return currentLine() },
+		"FatalContext":  func() int { logger.FatalContext(ctx, "msg"); return currentLine() },
+		"Info":          func() int { logger.Info("msg"); return currentLine() },
+		"InfoContext":   func() int { logger.InfoContext(ctx, "msg"); return currentLine() },
+		"Log":           func() int { logger.Log(ctx, slogx.LevelInfo, "msg"); return currentLine() },
+		"LogAttrs":      func() int { logger.LogAttrs(ctx, slogx.LevelInfo, "msg"); return currentLine() },
+		"LogRecord":     func() int { logger.LogRecord(ctx, slog.Record{Message: "msg"}); return currentLine() },
+		"NewRecord":     func() int { logger.LogRecord(ctx, slogx.NewRecord(now, 0, "msg", 0)); return currentLine() },
+		"Notice":        func() int { logger.Notice("msg"); return currentLine() },
+		"NoticeContext": func() int { logger.NoticeContext(ctx, "msg"); return currentLine() },
+		"Panic":         func() int { logger.Panic("msg"); return currentLine() },
+		"PanicContext":  func() int { logger.PanicContext(ctx, "msg"); return currentLine() },
+		"Trace":         func() int { logger.Trace("msg"); return currentLine() },
+		"TraceAttrs":    func() int { logger.TraceAttrs(ctx, "msg", noAttrs); return currentLine() },
+		"TraceContext":  func() int { logger.TraceContext(ctx, "msg"); return currentLine() },
+		"Warn":          func() int { logger.Warn("msg"); return currentLine() },
+		"WarnContext":   func() int { logger.WarnContext(ctx, "msg"); return currentLine() },
+	}
+	for name, fn := range tests {
+		recorder.pc = 0
+		line := fn()
+		frame, _ := runtime.CallersFrames([]uintptr{recorder.pc}).Next()
+		if !strings.HasSuffix(frame.File, "logger_test.go") || frame.Line != line {
+			t.Errorf("%s: expected source logger_test.go:%d, got %s:%d", name, line, frame.File, frame.Line)
+		}
+	}
+}
+
+// logHelper logs a message on behalf of its caller.
+func logHelper(logger *slogx.Logger, msg string) {
+	logger.LogAttrs(context.Background(), slogx.LevelInfo, msg)
+}
+
+func TestLoggerAdjustFrameCount(t *testing.T) {
+	recorder := &pcRecorder{Handler: slog.NewTextHandler(io.Discard, nil)}
+	logger := slogx.Wrap(slog.New(recorder))
+	logger.IncludeFileLine = true
+	logger.AdjustFrameCount = 1
+
+	logHelper(logger, "msg")
+	line := currentLine() - 1
+	frame, _ := runtime.CallersFrames([]uintptr{recorder.pc}).Next()
+	if !strings.HasSuffix(frame.File, "logger_test.go") || frame.Line != line {
+		t.Errorf("expected source logger_test.go:%d, got %s:%d", line, frame.File, frame.Line)
+	}
+}
+
+// attrsRecorder is a handler which records the attributes of the last record.
+type attrsRecorder struct {
+	slog.Handler
+	attrs []slog.Attr
+}
+
+func (h *attrsRecorder) Handle(ctx context.Context, r slog.Record) error {
+	h.attrs = nil
+	r.Attrs(func(attr slog.Attr) bool {
+		h.attrs = append(h.attrs, attr)
+		return true
+	})
+	return nil
+}
+
+func TestLoggerTraceAttrs(t *testing.T) {
+	ctx := context.Background()
+	level := &slog.LevelVar{}
+	recorder := &attrsRecorder{Handler: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: level})}
+	logger := slogx.Wrap(slog.New(recorder))
+
+	calls := 0
+	fn := func() []slog.Attr {
+		calls++
+		return []slog.Attr{slog.Int("expensive", 42)}
+	}
+	logger.TraceAttrs(ctx, "trace", fn)
+	logger.DebugAttrs(ctx, "debug", fn)
+	if calls != 0 {
+		t.Errorf("expected attribute function not to be called while disabled, called %d time(s)", calls)
+		return
+	}
+
+	level.Set(slog.Level(slogx.LevelTrace))
+	logger.TraceAttrs(ctx, "trace", fn)
+	if calls != 1 || len(recorder.attrs) != 1 || recorder.attrs[0].Key != "expensive" {
+		t.Errorf("expected attributes to be logged once enabled, got %v", recorder.attrs)
+	}
+}
+
+func TestLoggerFatalExitCode(t *testing.T) {
+	exitCode := 0
+	slogx.SetExitFunc(func(code int) { exitCode = code })
+	defer slogx.SetExitFunc(nil)
+
+	h := &shutdownRecorder{Handler: slog.NewTextHandler(&strings.Builder{}, nil)}
+	logger := slogx.Wrap(slog.New(h))
+	logger.Fatal("not exiting")
+	if exitCode != 0 || h.shutdown {
+		t.Errorf("expected logger not to exit when FatalExitCode is not set")
+		return
+	}
+
+	logger.FatalExitCode = 2
+	logger.Fatal("exiting")
+	if exitCode != 2 {
+		t.Errorf("expected exit code 2, got %d", exitCode)
+	}
+	if !h.shutdown {
+		t.Errorf("expected handler to be shut down before exiting")
+	}
+}
+
+func TestLoggerPanicOnPanicLevel(t *testing.T) {
+	var output strings.Builder
+	h := &shutdownRecorder{Handler: slog.NewTextHandler(&output, nil)}
+	logger := slogx.Wrap(slog.New(h))
+	logger.Panic("not panicking")
+
+	logger.PanicOnPanicLevel = true
+	func() {
+		defer func() {
+			if rec := recover(); rec != "panicking" {
+				t.Errorf("expected panic with message, got: %v", rec)
+			}
+		}()
+		logger.Panic("panicking")
+		t.Errorf("expected logger to panic")
+	}()
+	if count := strings.Count(output.String(), "msg=panicking"); count != 1 {
+		t.Errorf("expected message to be logged once before panicking, got %d: %s", count, output.String())
+		return
+	}
+
+	// the handlers must still be usable once the panic has been recovered
+	if h.shutdown {
+		t.Errorf("expected handler not to be shut down after panicking")
+		return
+	}
+	logger.Info("recovered")
+	if !strings.Contains(output.String(), "msg=recovered") {
+		t.Errorf("expected message to be logged after recovering, got: %s", output.String())
+	}
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	ctx := context.Background()
+	recorder := &attrsRecorder{Handler: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slogx.LevelTrace,
+	})}
+	logger := slogx.Wrap(slog.New(recorder))
+	func() {
+		defer slogx.RecoverAndLog(ctx, logger)
+		panic(errors.New("boom"))
+	}()
+	values := slogx.ToAttrMap(recorder.attrs)
+	if values[slogx.RecoveredPanicKey].String() != "boom" {
+		t.Errorf("expected recovered value boom, got %s", values[slogx.RecoveredPanicKey].String())
+	}
+	if stack := values[slogx.RecoveredStackKey].String(); !strings.Contains(stack, "TestRecoverAndLog") {
+		t.Errorf("expected stack trace to contain TestRecoverAndLog, got %s", stack)
+	}
+
+	shutdown := &shutdownRecorder{Handler: recorder}
+	logger = slogx.Wrap(slog.New(shutdown))
+	logger.PanicOnPanicLevel = true
+	func() {
+		defer func() {
+			if rec := recover(); rec != "again" {
+				t.Errorf("expected re-panic with original value, got: %v", rec)
+			}
+		}()
+		func() {
+			defer slogx.RecoverAndLog(ctx, logger)
+			panic("again")
+		}()
+		t.Errorf("expected RecoverAndLog to re-panic")
+	}()
+	if shutdown.shutdown {
+		t.Errorf("expected handler not to be shut down after re-panicking")
+	}
+}
+
+func TestRecoverAndLogSource(t *testing.T) {
+	recorder := &pcRecorder{Handler: slog.NewTextHandler(io.Discard, nil)}
+	logger := slogx.Wrap(slog.New(recorder))
+	logger.IncludeFileLine = true
+
+	var line int
+	var values []int
+	tests := map[string]func(){
+		"panic": func() {
+			line = currentLine() + 1
+			panic("boom")
+		},
+		"runtime error": func() {
+			line = currentLine() + 1
+			_ = values[len(values)]
+		},
+	}
+	for name, fn := range tests {
+		func() {
+			defer slogx.RecoverAndLog(context.Background(), logger)
+			fn()
+		}()
+		frame, _ := runtime.CallersFrames([]uintptr{recorder.pc}).Next()
+		if !strings.HasSuffix(frame.File, "logger_test.go") || frame.Line != line {
+			t.Errorf("%s: expected source logger_test.go:%d, got %s:%d", name, line, frame.File, frame.Line)
+		}
+	}
+}
+
+func TestLoggerWithReplace(t *testing.T) {
+	var output strings.Builder
+	recorder := &replaceRecorder{Handler: slog.NewTextHandler(&output, nil)}
+	logger := slogx.Wrap(slog.New(recorder)).WithName("replacer")
+	replaced := logger.WithReplace("a", 1, slog.String("b", "2"))
+	if len(recorder.replaced) != 2 || recorder.replaced[0].Key != "a" || recorder.replaced[1].Key != "b" {
+		t.Errorf("expected attributes a and b to be replaced, got %v", recorder.replaced)
+		return
+	}
+	if replaced.Name != "replacer" {
+		t.Errorf("expected logger settings to be kept, got name %q", replaced.Name)
+	}
+
+	// handlers which cannot replace attributes fall back to With()
+	output.Reset()
+	slogx.Wrap(slog.New(slog.NewTextHandler(&output, nil))).WithReplace("a", 1).WithReplace("a", 2).Info("message")
+	if !strings.Contains(output.String(), "a=1 a=2") {
+		t.Errorf("expected attributes to be appended, got %s", output.String())
+	}
+}
+
+// timeRecorder is a handler which records the time of each record.
+type timeRecorder struct {
+	slog.Handler
+	times []time.Time
+}
+
+func (h *timeRecorder) Handle(ctx context.Context, r slog.Record) error {
+	h.times = append(h.times, r.Time)
+	return nil
+}
+
+func (h *timeRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h
+}
+
+func TestLoggerTimeFunc(t *testing.T) {
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	recorder := &timeRecorder{Handler: slog.NewTextHandler(io.Discard, nil)}
+	logger := slogx.Wrap(slog.New(recorder))
+	logger.TimeFunc = func() time.Time { return fixed }
+	logger.With("key", "value").Info("message")
+	logger.LogAttrs(context.Background(), slogx.LevelInfo, "attrs")
+	if len(recorder.times) != 2 || !recorder.times[0].Equal(fixed) || !recorder.times[1].Equal(fixed) {
+		t.Errorf("expected records at %s, got %v", fixed, recorder.times)
+	}
+}
+
 // TODO: implement testing and benchmarks
 /*
 func BenchmarkSimple(b *testing.B) {
@@ -6,11 +6,16 @@ import (
 )
 
 // nilHandler simply discards all messages.
+//
+// The handler holds no state, so it is used by value and chaining it using WithAttrs() or WithGroup() returns the
+// same handler without allocating.
 type nilHandler struct{}
 
-// newNilHandler creates a new handler object.
-func newNilHandler() *nilHandler {
-	return &nilHandler{}
+// NewNilHandler creates a new handler object which discards all messages.
+//
+// This is the handler used by loggers returned by [Nil].
+func NewNilHandler() nilHandler {
+	return nilHandler{}
 }
 
 // Enabled determines whether or not the given level is enabled for the handler.
@@ -23,20 +28,20 @@ func (h nilHandler) Enabled(ctx context.Context, l slog.Level) bool {
 // Handle is responsible for writing the record to each and every handler.
 //
 // This function simply discards the record.
-func (h *nilHandler) Handle(ctx context.Context, r slog.Record) error {
+func (h nilHandler) Handle(ctx context.Context, r slog.Record) error {
 	return nil
 }
 
 // WithAttrs creates a new handler from the existing one adding the given attributes to it.
 //
-// This function always just returns a new, default NilHandler object.
+// This function always just returns the existing handler since it discards the attributes anyway.
 func (h nilHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return newNilHandler()
+	return h
 }
 
 // WithGroup creates a new handler from the existing one adding the given group to it.
 //
-// This function always just returns a new, default NilHandler object.
+// This function always just returns the existing handler since it discards the group anyway.
 func (h nilHandler) WithGroup(name string) slog.Handler {
-	return newNilHandler()
+	return h
 }
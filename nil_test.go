@@ -0,0 +1,41 @@
+package slogx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+func TestNilLoggerChaining(t *testing.T) {
+	var nilHandler slog.Handler = slogx.NewNilHandler()
+	logger := slogx.Nil().With("key", "value").WithGroup("group")
+	h := logger.Handler()
+	if h != nilHandler {
+		t.Errorf("expected chained logger to use the nil handler, got %#v", h)
+		return
+	}
+
+	ctx := context.Background()
+	for _, level := range []slogx.Level{slogx.LevelTrace, slogx.LevelInfo, slogx.LevelError, slogx.LevelPanic} {
+		if h.Enabled(ctx, slog.Level(level)) {
+			t.Errorf("expected level %s to be disabled", level)
+			return
+		}
+	}
+	if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "message", 0)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+
+	attrs := []slog.Attr{slog.String("key", "value")}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = h.WithAttrs(attrs).WithGroup("group")
+	})
+	if allocs != 0 {
+		t.Errorf("expected chaining the nil handler not to allocate, got %v allocations", allocs)
+	}
+}
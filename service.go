@@ -100,11 +100,10 @@ func ActiveLoggingServiceNameFromContext(ctx context.Context) string {
 // ActiveLoggingServiceFromContext() to retrieve the appropriate logging service to use for logging debug,
 // error, etc. messages within their code.
 //
-// If no name is supplied, the default logging service name is used.
+// If no name is supplied, the name of the logging service is used if it is a named *Logger or the default logging
+// service name is used otherwise.
 func ContextWithActiveLoggingService(ctx context.Context, s LoggingService, name string) context.Context {
-	if name == "" {
-		name = DefaultLoggingServiceName
-	}
+	name = loggingServiceName(s, name)
 	return ContextWithActiveLoggingServiceName(ContextWithLoggingService(ctx, s, name), name)
 }
 
@@ -134,14 +133,27 @@ type loggingServiceContextKey struct {
 // ContextWithLoggingService copies the given context and returns a new context with the given logging service
 // stored in it with the given name.
 //
-// If no name is supplied, the default logging service name is used.
+// If no name is supplied, the name of the logging service is used if it is a named *Logger or the default logging
+// service name is used otherwise.
 func ContextWithLoggingService(ctx context.Context, s LoggingService, name string) context.Context {
-	if name == "" {
-		name = DefaultLoggingServiceName
-	}
+	name = loggingServiceName(s, name)
 	return context.WithValue(ctx, loggingServiceContextKey{name: name}, s)
 }
 
+// loggingServiceName returns the name to use when storing the logging service in a context.
+//
+// If name is empty, the name of the logging service is returned if it is a named *Logger. Otherwise the default
+// logging service name is returned.
+func loggingServiceName(s LoggingService, name string) string {
+	if name != "" {
+		return name
+	}
+	if l, ok := s.(*Logger); ok && l != nil && l.Name != "" {
+		return l.Name
+	}
+	return DefaultLoggingServiceName
+}
+
 // LoggingServiceFromContext retrieves the logging service object stored in the given context with the given name,
 // if it exists.
 //
@@ -158,3 +170,115 @@ func LoggingServiceFromContext(ctx context.Context, name string) LoggingService
 	}
 	return nil
 }
+
+// Debug logs a message using DEBUG level with the active logging service in the context.
+//
+// If no active logging service is stored in the context, the default logger is used instead.
+func Debug(ctx context.Context, msg string, args ...any) {
+	s := activeLoggingService(ctx)
+	if l, ok := s.(*Logger); ok {
+		l.log(ctx, LevelDebug, msg, args...)
+		return
+	}
+	s.DebugContext(ctx, msg, args...)
+}
+
+// Error logs a message using ERROR level with the active logging service in the context.
+//
+// If no active logging service is stored in the context, the default logger is used instead.
+func Error(ctx context.Context, msg string, args ...any) {
+	s := activeLoggingService(ctx)
+	if l, ok := s.(*Logger); ok {
+		l.log(ctx, LevelError, msg, args...)
+		return
+	}
+	s.ErrorContext(ctx, msg, args...)
+}
+
+// Fatal logs a message using FATAL level with the active logging service in the context.
+//
+// If no active logging service is stored in the context, the default logger is used instead.
+func Fatal(ctx context.Context, msg string, args ...any) {
+	s := activeLoggingService(ctx)
+	if l, ok := s.(*Logger); ok {
+		l.log(ctx, LevelFatal, msg, args...)
+		l.exitOnFatal()
+		return
+	}
+	s.FatalContext(ctx, msg, args...)
+}
+
+// Info logs a message using INFO level with the active logging service in the context.
+//
+// If no active logging service is stored in the context, the default logger is used instead.
+func Info(ctx context.Context, msg string, args ...any) {
+	s := activeLoggingService(ctx)
+	if l, ok := s.(*Logger); ok {
+		l.log(ctx, LevelInfo, msg, args...)
+		return
+	}
+	s.InfoContext(ctx, msg, args...)
+}
+
+// Notice logs a message using NOTICE level with the active logging service in the context.
+//
+// If no active logging service is stored in the context, the default logger is used instead.
+func Notice(ctx context.Context, msg string, args ...any) {
+	s := activeLoggingService(ctx)
+	if l, ok := s.(*Logger); ok {
+		l.log(ctx, LevelNotice, msg, args...)
+		return
+	}
+	s.NoticeContext(ctx, msg, args...)
+}
+
+// Panic logs a message using PANIC level with the active logging service in the context.
+//
+// If no active logging service is stored in the context, the default logger is used instead.
+func Panic(ctx context.Context, msg string, args ...any) {
+	s := activeLoggingService(ctx)
+	if l, ok := s.(*Logger); ok {
+		l.log(ctx, LevelPanic, msg, args...)
+		l.panicOnPanic(msg)
+		return
+	}
+	s.PanicContext(ctx, msg, args...)
+}
+
+// Trace logs a message using TRACE level with the active logging service in the context.
+//
+// If no active logging service is stored in the context, the default logger is used instead.
+func Trace(ctx context.Context, msg string, args ...any) {
+	s := activeLoggingService(ctx)
+	if l, ok := s.(*Logger); ok {
+		l.log(ctx, LevelTrace, msg, args...)
+		return
+	}
+	s.TraceContext(ctx, msg, args...)
+}
+
+// Warn logs a message using WARN level with the active logging service in the context.
+//
+// If no active logging service is stored in the context, the default logger is used instead.
+func Warn(ctx context.Context, msg string, args ...any) {
+	s := activeLoggingService(ctx)
+	if l, ok := s.(*Logger); ok {
+		l.log(ctx, LevelWarn, msg, args...)
+		return
+	}
+	s.WarnContext(ctx, msg, args...)
+}
+
+// activeLoggingService returns the active logging service from the context or the default logger if there is none.
+//
+// The package-level logging functions call Logger.log directly when the returned service is a *Logger so that the
+// caller of the logging function, rather than the logging function itself, is used as the source of the record.
+func activeLoggingService(ctx context.Context) LoggingService {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if s := ActiveLoggingServiceFromContext(ctx); s != nil {
+		return s
+	}
+	return Default()
+}
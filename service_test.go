@@ -0,0 +1,72 @@
+package slogx_test
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+)
+
+func TestActiveLoggingServiceHelpers(t *testing.T) {
+	var output strings.Builder
+	logger := slogx.Wrap(slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{
+		Level:       slogx.LevelTrace,
+		ReplaceAttr: slogx.LevelReplaceAttr,
+	})))
+	ctx := slogx.ContextWithActiveLoggingService(context.Background(), logger, "")
+
+	slogx.Trace(ctx, "trace message")
+	slogx.Notice(ctx, "notice message", slog.String("key", "value"))
+	slogx.Error(ctx, "error message")
+	for _, expected := range []string{
+		`level=TRACE msg="trace message"`,
+		`level=NOTICE msg="notice message" key=value`,
+		`level=ERROR msg="error message"`,
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected %q in output: %s", expected, output.String())
+		}
+	}
+}
+
+func TestActiveLoggingServiceHelpersCallerSource(t *testing.T) {
+	recorder := &pcRecorder{Handler: slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		Level: slogx.LevelTrace,
+	})}
+	logger := slogx.Wrap(slog.New(recorder))
+	logger.IncludeFileLine = true
+	ctx := slogx.ContextWithActiveLoggingService(context.Background(), logger, "")
+
+	tests := map[string]func() int{
+		"Debug":  func() int { slogx.Debug(ctx, "msg"); return currentLine() },
+		"Error":  func() int { slogx.Error(ctx, "msg"); return currentLine() },
+		"Fatal":  func() int { slogx.Fatal(ctx, "msg"); return currentLine() },
+		"Info":   func() int { slogx.Info(ctx, "msg"); return currentLine() },
+		"Notice": func() int { slogx.Notice(ctx, "msg"); return currentLine() },
+		"Panic":  func() int { slogx.Panic(ctx, "msg"); return currentLine() },
+		"Trace":  func() int { slogx.Trace(ctx, "msg"); return currentLine() },
+		"Warn":   func() int { slogx.Warn(ctx, "msg"); return currentLine() },
+	}
+	for name, fn := range tests {
+		recorder.pc = 0
+		line := fn()
+		frame, _ := runtime.CallersFrames([]uintptr{recorder.pc}).Next()
+		if !strings.HasSuffix(frame.File, "service_test.go") || frame.Line != line {
+			t.Errorf("%s: expected source service_test.go:%d, got %s:%d", name, line, frame.File, frame.Line)
+		}
+	}
+}
+
+func TestLoggerAsLoggingService(t *testing.T) {
+	logger := slogx.Nil()
+	ctx := slogx.ContextWithLoggingService(context.Background(), logger, "nil")
+	s := slogx.LoggingServiceFromContext(ctx, "nil")
+	if l, ok := s.(*slogx.Logger); !ok || l != logger {
+		t.Errorf("expected logger to be retrieved as a logging service, got: %#v", s)
+	}
+}
@@ -0,0 +1,77 @@
+package slogx
+
+import (
+	"os"
+	"os/signal"
+)
+
+// namedLevels holds the named levels, in order, which InstallLevelSignalHandler steps through.
+var namedLevels = []Level{
+	LevelTrace,
+	LevelDebug,
+	LevelInfo,
+	LevelNotice,
+	LevelWarn,
+	LevelError,
+	LevelFatal,
+	LevelPanic,
+}
+
+// InstallLevelSignalHandler listens for the given signals and steps the level up or down through the named levels
+// (TRACE through PANIC) each time one is received.
+//
+// Receiving sigUp moves the level to the next higher named level, reducing the verbosity of the logs, while receiving
+// sigDown moves the level to the next lower named level, increasing the verbosity of the logs. The level is clamped at
+// LevelTrace and LevelPanic. If the current level falls between named levels, it moves to the nearest named level in
+// the appropriate direction.
+//
+// A typical setup on Unix systems would be to use syscall.SIGUSR2 for sigUp and syscall.SIGUSR1 for sigDown. The
+// returned function stops listening for the signals and should be called when the handler is no longer needed.
+func InstallLevelSignalHandler(lv *LevelVar, sigUp, sigDown os.Signal) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, sigUp, sigDown)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				switch sig {
+				case sigUp:
+					lv.Set(nextNamedLevel(lv.Level()))
+				case sigDown:
+					lv.Set(previousNamedLevel(lv.Level()))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// nextNamedLevel returns the lowest named level which is higher than the given level.
+//
+// If there is no higher named level, LevelPanic is returned.
+func nextNamedLevel(l Level) Level {
+	for _, level := range namedLevels {
+		if level > l {
+			return level
+		}
+	}
+	return LevelPanic
+}
+
+// previousNamedLevel returns the highest named level which is lower than the given level.
+//
+// If there is no lower named level, LevelTrace is returned.
+func previousNamedLevel(l Level) Level {
+	for i := len(namedLevels) - 1; i >= 0; i-- {
+		if namedLevels[i] < l {
+			return namedLevels[i]
+		}
+	}
+	return LevelTrace
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+
+package slogx_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+)
+
+func TestInstallLevelSignalHandler(t *testing.T) {
+	lv := slogx.NewLevelVar(slogx.LevelInfo)
+	changed := make(chan slogx.Level, 1)
+	lv.SetOnChange(func(old, new slogx.Level) { changed <- new })
+	stop := slogx.InstallLevelSignalHandler(lv, syscall.SIGUSR2, syscall.SIGUSR1)
+	defer stop()
+
+	steps := []struct {
+		sig      syscall.Signal
+		expected slogx.Level
+	}{
+		{sig: syscall.SIGUSR1, expected: slogx.LevelDebug},
+		{sig: syscall.SIGUSR1, expected: slogx.LevelTrace},
+		{sig: syscall.SIGUSR2, expected: slogx.LevelDebug},
+		{sig: syscall.SIGUSR2, expected: slogx.LevelInfo},
+		{sig: syscall.SIGUSR2, expected: slogx.LevelNotice},
+	}
+	for _, step := range steps {
+		if err := syscall.Kill(syscall.Getpid(), step.sig); err != nil {
+			t.Errorf("failed to send signal: %s", err.Error())
+			return
+		}
+		select {
+		case level := <-changed:
+			if level != step.expected {
+				t.Errorf("expected level %s, got %s", step.expected, level)
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("timed out waiting for level to change to %s", step.expected)
+			return
+		}
+	}
+}
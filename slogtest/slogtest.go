@@ -0,0 +1,80 @@
+// Package slogtest provides utilities for testing handlers and loggers.
+package slogtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx"
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+)
+
+// HandlerFactory creates a handler which writes JSON records, one per line, to the given writer.
+type HandlerFactory func(w io.Writer) slog.Handler
+
+// CaptureJSON creates a logger whose handler writes to an in-memory buffer and returns it along with a function which
+// decodes the records captured so far.
+//
+// If factory is nil, a writer handler logging every level is created using a JSON formatter with the default options
+// except that attributes are not nested, so each decoded record holds the formatter.JSONFormatterTimeAttr,
+// formatter.JSONFormatterLevelAttr and formatter.JSONFormatterMessageAttr keys alongside the attributes. Use a
+// factory to test other handlers or formatter options, such as wrapping the handler being tested around a writer
+// handler. Each decoded record is a map as returned by json.Unmarshal(), so numbers are decoded as float64 values.
+// Lines which are not valid JSON are skipped.
+func CaptureJSON(factory HandlerFactory) (*slogx.Logger, func() []map[string]any) {
+	if factory == nil {
+		factory = DefaultHandlerFactory
+	}
+	buf := &lockedBuffer{}
+	logger := slogx.Wrap(slog.New(factory(buf)))
+	return logger, buf.decode
+}
+
+// DefaultHandlerFactory creates a writer handler logging every level using a JSON formatter which does not nest
+// attributes.
+func DefaultHandlerFactory(w io.Writer) slog.Handler {
+	opts := formatter.DefaultJSONFormatterOptions()
+	opts.NestAttributes = false
+	return handler.NewWriterHandler(handler.WriterHandlerOptions{
+		Level:           slogx.NewLevelVar(slogx.LevelTrace),
+		RecordFormatter: formatter.NewJSONFormatter(opts),
+		Writer:          w,
+	})
+}
+
+// lockedBuffer is a buffer which is safe for concurrent use.
+type lockedBuffer struct {
+	buf  bytes.Buffer
+	lock sync.Mutex
+}
+
+// Write appends the given bytes to the buffer.
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+// decode decodes each line written to the buffer so far as a JSON object.
+func (b *lockedBuffer) decode() []map[string]any {
+	b.lock.Lock()
+	data := bytes.Clone(b.buf.Bytes())
+	b.lock.Unlock()
+
+	records := []map[string]any{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err == nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
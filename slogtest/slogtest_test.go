@@ -0,0 +1,44 @@
+package slogtest_test
+
+import (
+	"io"
+	"testing"
+
+	"log/slog"
+
+	"go.innotegrity.dev/slogx/formatter"
+	"go.innotegrity.dev/slogx/handler"
+	"go.innotegrity.dev/slogx/slogtest"
+)
+
+func TestCaptureJSON(t *testing.T) {
+	logger, records := slogtest.CaptureJSON(nil)
+	logger.Debug("first", slog.Int("count", 1))
+	logger.WithGroup("request").Warn("second", slog.String("method", "GET"))
+
+	captured := records()
+	if len(captured) != 2 {
+		t.Errorf("expected 2 records, got %d", len(captured))
+		return
+	}
+	if captured[0][formatter.JSONFormatterMessageAttr] != "first" ||
+		captured[0][formatter.JSONFormatterLevelAttr] != "debug" || captured[0]["count"] != float64(1) {
+		t.Errorf("unexpected first record: %v", captured[0])
+	}
+	if _, ok := captured[0][formatter.JSONFormatterTimeAttr]; !ok {
+		t.Errorf("expected time in first record: %v", captured[0])
+	}
+	if request, ok := captured[1]["request"].(map[string]any); !ok || request["method"] != "GET" {
+		t.Errorf("unexpected second record: %v", captured[1])
+	}
+}
+
+func TestCaptureJSONFactory(t *testing.T) {
+	logger, records := slogtest.CaptureJSON(func(w io.Writer) slog.Handler {
+		return handler.NewEnrichHandler(slogtest.DefaultHandlerFactory(w), slog.String("service", "api"))
+	})
+	logger.Info("message")
+	if captured := records(); len(captured) != 1 || captured[0]["service"] != "api" {
+		t.Errorf("unexpected records: %v", captured)
+	}
+}
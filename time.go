@@ -0,0 +1,88 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// startTimeContextKey is used to store a start time in a standard Go context object.
+type startTimeContextKey struct{}
+
+// ContextWithStartTime returns a new context with the given start time stored in it.
+//
+// This is typically called when a request or operation begins so that [Elapsed] can be used to log the time since it
+// began.
+func ContextWithStartTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, startTimeContextKey{}, t)
+}
+
+// StartTimeFromContext retrieves the start time stored in the given context, if it exists.
+//
+// The second return value indicates whether or not a start time was found.
+func StartTimeFromContext(ctx context.Context) (time.Time, bool) {
+	if v := ctx.Value(startTimeContextKey{}); v != nil {
+		if t, ok := v.(time.Time); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Elapsed returns an Attr for the duration since the start time stored in the given context.
+//
+// If no start time is stored in the context, an Attr with a nil value is returned instead.
+func Elapsed(ctx context.Context, key string) slog.Attr {
+	start, ok := StartTimeFromContext(ctx)
+	if !ok {
+		return slog.Attr{
+			Key:   key,
+			Value: slog.AnyValue(nil),
+		}
+	}
+	return slog.Duration(key, time.Since(start))
+}
+
+// LapTimer tracks successive laps of an operation for step-by-step timing logs.
+//
+// For example:
+//
+//	timer := slogx.NewLapTimer()
+//	loadConfig()
+//	logger.Info("config loaded", timer.Lap("elapsed"))
+//	connectDatabase()
+//	logger.Info("database connected", timer.Lap("elapsed"), timer.Total("total"))
+//
+// A LapTimer is safe for concurrent use.
+type LapTimer struct {
+	// unexported variables
+	lap   time.Time
+	lock  sync.Mutex
+	start time.Time
+}
+
+// NewLapTimer creates a new timer whose first lap starts now.
+func NewLapTimer() *LapTimer {
+	now := time.Now()
+	return &LapTimer{
+		lap:   now,
+		start: now,
+	}
+}
+
+// Lap returns an Attr for the duration since the previous lap (or since the timer was created for the first lap)
+// and starts a new lap.
+func (t *LapTimer) Lap(key string) slog.Attr {
+	now := time.Now()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	d := now.Sub(t.lap)
+	t.lap = now
+	return slog.Duration(key, d)
+}
+
+// Total returns an Attr for the duration since the timer was created without starting a new lap.
+func (t *LapTimer) Total(key string) slog.Attr {
+	return slog.Duration(key, time.Since(t.start))
+}
@@ -0,0 +1,41 @@
+package slogx_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.innotegrity.dev/slogx"
+)
+
+func TestElapsed(t *testing.T) {
+	attr := slogx.Elapsed(context.Background(), "elapsed")
+	if attr.Value.Any() != nil {
+		t.Errorf("expected nil value without a start time, got %v", attr.Value)
+	}
+
+	ctx := slogx.ContextWithStartTime(context.Background(), time.Now().Add(-time.Minute))
+	attr = slogx.Elapsed(ctx, "elapsed")
+	if attr.Value.Kind() != slog.KindDuration || attr.Value.Duration() < time.Minute {
+		t.Errorf("expected duration of at least 1m, got %v", attr.Value)
+	}
+}
+
+func TestLapTimer(t *testing.T) {
+	timer := slogx.NewLapTimer()
+	time.Sleep(20 * time.Millisecond)
+	first := timer.Lap("lap")
+	second := timer.Lap("lap")
+	total := timer.Total("total")
+
+	if first.Key != "lap" || first.Value.Kind() != slog.KindDuration || first.Value.Duration() < 20*time.Millisecond {
+		t.Errorf("expected first lap of at least 20ms, got %v", first)
+	}
+	if second.Value.Duration() >= first.Value.Duration() {
+		t.Errorf("expected second lap (%v) to be shorter than the first (%v)", second.Value, first.Value)
+	}
+	if total.Value.Duration() < first.Value.Duration()+second.Value.Duration() {
+		t.Errorf("expected total (%v) to include all laps", total.Value)
+	}
+}